@@ -3,10 +3,13 @@ package gbfs
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"reflect"
 	"strconv"
 	"time"
+
+	"github.com/petoc/gbfs/v2/schema"
 )
 
 var (
@@ -31,6 +34,10 @@ type (
 		UserAgent        string
 		HTTPClient       *http.Client
 		Cache            Cache
+		// ValidateSchema validates raw response against embedded JSON Schema
+		// of feed before decoding. Violations are returned as schema.Errors.
+		// Feeds of versions without embedded schemas are not accepted.
+		ValidateSchema bool
 	}
 	// ClientSubscribeOptions ...
 	ClientSubscribeOptions struct {
@@ -107,13 +114,49 @@ func (c *Client) GetURL(url string, feed Feed) error {
 		}
 		return errors.New("invalid response status: " + strconv.Itoa(res.StatusCode))
 	}
-	err = json.NewDecoder(res.Body).Decode(feed)
+	if !c.Options.ValidateSchema {
+		return json.NewDecoder(res.Body).Decode(feed)
+	}
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	schemaErrs, err := validateSchema(feed.Name(), b)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, feed)
 	if err != nil {
 		return err
 	}
+	if len(schemaErrs) > 0 {
+		return schemaErrs
+	}
 	return nil
 }
 
+// validateSchema validates raw feed against embedded schema of version
+// declared in feed. Feeds without schema in supported version are skipped,
+// feeds of versions without embedded schemas (including v1.0 feeds without
+// version field) are rejected with schema.ErrVersionNotSupported.
+func validateSchema(name string, b []byte) (schema.Errors, error) {
+	v := struct {
+		Version string `json:"version"`
+	}{}
+	_ = json.Unmarshal(b, &v)
+	if v.Version == "" {
+		v.Version = "1.0"
+	}
+	errs, err := schema.Validate(v.Version, name, b)
+	if errors.Is(err, schema.ErrSchemaNotFound) {
+		return nil, nil
+	}
+	if errors.Is(err, schema.ErrVersionNotSupported) {
+		return nil, NewError(name+": schema validation of version "+v.Version+": ", err)
+	}
+	return errs, err
+}
+
 // Get ...
 func (c *Client) Get(feed Feed) error {
 	cached, _ := cacheGet(c, feed)
@@ -170,6 +213,45 @@ func (c *Client) Get(feed Feed) error {
 	return nil
 }
 
+// Snapshot returns gbfs feed and all feeds listed in it for language keyed
+// by feed name. Feeds which failed to load are left out and their errors are
+// joined.
+func (c *Client) Snapshot(language string) (map[string]Feed, error) {
+	if language == "" {
+		language = c.Options.DefaultLanguage
+	}
+	g := &FeedGbfs{}
+	err := c.Get(g)
+	if err != nil {
+		return nil, err
+	}
+	l, ok := g.Data[language]
+	if !ok || l == nil {
+		return nil, ErrInvalidLanguage
+	}
+	feeds := map[string]Feed{
+		g.Name(): g,
+	}
+	errs := []error{}
+	for _, feed := range l.Feeds {
+		if feed == nil || feed.Name == nil {
+			continue
+		}
+		f := FeedStruct(*feed.Name)
+		if f == nil || f.Name() == FeedNameGbfs {
+			continue
+		}
+		f.SetLanguage(language)
+		err = c.Get(f)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		feeds[f.Name()] = f
+	}
+	return feeds, errors.Join(errs...)
+}
+
 func cloneValue(src, dst interface{}) {
 	x := reflect.ValueOf(src)
 	if x.Kind() == reflect.Ptr {
@@ -0,0 +1,29 @@
+package gbfs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/petoc/gbfs/v2/schema"
+)
+
+func TestClientValidateSchemaUnsupportedVersion(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"last_updated":1700000000,"ttl":0,"version":"2.2","data":{"en":{"feeds":[]}}}`))
+	}))
+	defer ts.Close()
+	c, err := NewClient(ClientOptions{
+		AutoDiscoveryURL: ts.URL + "/gbfs.json",
+		DefaultLanguage:  "en",
+		ValidateSchema:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Get(&FeedGbfs{})
+	if !errors.Is(err, schema.ErrVersionNotSupported) {
+		t.Fatalf("expected ErrVersionNotSupported, got %v", err)
+	}
+}
@@ -209,6 +209,8 @@ const (
 	V11 string = "1.1"
 	V20 string = "2.0"
 	V21 string = "2.1"
+	V22 string = "2.2"
+	V23 string = "2.3"
 
 	FeedNameGbfs               = "gbfs"
 	FeedNameGbfsVersions       = "gbfs_versions"
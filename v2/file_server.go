@@ -0,0 +1,116 @@
+package gbfs
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// precompressedEncodings are encodings of precompressed siblings served by
+// file handlers, in order of preference.
+var precompressedEncodings = []struct {
+	encoding  string
+	extension string
+}{
+	{"br", ".br"},
+	{"zstd", ".zst"},
+	{"gzip", ".gz"},
+}
+
+// negotiateEncoding returns encoding and extension of precompressed sibling
+// accepted by client, or empty strings, when no sibling is acceptable.
+func negotiateEncoding(r *http.Request, exists func(extension string) bool) (string, string) {
+	header := r.Header.Get("Accept-Encoding")
+	if header == "" {
+		return "", ""
+	}
+	accepted := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				v, err := strconv.ParseFloat(param[2:], 64)
+				if err == nil {
+					q = v
+				}
+			}
+		}
+		accepted[name] = q
+	}
+	encoding, extension, best := "", "", 0.0
+	for _, v := range precompressedEncodings {
+		q, ok := accepted[v.encoding]
+		if !ok {
+			q = accepted["*"]
+		}
+		if q > best && exists(v.extension) {
+			encoding, extension, best = v.encoding, v.extension, q
+		}
+	}
+	return encoding, extension
+}
+
+func setEncodingHeaders(w http.ResponseWriter, name, encoding string) {
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Encoding", encoding)
+}
+
+type fileHandler struct {
+	root       http.FileSystem
+	fileServer http.Handler
+}
+
+// NewFileHandler returns handler serving files of rootDir. Precompressed
+// siblings of files (.br, .zst, .gz) are served with Content-Encoding, when
+// client accepts them.
+func NewFileHandler(rootDir string) http.Handler {
+	root := http.Dir(rootDir)
+	return &fileHandler{
+		root:       root,
+		fileServer: http.FileServer(root),
+	}
+}
+
+func (h *fileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		h.fileServer.ServeHTTP(w, r)
+		return
+	}
+	name := path.Clean("/" + r.URL.Path)
+	w.Header().Add("Vary", "Accept-Encoding")
+	encoding, extension := negotiateEncoding(r, func(extension string) bool {
+		f, err := h.root.Open(name + extension)
+		if err != nil {
+			return false
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		return err == nil && !fi.IsDir()
+	})
+	if encoding == "" {
+		h.fileServer.ServeHTTP(w, r)
+		return
+	}
+	f, err := h.root.Open(name + extension)
+	if err != nil {
+		h.fileServer.ServeHTTP(w, r)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		h.fileServer.ServeHTTP(w, r)
+		return
+	}
+	setEncodingHeaders(w, name, encoding)
+	http.ServeContent(w, r, name, fi.ModTime(), f)
+}
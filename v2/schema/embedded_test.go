@@ -0,0 +1,18 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetUnsupportedVersion(t *testing.T) {
+	if _, err := Get("2.3", "station_status"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Get("2.3", "unknown_feed"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Fatalf("expected ErrSchemaNotFound, got %v", err)
+	}
+	if _, err := Get("2.2", "station_status"); !errors.Is(err, ErrVersionNotSupported) {
+		t.Fatalf("expected ErrVersionNotSupported, got %v", err)
+	}
+}
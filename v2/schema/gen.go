@@ -0,0 +1,130 @@
+//go:build ignore
+
+// Downloads official JSON Schemas of GBFS specification into schemas
+// directory.
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const baseURL = "https://raw.githubusercontent.com/MobilityData/gbfs-json-schema/master/"
+
+var feeds = map[string][]string{
+	"v1.0": {
+		"free_bike_status",
+		"gbfs",
+		"station_information",
+		"station_status",
+		"system_alerts",
+		"system_calendar",
+		"system_hours",
+		"system_information",
+		"system_pricing_plans",
+		"system_regions",
+	},
+	"v1.1": {
+		"free_bike_status",
+		"gbfs",
+		"gbfs_versions",
+		"station_information",
+		"station_status",
+		"system_alerts",
+		"system_calendar",
+		"system_hours",
+		"system_information",
+		"system_pricing_plans",
+		"system_regions",
+	},
+	"v2.0": {
+		"free_bike_status",
+		"gbfs",
+		"gbfs_versions",
+		"station_information",
+		"station_status",
+		"system_alerts",
+		"system_calendar",
+		"system_hours",
+		"system_information",
+		"system_pricing_plans",
+		"system_regions",
+	},
+	"v2.1": {
+		"free_bike_status",
+		"gbfs",
+		"gbfs_versions",
+		"geofencing_zones",
+		"station_information",
+		"station_status",
+		"system_alerts",
+		"system_calendar",
+		"system_hours",
+		"system_information",
+		"system_pricing_plans",
+		"system_regions",
+		"vehicle_types",
+	},
+	"v2.2": {
+		"free_bike_status",
+		"gbfs",
+		"gbfs_versions",
+		"geofencing_zones",
+		"station_information",
+		"station_status",
+		"system_alerts",
+		"system_calendar",
+		"system_hours",
+		"system_information",
+		"system_pricing_plans",
+		"system_regions",
+		"vehicle_types",
+	},
+	"v2.3": {
+		"free_bike_status",
+		"gbfs",
+		"gbfs_versions",
+		"geofencing_zones",
+		"station_information",
+		"station_status",
+		"system_alerts",
+		"system_calendar",
+		"system_hours",
+		"system_information",
+		"system_pricing_plans",
+		"system_regions",
+		"vehicle_types",
+	},
+}
+
+func main() {
+	for version, names := range feeds {
+		err := os.MkdirAll(filepath.Join("schemas", version), 0755)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, name := range names {
+			res, err := http.Get(baseURL + version + "/" + name + ".json")
+			if err != nil {
+				log.Fatal(err)
+			}
+			if res.StatusCode != http.StatusOK {
+				log.Fatal(version + "/" + name + ": invalid response status: " + strconv.Itoa(res.StatusCode))
+			}
+			b, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				log.Fatal(err)
+			}
+			err = os.WriteFile(filepath.Join("schemas", version, name+".json"), b, 0644)
+			if err != nil {
+				log.Fatal(err)
+			}
+			log.Println(version + "/" + name)
+		}
+	}
+}
@@ -0,0 +1,572 @@
+// Package schema validates raw feeds against JSON Schemas (draft-07) of GBFS
+// specification embedded in module.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrInvalidReference = errors.New("invalid schema reference")
+
+type (
+	// Error is violation of schema. InstancePath is JSON pointer of invalid
+	// value in document, SchemaPath is JSON pointer of violated keyword in
+	// schema.
+	Error struct {
+		InstancePath string `json:"instance_path"`
+		SchemaPath   string `json:"schema_path"`
+		Message      string `json:"message"`
+	}
+	// Errors ...
+	Errors []*Error
+	// Schema is compiled JSON Schema.
+	Schema struct {
+		root    interface{}
+		mu      sync.Mutex
+		regexps map[string]*regexp.Regexp
+	}
+)
+
+// Error ...
+func (e *Error) Error() string {
+	path := e.InstancePath
+	if path == "" {
+		path = "/"
+	}
+	return path + ": " + e.Message + " (" + e.SchemaPath + ")"
+}
+
+// Error ...
+func (e Errors) Error() string {
+	msgs := []string{}
+	for _, v := range e {
+		msgs = append(msgs, v.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func decode(b []byte) (interface{}, error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	err := d.Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Compile parses JSON Schema. Only local references (#/definitions/...)
+// are supported.
+func Compile(b []byte) (*Schema, error) {
+	root, err := decode(b)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{
+		root:    root,
+		regexps: make(map[string]*regexp.Regexp),
+	}, nil
+}
+
+// Validate validates raw JSON document.
+func (s *Schema) Validate(b []byte) (Errors, error) {
+	doc, err := decode(b)
+	if err != nil {
+		return nil, err
+	}
+	return s.ValidateValue(doc), nil
+}
+
+// ValidateValue validates document decoded with json.Decoder.UseNumber.
+func (s *Schema) ValidateValue(doc interface{}) Errors {
+	return s.validate(s.root, doc, "", "#")
+}
+
+func (s *Schema) regexp(pattern string) *regexp.Regexp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	re, ok := s.regexps[pattern]
+	if !ok {
+		re, _ = regexp.Compile(pattern)
+		s.regexps[pattern] = re
+	}
+	return re
+}
+
+// resolve returns subschema of root referenced by local JSON pointer.
+func (s *Schema) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidReference, ref)
+	}
+	v := s.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref[1:], "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch t := v.(type) {
+		case map[string]interface{}:
+			next, ok := t[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidReference, ref)
+			}
+			v = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidReference, ref)
+			}
+			v = t[i]
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrInvalidReference, ref)
+		}
+	}
+	return v, nil
+}
+
+func pointer(path, token string) string {
+	return path + "/" + strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func (s *Schema) validate(schema, doc interface{}, instancePath, schemaPath string) Errors {
+	errs := Errors{}
+	fail := func(keyword, message string) {
+		errs = append(errs, &Error{
+			InstancePath: instancePath,
+			SchemaPath:   schemaPath + "/" + keyword,
+			Message:      message,
+		})
+	}
+	if b, ok := schema.(bool); ok {
+		if !b {
+			errs = append(errs, &Error{
+				InstancePath: instancePath,
+				SchemaPath:   schemaPath,
+				Message:      "value not allowed",
+			})
+		}
+		return errs
+	}
+	m, ok := schema.(map[string]interface{})
+	if !ok {
+		return errs
+	}
+	if ref, ok := m["$ref"].(string); ok {
+		sub, err := s.resolve(ref)
+		if err != nil {
+			fail("$ref", err.Error())
+			return errs
+		}
+		return s.validate(sub, doc, instancePath, ref)
+	}
+	if v, ok := m["type"]; ok {
+		types := []string{}
+		switch tv := v.(type) {
+		case string:
+			types = append(types, tv)
+		case []interface{}:
+			for _, item := range tv {
+				if name, ok := item.(string); ok {
+					types = append(types, name)
+				}
+			}
+		}
+		matched := false
+		for _, name := range types {
+			if isType(doc, name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("type", "expected "+strings.Join(types, " or ")+", got "+typeOf(doc))
+			return errs
+		}
+	}
+	if v, ok := m["enum"].([]interface{}); ok {
+		found := false
+		for _, item := range v {
+			if equal(doc, item) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values := []string{}
+			for _, item := range v {
+				values = append(values, marshal(item))
+			}
+			fail("enum", "value "+marshal(doc)+" must be one of "+strings.Join(values, ", "))
+		}
+	}
+	if v, ok := m["const"]; ok && !equal(doc, v) {
+		fail("const", "value "+marshal(doc)+" must be "+marshal(v))
+	}
+	switch d := doc.(type) {
+	case json.Number:
+		s.validateNumber(m, d, fail)
+	case string:
+		s.validateString(m, d, fail)
+	case []interface{}:
+		errs = append(errs, s.validateArray(m, d, instancePath, schemaPath, fail)...)
+	case map[string]interface{}:
+		errs = append(errs, s.validateObject(m, d, instancePath, schemaPath, fail)...)
+	}
+	if v, ok := m["allOf"].([]interface{}); ok {
+		for i, sub := range v {
+			errs = append(errs, s.validate(sub, doc, instancePath, schemaPath+"/allOf/"+strconv.Itoa(i))...)
+		}
+	}
+	if v, ok := m["anyOf"].([]interface{}); ok {
+		matched := false
+		for i, sub := range v {
+			if len(s.validate(sub, doc, instancePath, schemaPath+"/anyOf/"+strconv.Itoa(i))) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("anyOf", "value does not match any of schemas")
+		}
+	}
+	if v, ok := m["oneOf"].([]interface{}); ok {
+		matched := 0
+		var subErrs Errors
+		for i, sub := range v {
+			e := s.validate(sub, doc, instancePath, schemaPath+"/oneOf/"+strconv.Itoa(i))
+			if len(e) == 0 {
+				matched++
+			} else if subErrs == nil || len(e) < len(subErrs) {
+				subErrs = e
+			}
+		}
+		switch {
+		case matched == 0 && len(v) == 1:
+			errs = append(errs, subErrs...)
+		case matched == 0:
+			fail("oneOf", "value does not match any of schemas")
+		case matched > 1:
+			fail("oneOf", "value matches "+strconv.Itoa(matched)+" schemas, expected exactly one")
+		}
+	}
+	if v, ok := m["not"]; ok && len(s.validate(v, doc, instancePath, schemaPath+"/not")) == 0 {
+		fail("not", "value must not match schema")
+	}
+	if v, ok := m["if"]; ok {
+		if len(s.validate(v, doc, instancePath, schemaPath+"/if")) == 0 {
+			if then, ok := m["then"]; ok {
+				errs = append(errs, s.validate(then, doc, instancePath, schemaPath+"/then")...)
+			}
+		} else if els, ok := m["else"]; ok {
+			errs = append(errs, s.validate(els, doc, instancePath, schemaPath+"/else")...)
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validateNumber(m map[string]interface{}, d json.Number, fail func(keyword, message string)) {
+	f, err := d.Float64()
+	if err != nil {
+		return
+	}
+	if v, ok := number(m["minimum"]); ok && f < v {
+		fail("minimum", "value "+d.String()+" must be at least "+formatFloat(v))
+	}
+	if v, ok := number(m["maximum"]); ok && f > v {
+		fail("maximum", "value "+d.String()+" must be at most "+formatFloat(v))
+	}
+	if v, ok := number(m["exclusiveMinimum"]); ok && f <= v {
+		fail("exclusiveMinimum", "value "+d.String()+" must be greater than "+formatFloat(v))
+	}
+	if v, ok := number(m["exclusiveMaximum"]); ok && f >= v {
+		fail("exclusiveMaximum", "value "+d.String()+" must be less than "+formatFloat(v))
+	}
+	if v, ok := number(m["multipleOf"]); ok && v > 0 {
+		q := f / v
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("multipleOf", "value "+d.String()+" must be multiple of "+formatFloat(v))
+		}
+	}
+}
+
+func (s *Schema) validateString(m map[string]interface{}, d string, fail func(keyword, message string)) {
+	length := utf8.RuneCountInString(d)
+	if v, ok := number(m["minLength"]); ok && float64(length) < v {
+		fail("minLength", "length "+strconv.Itoa(length)+" must be at least "+formatFloat(v))
+	}
+	if v, ok := number(m["maxLength"]); ok && float64(length) > v {
+		fail("maxLength", "length "+strconv.Itoa(length)+" must be at most "+formatFloat(v))
+	}
+	if v, ok := m["pattern"].(string); ok {
+		if re := s.regexp(v); re != nil && !re.MatchString(d) {
+			fail("pattern", strconv.Quote(d)+" does not match pattern "+strconv.Quote(v))
+		}
+	}
+	if v, ok := m["format"].(string); ok && !validFormat(v, d) {
+		fail("format", strconv.Quote(d)+" is not valid "+v)
+	}
+}
+
+func (s *Schema) validateArray(m map[string]interface{}, d []interface{}, instancePath, schemaPath string, fail func(keyword, message string)) Errors {
+	errs := Errors{}
+	if v, ok := number(m["minItems"]); ok && float64(len(d)) < v {
+		fail("minItems", "array must have at least "+formatFloat(v)+" items")
+	}
+	if v, ok := number(m["maxItems"]); ok && float64(len(d)) > v {
+		fail("maxItems", "array must have at most "+formatFloat(v)+" items")
+	}
+	if v, ok := m["uniqueItems"].(bool); ok && v {
+	unique:
+		for i := range d {
+			for j := 0; j < i; j++ {
+				if equal(d[i], d[j]) {
+					fail("uniqueItems", "items "+strconv.Itoa(j)+" and "+strconv.Itoa(i)+" are equal")
+					break unique
+				}
+			}
+		}
+	}
+	switch items := m["items"].(type) {
+	case []interface{}:
+		for i, item := range d {
+			if i < len(items) {
+				errs = append(errs, s.validate(items[i], item, pointer(instancePath, strconv.Itoa(i)), schemaPath+"/items/"+strconv.Itoa(i))...)
+			} else if additional, ok := m["additionalItems"]; ok {
+				errs = append(errs, s.validate(additional, item, pointer(instancePath, strconv.Itoa(i)), schemaPath+"/additionalItems")...)
+			}
+		}
+	case nil:
+	default:
+		for i, item := range d {
+			errs = append(errs, s.validate(items, item, pointer(instancePath, strconv.Itoa(i)), schemaPath+"/items")...)
+		}
+	}
+	if v, ok := m["contains"]; ok {
+		found := false
+		for _, item := range d {
+			if len(s.validate(v, item, instancePath, schemaPath+"/contains")) == 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("contains", "array does not contain matching item")
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validateObject(m map[string]interface{}, d map[string]interface{}, instancePath, schemaPath string, fail func(keyword, message string)) Errors {
+	errs := Errors{}
+	if v, ok := m["required"].([]interface{}); ok {
+		for _, item := range v {
+			name, ok := item.(string)
+			if !ok {
+				continue
+			}
+			if _, ok := d[name]; !ok {
+				fail("required", "missing required property "+strconv.Quote(name))
+			}
+		}
+	}
+	if v, ok := number(m["minProperties"]); ok && float64(len(d)) < v {
+		fail("minProperties", "object must have at least "+formatFloat(v)+" properties")
+	}
+	if v, ok := number(m["maxProperties"]); ok && float64(len(d)) > v {
+		fail("maxProperties", "object must have at most "+formatFloat(v)+" properties")
+	}
+	properties, _ := m["properties"].(map[string]interface{})
+	patternProperties, _ := m["patternProperties"].(map[string]interface{})
+	patterns := make([]string, 0, len(patternProperties))
+	for pattern := range patternProperties {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value := d[k]
+		path := pointer(instancePath, k)
+		if names, ok := m["propertyNames"]; ok {
+			errs = append(errs, s.validate(names, k, path, schemaPath+"/propertyNames")...)
+		}
+		matched := false
+		if sub, ok := properties[k]; ok {
+			matched = true
+			errs = append(errs, s.validate(sub, value, path, pointer(schemaPath+"/properties", k))...)
+		}
+		for _, pattern := range patterns {
+			if re := s.regexp(pattern); re != nil && re.MatchString(k) {
+				matched = true
+				errs = append(errs, s.validate(patternProperties[pattern], value, path, pointer(schemaPath+"/patternProperties", pattern))...)
+			}
+		}
+		if matched {
+			continue
+		}
+		if additional, ok := m["additionalProperties"]; ok {
+			if b, ok := additional.(bool); ok && !b {
+				errs = append(errs, &Error{
+					InstancePath: path,
+					SchemaPath:   schemaPath + "/additionalProperties",
+					Message:      "additional property " + strconv.Quote(k) + " not allowed",
+				})
+				continue
+			}
+			errs = append(errs, s.validate(additional, value, path, schemaPath+"/additionalProperties")...)
+		}
+	}
+	if v, ok := m["dependencies"].(map[string]interface{}); ok {
+		for _, k := range keys {
+			dependency, ok := v[k]
+			if !ok {
+				continue
+			}
+			switch dep := dependency.(type) {
+			case []interface{}:
+				for _, item := range dep {
+					name, _ := item.(string)
+					if _, ok := d[name]; !ok {
+						fail("dependencies", "property "+strconv.Quote(name)+" required by "+strconv.Quote(k))
+					}
+				}
+			default:
+				errs = append(errs, s.validate(dep, d, instancePath, pointer(schemaPath+"/dependencies", k))...)
+			}
+		}
+	}
+	return errs
+}
+
+func typeOf(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+func isType(v interface{}, name string) bool {
+	switch name {
+	case "number":
+		_, ok := v.(json.Number)
+		return ok
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	}
+	return typeOf(v) == name
+}
+
+func equal(a, b interface{}) bool {
+	na, okA := a.(json.Number)
+	nb, okB := b.(json.Number)
+	if okA && okB {
+		fa, errA := na.Float64()
+		fb, errB := nb.Float64()
+		return errA == nil && errB == nil && fa == fb
+	}
+	switch ta := a.(type) {
+	case []interface{}:
+		tb, ok := b.([]interface{})
+		if !ok || len(ta) != len(tb) {
+			return false
+		}
+		for i := range ta {
+			if !equal(ta[i], tb[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		tb, ok := b.(map[string]interface{})
+		if !ok || len(ta) != len(tb) {
+			return false
+		}
+		for k, v := range ta {
+			if !equal(v, tb[k]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func number(v interface{}) (float64, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+func marshal(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// validFormat checks formats used by GBFS schemas, unknown formats are
+// valid.
+func validFormat(format, v string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, v)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", v)
+		return err == nil
+	case "time":
+		_, err := time.Parse("15:04:05", v)
+		return err == nil
+	case "email":
+		_, err := mail.ParseAddress(v)
+		return err == nil
+	case "uri":
+		u, err := url.Parse(v)
+		return err == nil && u.Scheme != ""
+	case "uri-reference":
+		_, err := url.Parse(v)
+		return err == nil
+	}
+	return true
+}
@@ -25,7 +25,13 @@ var (
 type (
 	// Server ...
 	Server struct {
-		Options *ServerOptions
+		Options     *ServerOptions
+		gbfsMu      sync.Mutex
+		gbfsFeed    *FeedGbfs
+		gbfsFeeds   map[string]*gbfsFeedEntry
+		gbfsReady   bool
+		revisionsMu sync.Mutex
+		revisions   map[string]*feedRevision
 	}
 	// ServerOptions ...
 	ServerOptions struct {
@@ -37,6 +43,25 @@ type (
 		DefaultTTL    int
 		FeedHandlers  []*FeedHandler
 		UpdateHandler func(server *Server, feed Feed, path string, err error)
+		// PublishHandler is called for each feed processed without error,
+		// also when feed was not written, because it has not changed.
+		PublishHandler func(server *Server, feed Feed, path string, result *PublishResult)
+		Versions       []*ServerVersion
+		// VehicleFilters are applied to free_bike_status in order before
+		// feed is written.
+		VehicleFilters []*VehicleFilter
+		Serialization  SerializationOptions
+	}
+	// PublishResult describes feed processed without error. Published is
+	// false, when feed was not written, because its content has not
+	// changed.
+	PublishResult struct {
+		Published bool
+	}
+	// ServerVersion ...
+	ServerVersion struct {
+		Version string
+		URL     string
 	}
 	// FeedHandler ...
 	FeedHandler struct {
@@ -45,6 +70,12 @@ type (
 		Path     string
 		Handler  func(*Server) ([]Feed, error)
 	}
+	gbfsFeedEntry struct {
+		language     string
+		name         string
+		pathSegments []string
+		handler      *FeedHandler
+	}
 )
 
 func writeFeed(filePath string, feed Feed) error {
@@ -77,6 +108,13 @@ func NewServer(options ServerOptions) (*Server, error) {
 	if options.DefaultTTL <= 0 {
 		return nil, ErrInvalidDefaultTTL
 	}
+	for _, filter := range options.VehicleFilters {
+		for _, zone := range filter.Zones {
+			if err := zone.Validate(); err != nil {
+				return nil, NewError("invalid privacy zone: ", err)
+			}
+		}
+	}
 	s := &Server{
 		Options: &options,
 	}
@@ -85,75 +123,71 @@ func NewServer(options ServerOptions) (*Server, error) {
 
 // Start ...
 func (s *Server) Start() error {
-	if s.Options.FeedHandlers == nil || len(s.Options.FeedHandlers) == 0 {
+	if len(s.Options.FeedHandlers) == 0 {
 		return ErrMissingFeedHandlers
 	}
-	gbfsGenerated := false
-	gbfsFeed := &FeedGbfs{}
-	gbfsFeed.SetTTL(s.Options.DefaultTTL)
-	gbfsFeed.SetVersion(s.Options.Version)
+	s.gbfsMu.Lock()
+	s.gbfsFeed = &FeedGbfs{
+		Data: make(map[string]*FeedGbfsLanguage),
+	}
+	s.gbfsFeed.SetTTL(s.Options.DefaultTTL)
+	s.gbfsFeed.SetVersion(s.Options.Version)
+	s.gbfsFeeds = make(map[string]*gbfsFeedEntry)
+	s.gbfsReady = false
+	s.gbfsMu.Unlock()
+	s.revisionsMu.Lock()
+	s.revisions = make(map[string]*feedRevision)
+	s.revisionsMu.Unlock()
+	feedHandlers := append([]*FeedHandler{}, s.Options.FeedHandlers...)
+	if len(s.Options.Versions) > 0 {
+		feedHandlers = append(feedHandlers, s.gbfsVersionsFeedHandler())
+	}
 	var wgGbfsFeed sync.WaitGroup
-	wgGbfsFeed.Add(len(s.Options.FeedHandlers))
-	for _, feedHandler := range s.Options.FeedHandlers {
+	wgGbfsFeed.Add(len(feedHandlers))
+	for _, feedHandler := range feedHandlers {
 		go (func(feedHandler *FeedHandler) {
 			if feedHandler.TTL == 0 {
 				feedHandler.TTL = s.Options.DefaultTTL
 			}
+			first := true
 			for {
 				feeds, err := feedHandler.Handler(s)
 				if err != nil {
 					s.Options.UpdateHandler(s, nil, "", err)
-					if !gbfsGenerated {
+					if first {
 						wgGbfsFeed.Done()
 					}
 					break
 				}
+				published := []*gbfsFeedEntry{}
+				failed := []string{}
 				for _, feed := range feeds {
-					feed.SetLastUpdated(Timestamp(time.Now().Unix()))
+					for _, filter := range s.Options.VehicleFilters {
+						feed = filter.FilterFeed(feed)
+					}
 					if feed.GetTTL() == 0 {
 						feed.SetTTL(feedHandler.TTL)
 					}
 					feed.SetVersion(s.Options.Version)
-					pathSegments := []string{}
-					if s.Options.BasePath != "" {
-						pathSegments = append(pathSegments, strings.Trim(s.Options.BasePath, "/"))
-					}
-					if feed.GetLanguage() != "" {
-						pathSegments = append(pathSegments, feed.GetLanguage())
-					}
-					path := strings.Trim(feedHandler.Path, "/")
-					if path == "" {
-						path = feed.Name() + ".json"
-					}
-					pathSegments = append(pathSegments, path)
-					filePath := strings.Join(append([]string{s.Options.RootDir}, pathSegments...), "/")
-					err := writeFeed(filePath, feed)
-					s.Options.UpdateHandler(s, feed, strings.Join(pathSegments, "/"), err)
-					if err != nil {
+					pathSegments := s.feedPathSegments(feedHandler, feed)
+					err := s.writeFeedIfChanged(pathSegments, feed)
+					s.reportFeed(feed, strings.Join(pathSegments, "/"), err)
+					if err != nil && !errors.Is(err, errFeedUnchanged) {
+						failed = append(failed, gbfsFeedKey(feed.GetLanguage(), feed.Name()))
 						continue
 					}
-					if !gbfsGenerated && feed.Name() != FeedNameGbfs {
-						gbfsFeed.Lock()
-						if gbfsFeed.Data == nil {
-							gbfsFeed.Data = make(map[string]*FeedGbfsLanguage)
-						}
-						gbfsFeedLanguage, ok := gbfsFeed.Data[feed.GetLanguage()]
-						if !ok {
-							gbfsFeedLanguage = &FeedGbfsLanguage{
-								Feeds: []*FeedGbfsFeed{},
-							}
-							gbfsFeed.Data[feed.GetLanguage()] = gbfsFeedLanguage
-						}
-						if gbfsFeedLanguage != nil && gbfsFeedLanguage.Feeds != nil {
-							gbfsFeedLanguage.Feeds = append(gbfsFeedLanguage.Feeds, &FeedGbfsFeed{
-								Name: NewString(feed.Name()),
-								URL:  NewString(strings.Join(append([]string{strings.Trim(s.Options.BaseURL, "/")}, pathSegments...), "/")),
-							})
-						}
-						gbfsFeed.Unlock()
+					if feed.Name() != FeedNameGbfs {
+						published = append(published, &gbfsFeedEntry{
+							language:     feed.GetLanguage(),
+							name:         feed.Name(),
+							pathSegments: pathSegments,
+							handler:      feedHandler,
+						})
 					}
 				}
-				if !gbfsGenerated {
+				s.updateGbfsFeeds(feedHandler, published, failed)
+				if first {
+					first = false
 					wgGbfsFeed.Done()
 				}
 				if feedHandler.TTL == 0 {
@@ -164,38 +198,167 @@ func (s *Server) Start() error {
 		})(feedHandler)
 	}
 	wgGbfsFeed.Wait()
-	gbfsGenerated = true
-	feedNames := FeedNameAll()
-	for _, langData := range gbfsFeed.Data {
-		if langData.Feeds == nil {
+	s.gbfsMu.Lock()
+	s.gbfsReady = true
+	s.gbfsMu.Unlock()
+	for {
+		ttl := s.writeGbfsFeed()
+		if ttl == 0 {
+			break
+		}
+		time.Sleep(time.Duration(ttl) * time.Second)
+	}
+	return nil
+}
+
+func (s *Server) feedURL(pathSegments []string) string {
+	return strings.Join(append([]string{strings.Trim(s.Options.BaseURL, "/")}, pathSegments...), "/")
+}
+
+func gbfsFeedKey(language, name string) string {
+	return language + "/" + name
+}
+
+func (s *Server) feedPathSegments(feedHandler *FeedHandler, feed Feed) []string {
+	pathSegments := []string{}
+	if s.Options.BasePath != "" {
+		pathSegments = append(pathSegments, strings.Trim(s.Options.BasePath, "/"))
+	}
+	if feed.GetLanguage() != "" {
+		pathSegments = append(pathSegments, feed.GetLanguage())
+	}
+	path := ""
+	if feedHandler != nil {
+		path = strings.Trim(feedHandler.Path, "/")
+	}
+	if path == "" {
+		path = feed.Name() + ".json"
+	}
+	return append(pathSegments, path)
+}
+
+// updateGbfsFeeds replaces entries owned by feed handler with currently
+// published feeds and rewrites gbfs.json when the set of feeds has changed.
+// Feeds which failed to be written keep their previous entries.
+func (s *Server) updateGbfsFeeds(feedHandler *FeedHandler, published []*gbfsFeedEntry, failed []string) {
+	s.gbfsMu.Lock()
+	changed := false
+	current := map[string]bool{}
+	for _, entry := range published {
+		key := gbfsFeedKey(entry.language, entry.name)
+		current[key] = true
+		existing, ok := s.gbfsFeeds[key]
+		if ok && existing.handler == entry.handler && strings.Join(existing.pathSegments, "/") == strings.Join(entry.pathSegments, "/") {
 			continue
 		}
-		sort.Slice(langData.Feeds, func(i, j int) bool {
-			if langData.Feeds[i].Name == nil || langData.Feeds[j].Name == nil {
-				return false
+		s.gbfsFeeds[key] = entry
+		changed = true
+	}
+	retired := []*gbfsFeedEntry{}
+	for key, entry := range s.gbfsFeeds {
+		if entry.handler != feedHandler || current[key] || inSlice(key, failed) {
+			continue
+		}
+		delete(s.gbfsFeeds, key)
+		retired = append(retired, entry)
+		changed = true
+	}
+	if !changed {
+		s.gbfsMu.Unlock()
+		return
+	}
+	feedNames := FeedNameAll()
+	data := make(map[string]*FeedGbfsLanguage)
+	for _, entry := range s.gbfsFeeds {
+		if entry.language != "" {
+			data[entry.language] = &FeedGbfsLanguage{
+				Feeds: []*FeedGbfsFeed{},
 			}
-			if indexInSlice(*langData.Feeds[i].Name, feedNames) > indexInSlice(*langData.Feeds[j].Name, feedNames) {
-				return false
+		}
+	}
+	for _, entry := range s.gbfsFeeds {
+		languages := []string{entry.language}
+		if entry.language == "" && len(data) > 0 {
+			// language independent feeds are listed for every language
+			languages = []string{}
+			for language := range data {
+				if language != "" {
+					languages = append(languages, language)
+				}
+			}
+		}
+		for _, language := range languages {
+			gbfsFeedLanguage, ok := data[language]
+			if !ok {
+				gbfsFeedLanguage = &FeedGbfsLanguage{
+					Feeds: []*FeedGbfsFeed{},
+				}
+				data[language] = gbfsFeedLanguage
+			}
+			gbfsFeedLanguage.Feeds = append(gbfsFeedLanguage.Feeds, &FeedGbfsFeed{
+				Name: NewString(entry.name),
+				URL:  NewString(s.feedURL(entry.pathSegments)),
+			})
+		}
+	}
+	for _, langData := range data {
+		feeds := langData.Feeds
+		sort.SliceStable(feeds, func(i, j int) bool {
+			ii := indexInSlice(*feeds[i].Name, feedNames)
+			ij := indexInSlice(*feeds[j].Name, feedNames)
+			if ii != ij {
+				return ii < ij
 			}
-			return true
+			return *feeds[i].Name < *feeds[j].Name
 		})
 	}
-	for {
-		gbfsFeed.SetLastUpdated(Timestamp(time.Now().Unix()))
-		pathSegments := []string{}
-		if s.Options.BasePath != "" {
-			pathSegments = append(pathSegments, strings.Trim(s.Options.BasePath, "/"))
-		}
-		pathSegments = append(pathSegments, gbfsFeed.Name()+".json")
-		filePath := strings.Join(append([]string{s.Options.RootDir}, pathSegments...), "/")
-		err := writeFeed(filePath, gbfsFeed)
-		s.Options.UpdateHandler(s, gbfsFeed, strings.Join(pathSegments, "/"), err)
-		if gbfsFeed.GetTTL() == 0 {
-			break
+	s.gbfsFeed.Lock()
+	s.gbfsFeed.Data = data
+	s.gbfsFeed.Unlock()
+	ready := s.gbfsReady
+	s.gbfsMu.Unlock()
+	if ready {
+		s.writeGbfsFeed()
+	}
+	for _, entry := range retired {
+		path := strings.Join(entry.pathSegments, "/")
+		s.forgetRevision(path)
+		err := s.removeFeed(path)
+		if err != nil {
+			s.Options.UpdateHandler(s, nil, path, err)
 		}
-		time.Sleep(time.Duration(gbfsFeed.GetTTL()) * time.Second)
 	}
-	return nil
+}
+
+// writeGbfsFeed writes current autodiscovery feed and returns its TTL.
+func (s *Server) writeGbfsFeed() int {
+	s.gbfsMu.Lock()
+	s.gbfsFeed.Lock()
+	pathSegments := []string{}
+	if s.Options.BasePath != "" {
+		pathSegments = append(pathSegments, strings.Trim(s.Options.BasePath, "/"))
+	}
+	pathSegments = append(pathSegments, s.gbfsFeed.Name()+".json")
+	err := s.writeFeedIfChanged(pathSegments, s.gbfsFeed)
+	s.gbfsFeed.Unlock()
+	s.gbfsMu.Unlock()
+	s.reportFeed(s.gbfsFeed, strings.Join(pathSegments, "/"), err)
+	return s.gbfsFeed.GetTTL()
+}
+
+// reportFeed passes result of writing feed to UpdateHandler, when feed was
+// written or failed, and to PublishHandler, when feed was processed without
+// error.
+func (s *Server) reportFeed(feed Feed, path string, err error) {
+	unchanged := errors.Is(err, errFeedUnchanged)
+	if !unchanged {
+		s.Options.UpdateHandler(s, feed, path, err)
+	}
+	if (err == nil || unchanged) && s.Options.PublishHandler != nil {
+		s.Options.PublishHandler(s, feed, path, &PublishResult{
+			Published: !unchanged,
+		})
+	}
 }
 
 // NewFileServer ...
@@ -208,7 +371,7 @@ func NewFileServer(addr, rootDir string) (*http.Server, error) {
 	}
 	s := &http.Server{
 		Addr:         addr,
-		Handler:      http.FileServer(http.Dir(rootDir)),
+		Handler:      NewFileHandler(rootDir),
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 5 * time.Second,
 	}
@@ -0,0 +1,94 @@
+package gbfs
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errFeedUnchanged is returned, when feed was not written, because its
+// content has not changed since it was last written.
+var errFeedUnchanged = errors.New("feed unchanged, not published")
+
+type feedRevision struct {
+	hash        [32]byte
+	lastUpdated string
+}
+
+// stampFeed sets last_updated of feed and reports whether content of feed
+// changed since it was last written to path. Content is compared without
+// last_updated. Unchanged feed keeps last_updated of previous revision,
+// changed feed gets current time, unless feed handler has set last_updated
+// itself.
+func (s *Server) stampFeed(path string, feed Feed) (*feedRevision, bool, error) {
+	b, err := json.Marshal(feed)
+	if err != nil {
+		return nil, false, err
+	}
+	fields := map[string]json.RawMessage{}
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		return nil, false, err
+	}
+	lastUpdated := string(fields["last_updated"])
+	delete(fields, "last_updated")
+	b, err = json.Marshal(fields)
+	if err != nil {
+		return nil, false, err
+	}
+	rev := &feedRevision{
+		hash: sha256.Sum256(b),
+	}
+	s.revisionsMu.Lock()
+	prev := s.revisions[path]
+	s.revisionsMu.Unlock()
+	supplied := lastUpdated != "" && lastUpdated != "null" && (prev == nil || lastUpdated != prev.lastUpdated)
+	changed := true
+	switch {
+	case supplied:
+		rev.lastUpdated = lastUpdated
+	case prev != nil && prev.hash == rev.hash:
+		rev.lastUpdated = prev.lastUpdated
+		changed = false
+	default:
+		rev.lastUpdated = strconv.FormatInt(time.Now().Unix(), 10)
+	}
+	if rev.lastUpdated != lastUpdated {
+		t, err := strconv.ParseInt(rev.lastUpdated, 10, 64)
+		if err != nil {
+			return nil, false, err
+		}
+		feed.SetLastUpdated(Timestamp(t))
+	}
+	return rev, changed, nil
+}
+
+// writeFeedIfChanged stamps feed and writes it, when its content changed.
+// It returns errFeedUnchanged otherwise.
+func (s *Server) writeFeedIfChanged(pathSegments []string, feed Feed) error {
+	path := strings.Join(pathSegments, "/")
+	rev, changed, err := s.stampFeed(path, feed)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return errFeedUnchanged
+	}
+	err = s.writeFeed(path, feed)
+	if err != nil {
+		return err
+	}
+	s.revisionsMu.Lock()
+	s.revisions[path] = rev
+	s.revisionsMu.Unlock()
+	return nil
+}
+
+func (s *Server) forgetRevision(path string) {
+	s.revisionsMu.Lock()
+	delete(s.revisions, path)
+	s.revisionsMu.Unlock()
+}
@@ -0,0 +1,70 @@
+package gbfs
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// gbfsVersionsFeedHandler returns internal feed handler publishing
+// gbfs_versions.json with current version and all sibling versions.
+func (s *Server) gbfsVersionsFeedHandler() *FeedHandler {
+	return &FeedHandler{
+		TTL: s.Options.DefaultTTL,
+		Handler: func(s *Server) ([]Feed, error) {
+			feed := &FeedGbfsVersions{
+				Data: &FeedGbfsVersionsData{
+					Versions: s.gbfsVersions(),
+				},
+			}
+			return []Feed{feed}, nil
+		},
+	}
+}
+
+func (s *Server) gbfsURL() string {
+	return s.feedURL(s.feedPathSegments(nil, &FeedGbfs{}))
+}
+
+func (s *Server) gbfsVersions() []*FeedGbfsVersionsVersion {
+	serverVersions := append([]*ServerVersion{{Version: s.Options.Version, URL: s.gbfsURL()}}, s.Options.Versions...)
+	versions := []*FeedGbfsVersionsVersion{}
+	seen := map[string]bool{}
+	for _, v := range serverVersions {
+		if v == nil || v.Version == "" || v.URL == "" || seen[v.Version] {
+			continue
+		}
+		seen[v.Version] = true
+		versions = append(versions, &FeedGbfsVersionsVersion{
+			Version: NewString(v.Version),
+			URL:     NewString(v.URL),
+		})
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return CompareVersions(*versions[i].Version, *versions[j].Version) < 0
+	})
+	return versions
+}
+
+// CompareVersions compares two GBFS versions numerically and returns -1, 0
+// or 1. Missing or non-numeric segments are treated as 0.
+func CompareVersions(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var av, bv int
+		if i < len(as) {
+			av, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			bv, _ = strconv.Atoi(bs[i])
+		}
+		if av < bv {
+			return -1
+		}
+		if av > bv {
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,204 @@
+package validate
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	gbfs "github.com/petoc/gbfs/v2"
+)
+
+// rule checks scalar value and returns message of finding, or empty string
+// when value is valid.
+type rule struct {
+	id    string
+	check func(v interface{}) string
+}
+
+func enum(values []string) *rule {
+	return &rule{
+		id: RuleEnum,
+		check: func(v interface{}) string {
+			s, ok := v.(string)
+			if ok && slices.Contains(values, s) {
+				return ""
+			}
+			return "invalid value " + quote(v) + ", expected one of " + strings.Join(values, ", ")
+		},
+	}
+}
+
+func inRange(min, max float64) *rule {
+	return &rule{
+		id: RuleRange,
+		check: func(v interface{}) string {
+			f, ok := number(v)
+			if ok && f >= min && f <= max {
+				return ""
+			}
+			return "value " + quote(v) + " out of range [" + formatFloat(min) + ", " + formatFloat(max) + "]"
+		},
+	}
+}
+
+func format(layout, name string) *rule {
+	return &rule{
+		id: RuleFormat,
+		check: func(v interface{}) string {
+			s, ok := v.(string)
+			if ok {
+				_, err := time.Parse(layout, s)
+				if err == nil {
+					return ""
+				}
+			}
+			return "invalid value " + quote(v) + ", expected " + name
+		},
+	}
+}
+
+var (
+	nonNegative = &rule{
+		id: RuleNonNegative,
+		check: func(v interface{}) string {
+			f, ok := number(v)
+			if ok && f >= 0 {
+				return ""
+			}
+			return "value " + quote(v) + " must not be negative"
+		},
+	}
+	languagePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{1,8})*$`)
+	language        = &rule{
+		id: RuleLanguage,
+		check: func(v interface{}) string {
+			s, ok := v.(string)
+			if ok && languagePattern.MatchString(s) {
+				return ""
+			}
+			return "invalid language code " + quote(v)
+		},
+	}
+	// hours of end time may exceed 24 for rental hours ending next day
+	timeOfDayPattern = regexp.MustCompile(`^([0-3][0-9]|4[0-7]):[0-5][0-9]:[0-5][0-9]$`)
+	timeOfDay        = &rule{
+		id: RuleFormat,
+		check: func(v interface{}) string {
+			s, ok := v.(string)
+			if ok && timeOfDayPattern.MatchString(s) {
+				return ""
+			}
+			return "invalid value " + quote(v) + ", expected time HH:MM:SS"
+		},
+	}
+	timestamp = format(time.RFC3339, "RFC3339 timestamp")
+	date      = format(gbfs.DateFormat, "date YYYY-MM-DD")
+)
+
+// skipKeys are not walked, coordinates of geometries are not checked.
+var skipKeys = map[string]bool{
+	"coordinates": true,
+}
+
+// keyRules apply to fields with given name in any feed.
+var keyRules = map[string]*rule{
+	"lat":                  inRange(-90, 90),
+	"lon":                  inRange(-180, 180),
+	"language":             language,
+	"available_until":      timestamp,
+	"current_fuel_percent": inRange(0, 1),
+}
+
+// fieldRules apply to fields of feeds by path without indexes.
+var fieldRules = map[string]map[string]*rule{
+	gbfs.FeedNameStationInformation: {
+		"data.stations[].rental_methods[]": enum(gbfs.RentalMethodAll()),
+	},
+	gbfs.FeedNameSystemAlerts: {
+		"data.alerts[].type": enum(gbfs.AlertTypeAll()),
+	},
+	gbfs.FeedNameSystemCalendar: {
+		"data.calendars[].start_month": inRange(1, 12),
+		"data.calendars[].start_day":   inRange(1, 31),
+		"data.calendars[].end_month":   inRange(1, 12),
+		"data.calendars[].end_day":     inRange(1, 31),
+	},
+	gbfs.FeedNameSystemHours: {
+		"data.rental_hours[].user_types[]": enum(gbfs.UserTypeAll()),
+		"data.rental_hours[].days[]":       enum(gbfs.DayAll()),
+		"data.rental_hours[].start_time":   timeOfDay,
+		"data.rental_hours[].end_time":     timeOfDay,
+	},
+	gbfs.FeedNameSystemInformation: {
+		"data.start_date": date,
+	},
+	gbfs.FeedNameVehicleTypes: {
+		"data.vehicle_types[].form_factor":     enum(gbfs.FormFactorAll()),
+		"data.vehicle_types[].propulsion_type": enum(gbfs.PropulsionTypeAll()),
+	},
+}
+
+// fieldVersions are versions in which fields were added.
+var fieldVersions = map[string]map[string]string{
+	gbfs.FeedNameFreeBikeStatus: {
+		"data.bikes[].system_id":            "3.0-RC",
+		"data.bikes[].rental_uris":          gbfs.V11,
+		"data.bikes[].vehicle_type_id":      gbfs.V21,
+		"data.bikes[].last_reported":        gbfs.V21,
+		"data.bikes[].current_range_meters": gbfs.V21,
+		"data.bikes[].current_fuel_percent": gbfs.V23,
+		"data.bikes[].station_id":           gbfs.V21,
+		"data.bikes[].home_station_id":      gbfs.V23,
+		"data.bikes[].pricing_plan_id":      gbfs.V22,
+		"data.bikes[].vehicle_equipment":    gbfs.V23,
+		"data.bikes[].available_until":      gbfs.V23,
+	},
+	gbfs.FeedNameStationInformation: {
+		"data.stations[].is_virtual_station":    gbfs.V21,
+		"data.stations[].station_area":          gbfs.V21,
+		"data.stations[].vehicle_capacity":      gbfs.V21,
+		"data.stations[].vehicle_type_capacity": gbfs.V21,
+		"data.stations[].is_valet_station":      gbfs.V21,
+		"data.stations[].rental_uris":           gbfs.V11,
+	},
+	gbfs.FeedNameStationStatus: {
+		"data.stations[].vehicle_types_available": gbfs.V21,
+		"data.stations[].vehicle_docks_available": gbfs.V21,
+	},
+	gbfs.FeedNameSystemInformation: {
+		"data.feed_contact_email":            gbfs.V11,
+		"data.license_id":                    "3.0-RC",
+		"data.license_url":                   "3.0-RC",
+		"data.attribution_organization_name": "3.0-RC",
+		"data.attribution_url":               "3.0-RC",
+		"data.rental_apps":                   gbfs.V11,
+	},
+}
+
+func number(v interface{}) (float64, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil && !math.IsNaN(f)
+}
+
+func quote(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return strconv.Quote(t)
+	case json.Number:
+		return t.String()
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
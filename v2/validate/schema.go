@@ -0,0 +1,73 @@
+package validate
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/petoc/gbfs/v2/schema"
+)
+
+const RuleSchema = "schema"
+
+// Schema validates raw feed against embedded official JSON Schema of given
+// version. Findings have instance path of invalid value and schema path in
+// message.
+func Schema(name, version string, raw []byte) Findings {
+	errs, err := schema.Validate(version, name, raw)
+	if errors.Is(err, schema.ErrSchemaNotFound) || errors.Is(err, schema.ErrVersionNotSupported) {
+		return Findings{{
+			Severity: SeverityWarning,
+			Feed:     name,
+			Path:     "version",
+			Rule:     RuleVersion,
+			Message:  "no schema for version " + strconv.Quote(version),
+		}}
+	}
+	if err != nil {
+		return Findings{{
+			Severity: SeverityError,
+			Feed:     name,
+			Rule:     RuleSchema,
+			Message:  err.Error(),
+		}}
+	}
+	return SchemaFindings(name, errs)
+}
+
+// SchemaFindings converts schema errors of feed, e.g. returned by client
+// with ValidateSchema option, to findings.
+func SchemaFindings(name string, errs schema.Errors) Findings {
+	findings := Findings{}
+	for _, e := range errs {
+		findings = append(findings, &Finding{
+			Severity: SeverityError,
+			Feed:     name,
+			Path:     instancePath(e.InstancePath),
+			Rule:     RuleSchema,
+			Message:  e.Message + " (" + e.SchemaPath + ")",
+		})
+	}
+	return findings
+}
+
+// instancePath converts JSON pointer to path used by findings, e.g.
+// /data/stations/0/lat to data.stations[0].lat.
+func instancePath(pointer string) string {
+	path := ""
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		if _, err := strconv.Atoi(token); err == nil {
+			path += "[" + token + "]"
+			continue
+		}
+		if path != "" {
+			path += "."
+		}
+		path += token
+	}
+	return path
+}
@@ -0,0 +1,360 @@
+package validate
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+
+	gbfs "github.com/petoc/gbfs/v2"
+)
+
+const (
+	RuleReference    = "reference"
+	RuleVehicleCount = "vehicle_count"
+	RuleCapacity     = "capacity"
+	RuleRequiredFeed = "required_feed"
+)
+
+// System checks consistency of snapshot of system feeds in single language
+// keyed by feed name: references between feeds, stations missing in
+// station_status, counts of vehicles per type, capacity of stations and
+// feeds missing in gbfs.json.
+func System(feeds map[string]gbfs.Feed) Findings {
+	findings := Findings{}
+	names := []string{}
+	for name, feed := range feeds {
+		if present(feed) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		findings = append(findings, validateReferences(feeds[name], feeds)...)
+	}
+	findings = append(findings, validateStations(feeds)...)
+	findings = append(findings, validateDiscovery(feeds, names)...)
+	return findings
+}
+
+func present(feed gbfs.Feed) bool {
+	return feed != nil && !reflect.ValueOf(feed).IsNil()
+}
+
+// validateReferences checks that identifiers referenced by feed exist in
+// other feeds, references to feeds which are not present are not checked.
+// Null elements of lists are skipped.
+func validateReferences(feed gbfs.Feed, feeds map[string]gbfs.Feed) Findings {
+	findings := Findings{}
+	stationIDs := feedIDs(feeds[gbfs.FeedNameStationInformation])
+	vehicleTypeIDs := feedIDs(feeds[gbfs.FeedNameVehicleTypes])
+	regionIDs := feedIDs(feeds[gbfs.FeedNameSystemRegions])
+	check := func(ids map[gbfs.ID]bool, id *gbfs.ID, path, target string) {
+		if ids == nil || id == nil || ids[*id] {
+			return
+		}
+		findings = append(findings, &Finding{
+			Severity: SeverityError,
+			Feed:     feed.Name(),
+			Path:     path,
+			Rule:     RuleReference,
+			Message:  strconv.Quote(string(*id)) + " not found in " + target,
+		})
+	}
+	switch f := feed.(type) {
+	case *gbfs.FeedStationStatus:
+		if f.Data == nil {
+			break
+		}
+		for i, v := range f.Data.Stations {
+			if v == nil {
+				continue
+			}
+			path := "data.stations[" + strconv.Itoa(i) + "]"
+			check(stationIDs, v.StationID, path+".station_id", gbfs.FeedNameStationInformation)
+			for j, t := range v.VehicleTypesAvailable {
+				if t == nil {
+					continue
+				}
+				check(vehicleTypeIDs, t.VehicleTypeID, path+".vehicle_types_available["+strconv.Itoa(j)+"].vehicle_type_id", gbfs.FeedNameVehicleTypes)
+			}
+			for j, d := range v.VehicleDocksAvailable {
+				if d == nil {
+					continue
+				}
+				for k, id := range d.VehicleTypeIDs {
+					check(vehicleTypeIDs, id, path+".vehicle_docks_available["+strconv.Itoa(j)+"].vehicle_type_ids["+strconv.Itoa(k)+"]", gbfs.FeedNameVehicleTypes)
+				}
+			}
+		}
+	case *gbfs.FeedFreeBikeStatus:
+		if f.Data == nil {
+			break
+		}
+		for i, v := range f.Data.Bikes {
+			if v == nil {
+				continue
+			}
+			path := "data.bikes[" + strconv.Itoa(i) + "]"
+			check(vehicleTypeIDs, v.VehicleTypeID, path+".vehicle_type_id", gbfs.FeedNameVehicleTypes)
+		}
+	case *gbfs.FeedStationInformation:
+		if f.Data == nil {
+			break
+		}
+		for i, v := range f.Data.Stations {
+			if v == nil {
+				continue
+			}
+			path := "data.stations[" + strconv.Itoa(i) + "]"
+			check(regionIDs, v.RegionID, path+".region_id", gbfs.FeedNameSystemRegions)
+			for _, id := range sortedIDs(v.VehicleCapacity) {
+				check(vehicleTypeIDs, &id, path+".vehicle_capacity."+string(id), gbfs.FeedNameVehicleTypes)
+			}
+			for _, id := range sortedIDs(v.VehicleTypeCapacity) {
+				check(vehicleTypeIDs, &id, path+".vehicle_type_capacity."+string(id), gbfs.FeedNameVehicleTypes)
+			}
+		}
+	case *gbfs.FeedSystemAlerts:
+		if f.Data == nil {
+			break
+		}
+		for i, v := range f.Data.Alerts {
+			if v == nil {
+				continue
+			}
+			path := "data.alerts[" + strconv.Itoa(i) + "]"
+			for j, id := range v.StationIDs {
+				check(stationIDs, id, path+".station_ids["+strconv.Itoa(j)+"]", gbfs.FeedNameStationInformation)
+			}
+			for j, id := range v.RegionIDs {
+				check(regionIDs, id, path+".region_ids["+strconv.Itoa(j)+"]", gbfs.FeedNameSystemRegions)
+			}
+		}
+	}
+	return findings
+}
+
+func sortedIDs(m map[gbfs.ID]int64) []gbfs.ID {
+	ids := make([]gbfs.ID, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// feedIDs returns set of primary identifiers of feed, or nil when feed is
+// not available.
+func feedIDs(feed gbfs.Feed) map[gbfs.ID]bool {
+	if !present(feed) {
+		return nil
+	}
+	ids := map[gbfs.ID]bool{}
+	add := func(id *gbfs.ID) {
+		if id != nil {
+			ids[*id] = true
+		}
+	}
+	switch f := feed.(type) {
+	case *gbfs.FeedStationInformation:
+		if f.Data == nil {
+			return nil
+		}
+		for _, v := range f.Data.Stations {
+			if v == nil {
+				continue
+			}
+			add(v.StationID)
+		}
+	case *gbfs.FeedVehicleTypes:
+		if f.Data == nil {
+			return nil
+		}
+		for _, v := range f.Data.VehicleTypes {
+			if v == nil {
+				continue
+			}
+			add(v.VehicleTypeID)
+		}
+	case *gbfs.FeedSystemRegions:
+		if f.Data == nil {
+			return nil
+		}
+		for _, v := range f.Data.Regions {
+			if v == nil {
+				continue
+			}
+			add(v.RegionID)
+		}
+	default:
+		return nil
+	}
+	return ids
+}
+
+func validateStations(feeds map[string]gbfs.Feed) Findings {
+	findings := Findings{}
+	info, _ := feeds[gbfs.FeedNameStationInformation].(*gbfs.FeedStationInformation)
+	status, _ := feeds[gbfs.FeedNameStationStatus].(*gbfs.FeedStationStatus)
+	if info == nil || info.Data == nil || status == nil || status.Data == nil {
+		return findings
+	}
+	stations := map[gbfs.ID]*gbfs.FeedStationInformationStation{}
+	for _, v := range info.Data.Stations {
+		if v != nil && v.StationID != nil {
+			stations[*v.StationID] = v
+		}
+	}
+	reported := map[gbfs.ID]bool{}
+	for i, v := range status.Data.Stations {
+		if v == nil || v.StationID == nil {
+			continue
+		}
+		reported[*v.StationID] = true
+		path := "data.stations[" + strconv.Itoa(i) + "]"
+		if len(v.VehicleTypesAvailable) > 0 && v.NumBikesAvailable != nil {
+			var sum int64
+			for _, t := range v.VehicleTypesAvailable {
+				if t != nil && t.Count != nil {
+					sum += *t.Count
+				}
+			}
+			if sum != *v.NumBikesAvailable {
+				findings = append(findings, &Finding{
+					Severity: SeverityError,
+					Feed:     gbfs.FeedNameStationStatus,
+					Path:     path + ".vehicle_types_available",
+					Rule:     RuleVehicleCount,
+					Message:  "sum of counts " + strconv.FormatInt(sum, 10) + " does not match num_bikes_available " + strconv.FormatInt(*v.NumBikesAvailable, 10),
+				})
+			}
+		}
+		station, ok := stations[*v.StationID]
+		if !ok || station.Capacity == nil {
+			continue
+		}
+		var used int64
+		if v.NumDocksAvailable != nil {
+			used += *v.NumDocksAvailable
+		}
+		if v.NumBikesAvailable != nil {
+			used += *v.NumBikesAvailable
+		}
+		if used > *station.Capacity {
+			findings = append(findings, &Finding{
+				Severity: SeverityError,
+				Feed:     gbfs.FeedNameStationStatus,
+				Path:     path,
+				Rule:     RuleCapacity,
+				Message:  "num_docks_available and num_bikes_available " + strconv.FormatInt(used, 10) + " exceed capacity " + strconv.FormatInt(*station.Capacity, 10),
+			})
+		}
+	}
+	for i, v := range info.Data.Stations {
+		if v == nil || v.StationID == nil || reported[*v.StationID] {
+			continue
+		}
+		findings = append(findings, &Finding{
+			Severity: SeverityError,
+			Feed:     gbfs.FeedNameStationInformation,
+			Path:     "data.stations[" + strconv.Itoa(i) + "].station_id",
+			Rule:     RuleReference,
+			Message:  strconv.Quote(string(*v.StationID)) + " not found in " + gbfs.FeedNameStationStatus,
+		})
+	}
+	return findings
+}
+
+// validateDiscovery checks that gbfs.json lists system_information, feeds
+// present in snapshot and feeds referenced by them in each language.
+func validateDiscovery(feeds map[string]gbfs.Feed, names []string) Findings {
+	findings := Findings{}
+	g, _ := feeds[gbfs.FeedNameGbfs].(*gbfs.FeedGbfs)
+	if g == nil || len(g.Data) == 0 {
+		return append(findings, &Finding{
+			Severity: SeverityError,
+			Feed:     gbfs.FeedNameGbfs,
+			Path:     "data",
+			Rule:     RuleRequiredFeed,
+			Message:  "missing gbfs feed",
+		})
+	}
+	required := map[string]string{
+		gbfs.FeedNameSystemInformation: "required feed",
+	}
+	for _, name := range names {
+		if name != gbfs.FeedNameGbfs {
+			required[name] = "present in snapshot"
+		}
+	}
+	for name, by := range referencedFeeds(feeds) {
+		required[name] = "referenced by " + by
+	}
+	for _, language := range sortedKeys(g.Data) {
+		listed := map[string]bool{}
+		if l := g.Data[language]; l != nil {
+			for _, f := range l.Feeds {
+				if f != nil && f.Name != nil {
+					listed[*f.Name] = true
+				}
+			}
+		}
+		missing := []string{}
+		for name, by := range required {
+			if !listed[name] {
+				missing = append(missing, name+" not listed, "+by)
+			}
+		}
+		if listed[gbfs.FeedNameStationInformation] || listed[gbfs.FeedNameStationStatus] {
+			for _, name := range []string{gbfs.FeedNameStationInformation, gbfs.FeedNameStationStatus} {
+				if !listed[name] && required[name] == "" {
+					missing = append(missing, name+" not listed, required for docked system")
+				}
+			}
+		}
+		sort.Strings(missing)
+		for _, msg := range missing {
+			findings = append(findings, &Finding{
+				Severity: SeverityError,
+				Feed:     gbfs.FeedNameGbfs,
+				Path:     "data." + language + ".feeds",
+				Rule:     RuleRequiredFeed,
+				Message:  msg,
+			})
+		}
+	}
+	return findings
+}
+
+// referencedFeeds returns feeds referenced by identifiers in snapshot,
+// mapped to name of referencing feed.
+func referencedFeeds(feeds map[string]gbfs.Feed) map[string]string {
+	refs := map[string]string{}
+	if f, ok := feeds[gbfs.FeedNameFreeBikeStatus].(*gbfs.FeedFreeBikeStatus); ok && f != nil && f.Data != nil {
+		for _, v := range f.Data.Bikes {
+			if v == nil {
+				continue
+			}
+			if v.VehicleTypeID != nil {
+				refs[gbfs.FeedNameVehicleTypes] = f.Name()
+			}
+		}
+	}
+	if f, ok := feeds[gbfs.FeedNameStationStatus].(*gbfs.FeedStationStatus); ok && f != nil && f.Data != nil {
+		for _, v := range f.Data.Stations {
+			if v != nil && len(v.VehicleTypesAvailable) > 0 {
+				refs[gbfs.FeedNameVehicleTypes] = f.Name()
+			}
+		}
+	}
+	if f, ok := feeds[gbfs.FeedNameStationInformation].(*gbfs.FeedStationInformation); ok && f != nil && f.Data != nil {
+		for _, v := range f.Data.Stations {
+			if v != nil && v.RegionID != nil {
+				refs[gbfs.FeedNameSystemRegions] = f.Name()
+			}
+		}
+	}
+	return refs
+}
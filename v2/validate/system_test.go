@@ -0,0 +1,30 @@
+package validate
+
+import (
+	"encoding/json"
+	"testing"
+
+	gbfs "github.com/petoc/gbfs/v2"
+)
+
+func TestSystemNullElements(t *testing.T) {
+	docs := map[string]string{
+		gbfs.FeedNameGbfs:               `{"data":{"en":{"feeds":[null,{"name":"station_information"}]}}}`,
+		gbfs.FeedNameStationInformation: `{"data":{"stations":[null,{"station_id":"s1"}]}}`,
+		gbfs.FeedNameStationStatus:      `{"data":{"stations":[null,{"station_id":"s1","vehicle_types_available":[null],"vehicle_docks_available":[null]}]}}`,
+		gbfs.FeedNameFreeBikeStatus:     `{"data":{"bikes":[null]}}`,
+		gbfs.FeedNameVehicleTypes:       `{"data":{"vehicle_types":[null]}}`,
+		gbfs.FeedNameSystemAlerts:       `{"data":{"alerts":[null,{"station_ids":[null]}]}}`,
+		gbfs.FeedNameSystemRegions:      `{"data":{"regions":[null]}}`,
+		gbfs.FeedNameSystemPricingPlans: `{"data":{"plans":[null]}}`,
+	}
+	feeds := map[string]gbfs.Feed{}
+	for name, doc := range docs {
+		f := gbfs.FeedStruct(name)
+		if err := json.Unmarshal([]byte(doc), f); err != nil {
+			t.Fatal(name, err)
+		}
+		feeds[name] = f
+	}
+	System(feeds)
+}
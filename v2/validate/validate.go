@@ -0,0 +1,341 @@
+// Package validate checks decoded feeds against rules of GBFS specification
+// and reports findings with severity, JSON path and rule ID.
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	gbfs "github.com/petoc/gbfs/v2"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+
+	RuleRequired              = "required"
+	RuleConditionallyRequired = "conditionally_required"
+	RuleVersion               = "version"
+	RuleEnum                  = "enum"
+	RuleRange                 = "range"
+	RuleNonNegative           = "non_negative"
+	RuleFormat                = "format"
+	RuleLanguage              = "language"
+)
+
+type (
+	// Finding is single violation of rule.
+	Finding struct {
+		Severity string `json:"severity"`
+		Feed     string `json:"feed"`
+		Path     string `json:"path"`
+		Rule     string `json:"rule"`
+		Message  string `json:"message"`
+	}
+	// Findings ...
+	Findings []*Finding
+)
+
+// Error ...
+func (e *Finding) Error() string {
+	return e.Severity + ": " + e.Feed + ": " + e.Path + ": " + e.Message + " (" + e.Rule + ")"
+}
+
+// Error ...
+func (e Findings) Error() string {
+	msgs := []string{}
+	for _, v := range e {
+		msgs = append(msgs, v.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// HasErrors reports whether there is at least one finding with error
+// severity.
+func (e Findings) HasErrors() bool {
+	for _, v := range e {
+		if v.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Feed checks feed against rules of given version: required fields, fields
+// not defined in version, enum membership, coordinate ranges, non-negative
+// numbers, formats of dates and times, and language codes.
+func Feed(feed gbfs.Feed, version string) Findings {
+	w := &walker{
+		feed:     feed.Name(),
+		version:  version,
+		rules:    fieldRules[feed.Name()],
+		since:    fieldVersions[feed.Name()],
+		findings: Findings{},
+	}
+	if !slices.Contains(versions, version) {
+		w.add(SeverityWarning, "version", RuleVersion, "unsupported version "+strconv.Quote(version))
+		return w.findings
+	}
+	if version >= gbfs.V11 && feed.GetVersion() == "" {
+		w.add(SeverityError, "version", RuleRequired, "missing required field")
+	}
+	w.required("", reflect.ValueOf(feed))
+	w.conditional(feed)
+	if f, ok := feed.(*gbfs.FeedGbfs); ok {
+		for _, k := range sortedKeys(f.Data) {
+			if msg := language.check(k); msg != "" {
+				w.add(SeverityError, "data."+k, RuleLanguage, msg)
+			}
+		}
+	}
+	b, err := json.Marshal(feed)
+	if err != nil {
+		return w.findings
+	}
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	err = d.Decode(&v)
+	if err != nil {
+		return w.findings
+	}
+	w.walk("", "", "", v)
+	return w.findings
+}
+
+var versions = []string{gbfs.V10, gbfs.V11, gbfs.V20, gbfs.V21, gbfs.V22, gbfs.V23}
+
+type walker struct {
+	feed     string
+	version  string
+	rules    map[string]*rule
+	since    map[string]string
+	findings Findings
+}
+
+func (w *walker) add(severity, path, rule, message string) {
+	w.findings = append(w.findings, &Finding{
+		Severity: severity,
+		Feed:     w.feed,
+		Path:     path,
+		Rule:     rule,
+		Message:  message,
+	})
+}
+
+// required checks fields without omitempty in json tag, except
+// conditionally required fields.
+func (w *walker) required(path string, v reflect.Value) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			elemPath := path + "[" + strconv.Itoa(i) + "]"
+			if isNil(v.Index(i)) {
+				// null element of list
+				w.add(SeverityError, elemPath, RuleRequired, "missing required field")
+				continue
+			}
+			w.required(elemPath, v.Index(i))
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		for _, k := range keys {
+			w.required(path+"."+k.String(), v.MapIndex(k))
+		}
+	case reflect.Struct:
+		for _, field := range jsonFields(v) {
+			fieldPath := field.name
+			if path != "" {
+				fieldPath = path + "." + field.name
+			}
+			if !field.omitempty && isNil(field.value) && !conditionalFields[w.feed+"."+genericPath(fieldPath)] {
+				w.add(SeverityError, fieldPath, RuleRequired, "missing required field")
+				continue
+			}
+			w.required(fieldPath, field.value)
+		}
+	}
+}
+
+// conditionalFields are fields without omitempty, which are required only
+// in some cases.
+var conditionalFields = map[string]bool{
+	gbfs.FeedNameFreeBikeStatus + ".data.bikes[].lat": true,
+	gbfs.FeedNameFreeBikeStatus + ".data.bikes[].lon": true,
+}
+
+func (w *walker) conditional(feed gbfs.Feed) {
+	switch f := feed.(type) {
+	case *gbfs.FeedFreeBikeStatus:
+		if f.Data == nil {
+			return
+		}
+		for i, v := range f.Data.Bikes {
+			// since 2.1 lat and lon may be left out for bikes at station,
+			// station_id is not part of feed model
+			if v == nil || w.version >= gbfs.V21 {
+				continue
+			}
+			path := "data.bikes[" + strconv.Itoa(i) + "]"
+			if v.Lat == nil {
+				w.add(SeverityError, path+".lat", RuleConditionallyRequired, "required before version 2.1")
+			}
+			if v.Lon == nil {
+				w.add(SeverityError, path+".lon", RuleConditionallyRequired, "required before version 2.1")
+			}
+		}
+	case *gbfs.FeedVehicleTypes:
+		if f.Data == nil {
+			return
+		}
+		for i, v := range f.Data.VehicleTypes {
+			if v == nil || v.PropulsionType == nil || *v.PropulsionType == gbfs.PropulsionTypeHuman {
+				continue
+			}
+			if v.MaxRangeMeters == nil {
+				w.add(SeverityError, "data.vehicle_types["+strconv.Itoa(i)+"].max_range_meters", RuleConditionallyRequired, "required for motorized vehicles")
+			}
+		}
+	}
+}
+
+// walk applies rules to values of decoded feed. Path is JSON path of value,
+// generic path is path without indexes used to look up rules.
+func (w *walker) walk(path, generic, key string, v interface{}) {
+	if since, ok := w.since[generic]; ok && w.version < since {
+		w.add(SeverityWarning, path, RuleVersion, "field not defined in version "+w.version+", added in "+since)
+	}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(t) {
+			if skipKeys[k] {
+				continue
+			}
+			if path == "" {
+				w.walk(k, k, k, t[k])
+				continue
+			}
+			w.walk(path+"."+k, generic+"."+k, k, t[k])
+		}
+	case []interface{}:
+		for i, item := range t {
+			w.walk(path+"["+strconv.Itoa(i)+"]", generic+"[]", key, item)
+		}
+	case nil:
+	default:
+		r, ok := w.rules[generic]
+		if !ok {
+			r, ok = keyRules[key]
+		}
+		if !ok {
+			if _, isNumber := v.(json.Number); !isNumber {
+				return
+			}
+			r = nonNegative
+		}
+		if msg := r.check(v); msg != "" {
+			w.add(SeverityError, path, r.id, msg)
+		}
+	}
+}
+
+type jsonField struct {
+	name      string
+	omitempty bool
+	value     reflect.Value
+}
+
+// jsonFields returns fields of struct as seen by encoding/json, fields of
+// embedded structs are shadowed by fields of outer struct.
+func jsonFields(v reflect.Value) []*jsonField {
+	fields := []*jsonField{}
+	embedded := []reflect.Value{}
+	seen := map[string]bool{}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			if f.Type.Kind() == reflect.Struct {
+				embedded = append(embedded, v.Field(i))
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name := strings.TrimSpace(parts[0])
+		if name == "" {
+			name = f.Name
+		}
+		seen[name] = true
+		fields = append(fields, &jsonField{
+			name:      name,
+			omitempty: slices.Contains(parts[1:], "omitempty"),
+			value:     v.Field(i),
+		})
+	}
+	for _, e := range embedded {
+		for _, field := range jsonFields(e) {
+			if !seen[field.name] {
+				seen[field.name] = true
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	}
+	return false
+}
+
+// genericPath removes indexes from path.
+func genericPath(path string) string {
+	b := strings.Builder{}
+	skip := false
+	for _, r := range path {
+		switch {
+		case r == '[':
+			skip = true
+			b.WriteRune(r)
+		case r == ']':
+			skip = false
+			b.WriteRune(r)
+		case !skip:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,109 @@
+package validate
+
+import (
+	"encoding/json"
+	"testing"
+
+	gbfs "github.com/petoc/gbfs/v2"
+)
+
+func TestFeedRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		feed     string
+		version  string
+		doc      string
+		expected []string
+		absent   []string
+	}{
+		{
+			name:     "required fields",
+			feed:     gbfs.FeedNameSystemInformation,
+			version:  gbfs.V23,
+			doc:      `{"version":"2.3","data":{"system_id":"s","language":"en","timezone":"Europe/Bratislava"}}`,
+			expected: []string{"data.name required"},
+			absent:   []string{"data.system_id required", "version required"},
+		},
+		{
+			name:     "missing version",
+			feed:     gbfs.FeedNameSystemInformation,
+			version:  gbfs.V23,
+			doc:      `{"data":{"system_id":"s","language":"en","name":"n","timezone":"Europe/Bratislava"}}`,
+			expected: []string{"version required"},
+		},
+		{
+			name:    "version not required in 1.0",
+			feed:    gbfs.FeedNameSystemInformation,
+			version: gbfs.V10,
+			doc:     `{"data":{"system_id":"s","language":"en","name":"n","timezone":"Europe/Bratislava"}}`,
+			absent:  []string{"version required"},
+		},
+		{
+			name:     "unsupported version",
+			feed:     gbfs.FeedNameSystemInformation,
+			version:  "9.9",
+			doc:      `{"data":{}}`,
+			expected: []string{"version version"},
+			absent:   []string{"data.name required"},
+		},
+		{
+			name:     "bike position before 2.1",
+			feed:     gbfs.FeedNameFreeBikeStatus,
+			version:  gbfs.V20,
+			doc:      `{"version":"2.0","data":{"bikes":[{"bike_id":"b1","is_reserved":false,"is_disabled":false}]}}`,
+			expected: []string{"data.bikes[0].lat conditionally_required", "data.bikes[0].lon conditionally_required"},
+			absent:   []string{"data.bikes[0].lat required"},
+		},
+		{
+			name:    "bike position since 2.1",
+			feed:    gbfs.FeedNameFreeBikeStatus,
+			version: gbfs.V21,
+			doc:     `{"version":"2.1","data":{"bikes":[{"bike_id":"b1","is_reserved":false,"is_disabled":false}]}}`,
+			absent:  []string{"data.bikes[0].lat conditionally_required", "data.bikes[0].lat required"},
+		},
+		{
+			name:     "range of motorized vehicles",
+			feed:     gbfs.FeedNameVehicleTypes,
+			version:  gbfs.V23,
+			doc:      `{"version":"2.3","data":{"vehicle_types":[{"vehicle_type_id":"e","form_factor":"scooter","propulsion_type":"electric"},{"vehicle_type_id":"h","form_factor":"bicycle","propulsion_type":"human"}]}}`,
+			expected: []string{"data.vehicle_types[0].max_range_meters conditionally_required"},
+			absent:   []string{"data.vehicle_types[1].max_range_meters conditionally_required"},
+		},
+		{
+			name:     "null elements",
+			feed:     gbfs.FeedNameStationStatus,
+			version:  gbfs.V23,
+			doc:      `{"version":"2.3","data":{"stations":[null,{"station_id":"s1","num_bikes_available":1,"num_docks_available":1,"is_installed":true,"is_renting":true,"is_returning":true,"last_reported":1,"vehicle_types_available":[null]}]}}`,
+			expected: []string{"data.stations[0] required", "data.stations[1].vehicle_types_available[0] required"},
+			absent:   []string{"data.stations[1].station_id required"},
+		},
+		{
+			name:     "null elements of free bikes",
+			feed:     gbfs.FeedNameFreeBikeStatus,
+			version:  gbfs.V20,
+			doc:      `{"version":"2.0","data":{"bikes":[null]}}`,
+			expected: []string{"data.bikes[0] required"},
+			absent:   []string{"data.bikes[0].lat conditionally_required"},
+		},
+	}
+	for _, test := range tests {
+		f := gbfs.FeedStruct(test.feed)
+		if err := json.Unmarshal([]byte(test.doc), f); err != nil {
+			t.Fatal(test.name, err)
+		}
+		found := map[string]bool{}
+		for _, finding := range Feed(f, test.version) {
+			found[finding.Path+" "+finding.Rule] = true
+		}
+		for _, v := range test.expected {
+			if !found[v] {
+				t.Errorf("%s: expected finding %s, got %v", test.name, v, found)
+			}
+		}
+		for _, v := range test.absent {
+			if found[v] {
+				t.Errorf("%s: unexpected finding %s", test.name, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package gbfs
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrInvalidGeometry is returned, when geometry of privacy zone is not
+// valid Polygon or MultiPolygon.
+var ErrInvalidGeometry = errors.New("invalid geometry")
+
+// DefaultPrivacyZonePrecision is number of decimal places coordinates are
+// rounded to in privacy zone without Precision, about 110 meters.
+const DefaultPrivacyZonePrecision = 3
+
+type (
+	// VehicleFilter removes vehicles from free_bike_status and coarsens their
+	// positions before feed is published.
+	VehicleFilter struct {
+		ExcludeReserved bool
+		ExcludeDisabled bool
+		// Exclude removes vehicles for which it returns true.
+		Exclude func(vehicle *FeedFreeBikeStatusBike) bool
+		// Zones coarsen coordinates of vehicles inside them, first matching
+		// zone is used.
+		Zones []*PrivacyZone
+		// ParkedDelay hides vehicles until they stay parked at the same
+		// position (within ParkedDistance meters, default 50) for given
+		// duration. Vehicles of first filtered feed are considered
+		// parked already, unless DelayInitialVehicles is set, so that fleet
+		// is not hidden after restart.
+		ParkedDelay          time.Duration
+		ParkedDistance       float64
+		DelayInitialVehicles bool
+		mu                   sync.Mutex
+		parked               map[ID]*parkedVehicle
+		started              bool
+	}
+	// PrivacyZone snaps coordinates of vehicles inside Polygon or
+	// MultiPolygon geometry to SnapLat and SnapLon, or rounds them to
+	// Precision decimal places, when snap point is not set. Zero Precision
+	// means DefaultPrivacyZonePrecision.
+	PrivacyZone struct {
+		Geometry  *GeoJSONGeometry
+		SnapLat   *float64
+		SnapLon   *float64
+		Precision int
+		once      sync.Once
+		polygons  [][][][]float64
+		err       error
+	}
+	parkedVehicle struct {
+		lat   *float64
+		lon   *float64
+		since time.Time
+	}
+)
+
+// FilterFeed returns copy of free_bike_status feed with vehicles filtered,
+// other feeds are returned unchanged.
+func (f *VehicleFilter) FilterFeed(feed Feed) Feed {
+	v, ok := feed.(*FeedFreeBikeStatus)
+	if !ok || v == nil || v.Data == nil {
+		return feed
+	}
+	now := time.Now()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	seen := map[ID]bool{}
+	vehicles := []*FeedFreeBikeStatusBike{}
+	for _, vehicle := range v.Data.Bikes {
+		if vehicle == nil {
+			continue
+		}
+		if vehicle.BikeID != nil {
+			seen[*vehicle.BikeID] = true
+		}
+		if !f.parkedLongEnough(vehicle, now) || f.excluded(vehicle) {
+			continue
+		}
+		vehicles = append(vehicles, f.coarsen(vehicle))
+	}
+	for id := range f.parked {
+		if !seen[id] {
+			delete(f.parked, id)
+		}
+	}
+	f.started = true
+	filtered := &FeedFreeBikeStatus{
+		Data: &FeedFreeBikeStatusData{
+			Bikes: vehicles,
+		},
+	}
+	filtered.Language = v.Language
+	filtered.LastUpdated = v.LastUpdated
+	filtered.TTL = v.TTL
+	filtered.Version = v.Version
+	return filtered
+}
+
+func (f *VehicleFilter) excluded(vehicle *FeedFreeBikeStatusBike) bool {
+	if f.ExcludeReserved && vehicle.IsReserved != nil && bool(*vehicle.IsReserved) {
+		return true
+	}
+	if f.ExcludeDisabled && vehicle.IsDisabled != nil && bool(*vehicle.IsDisabled) {
+		return true
+	}
+	return f.Exclude != nil && f.Exclude(vehicle)
+}
+
+func (f *VehicleFilter) parkedLongEnough(vehicle *FeedFreeBikeStatusBike, now time.Time) bool {
+	if f.ParkedDelay <= 0 || vehicle.BikeID == nil {
+		return true
+	}
+	if f.parked == nil {
+		f.parked = make(map[ID]*parkedVehicle)
+	}
+	current := &parkedVehicle{
+		since: now,
+	}
+	if vehicle.Lat != nil && vehicle.Lon != nil {
+		current.lat = NewFloat64(vehicle.Lat.Float64)
+		current.lon = NewFloat64(vehicle.Lon.Float64)
+	}
+	prev, ok := f.parked[*vehicle.BikeID]
+	if !ok && !f.started && !f.DelayInitialVehicles {
+		current.since = now.Add(-f.ParkedDelay)
+		f.parked[*vehicle.BikeID] = current
+		return true
+	}
+	if !ok || f.moved(prev, current) {
+		f.parked[*vehicle.BikeID] = current
+		return false
+	}
+	return now.Sub(prev.since) >= f.ParkedDelay
+}
+
+func (f *VehicleFilter) moved(prev, current *parkedVehicle) bool {
+	if prev.lat == nil || current.lat == nil {
+		return (prev.lat == nil) != (current.lat == nil)
+	}
+	maxDistance := f.ParkedDistance
+	if maxDistance <= 0 {
+		maxDistance = 50
+	}
+	return distance(*prev.lat, *prev.lon, *current.lat, *current.lon) > maxDistance
+}
+
+func (f *VehicleFilter) coarsen(vehicle *FeedFreeBikeStatusBike) *FeedFreeBikeStatusBike {
+	if vehicle.Lat == nil || vehicle.Lon == nil {
+		return vehicle
+	}
+	for _, zone := range f.Zones {
+		if !zone.Contains(vehicle.Lat.Float64, vehicle.Lon.Float64) {
+			continue
+		}
+		lat, lon := zone.coarsen(vehicle.Lat.Float64, vehicle.Lon.Float64)
+		coarsened := *vehicle
+		coarsened.Lat = NewCoordinate(lat)
+		coarsened.Lon = NewCoordinate(lon)
+		return &coarsened
+	}
+	return vehicle
+}
+
+func (z *PrivacyZone) coarsen(lat, lon float64) (float64, float64) {
+	if z.SnapLat != nil && z.SnapLon != nil {
+		return *z.SnapLat, *z.SnapLon
+	}
+	precision := z.Precision
+	if precision == 0 {
+		precision = DefaultPrivacyZonePrecision
+	}
+	p := math.Pow(10, float64(precision))
+	return math.Round(lat*p) / p, math.Round(lon*p) / p
+}
+
+// Validate returns error, when geometry of zone is not valid Polygon or
+// MultiPolygon.
+func (z *PrivacyZone) Validate() error {
+	z.once.Do(func() {
+		z.polygons, z.err = geometryPolygons(z.Geometry)
+	})
+	return z.err
+}
+
+// Contains reports whether point is inside zone. Zone with invalid geometry
+// contains no point.
+func (z *PrivacyZone) Contains(lat, lon float64) bool {
+	if z.Validate() != nil {
+		return false
+	}
+	for _, polygon := range z.polygons {
+		if polygonContains(polygon, lon, lat) {
+			return true
+		}
+	}
+	return false
+}
+
+// geometryPolygons returns polygons of Polygon or MultiPolygon geometry.
+func geometryPolygons(geometry *GeoJSONGeometry) ([][][][]float64, error) {
+	if geometry == nil {
+		return nil, NewError("missing geometry: ", ErrInvalidGeometry)
+	}
+	b, err := json.Marshal(geometry.Coordinates)
+	if err != nil {
+		return nil, NewError("invalid coordinates: ", ErrInvalidGeometry)
+	}
+	switch geometry.Type {
+	case "Polygon":
+		polygon := [][][]float64{}
+		if json.Unmarshal(b, &polygon) != nil {
+			return nil, NewError("invalid coordinates: ", ErrInvalidGeometry)
+		}
+		return [][][][]float64{polygon}, nil
+	case "MultiPolygon":
+		polygons := [][][][]float64{}
+		if json.Unmarshal(b, &polygons) != nil {
+			return nil, NewError("invalid coordinates: ", ErrInvalidGeometry)
+		}
+		return polygons, nil
+	}
+	return nil, NewError("not a Polygon or MultiPolygon: ", ErrInvalidGeometry)
+}
+
+// polygonContains reports whether point is inside first ring of polygon
+// and outside of its holes.
+func polygonContains(polygon [][][]float64, x, y float64) bool {
+	if len(polygon) == 0 || !ringContains(polygon[0], x, y) {
+		return false
+	}
+	for _, hole := range polygon[1:] {
+		if ringContains(hole, x, y) {
+			return false
+		}
+	}
+	return true
+}
+
+func ringContains(ring [][]float64, x, y float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		if len(ring[i]) < 2 || len(ring[j]) < 2 {
+			continue
+		}
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// distance returns great-circle distance in meters.
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371000
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
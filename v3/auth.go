@@ -0,0 +1,197 @@
+package gbfs
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrMissingConsumerStore = errors.New("missing consumer store")
+	ErrConsumerNotFound     = errors.New("consumer not found")
+)
+
+type (
+	// Consumer is registered consumer of feeds. RateLimit is number of
+	// requests per second with bursts up to Burst requests, zero values
+	// are taken from AuthOptions.
+	Consumer struct {
+		ID        string
+		RateLimit float64
+		Burst     int
+	}
+	// ConsumerStore looks up consumers by API key or bearer token. It
+	// returns ErrConsumerNotFound for unknown credentials.
+	ConsumerStore interface {
+		LookupConsumer(credential string) (*Consumer, error)
+	}
+	// MapConsumerStore is ConsumerStore keyed by credential.
+	MapConsumerStore map[string]*Consumer
+	// AuthOptions configure AuthHandler.
+	AuthOptions struct {
+		Store ConsumerStore
+		// Header with API key, default X-API-Key.
+		Header string
+		// QueryParameter with API key, default api_key. Set to "-" to accept
+		// API keys only in headers.
+		QueryParameter string
+		// RateLimit in requests per second, default 1.
+		RateLimit float64
+		// Burst, default 10.
+		Burst int
+	}
+	// ConsumerUsage counts requests of consumer.
+	ConsumerUsage struct {
+		ConsumerID  string    `json:"consumer_id"`
+		Requests    int64     `json:"requests"`
+		Limited     int64     `json:"limited"`
+		LastRequest time.Time `json:"last_request"`
+	}
+	// AuthHandler allows requests of registered consumers only and limits
+	// their rate with token bucket per consumer.
+	AuthHandler struct {
+		Options AuthOptions
+		next    http.Handler
+		mu      sync.Mutex
+		buckets map[string]*tokenBucket
+		usage   map[string]*ConsumerUsage
+		now     func() time.Time
+	}
+	tokenBucket struct {
+		tokens float64
+		last   time.Time
+	}
+)
+
+func (s MapConsumerStore) LookupConsumer(credential string) (*Consumer, error) {
+	if c, ok := s[credential]; ok {
+		return c, nil
+	}
+	return nil, ErrConsumerNotFound
+}
+
+func NewAuthHandler(next http.Handler, options AuthOptions) (*AuthHandler, error) {
+	if options.Store == nil {
+		return nil, ErrMissingConsumerStore
+	}
+	if options.Header == "" {
+		options.Header = "X-API-Key"
+	}
+	if options.QueryParameter == "" {
+		options.QueryParameter = "api_key"
+	}
+	if options.RateLimit <= 0 {
+		options.RateLimit = 1
+	}
+	if options.Burst <= 0 {
+		options.Burst = 10
+	}
+	return &AuthHandler{
+		Options: options,
+		next:    next,
+		buckets: make(map[string]*tokenBucket),
+		usage:   make(map[string]*ConsumerUsage),
+		now:     time.Now,
+	}, nil
+}
+
+// credential returns API key or bearer token of request.
+func (h *AuthHandler) credential(r *http.Request) string {
+	if v := r.Header.Get(h.Options.Header); v != "" {
+		return v
+	}
+	if v := r.Header.Get("Authorization"); len(v) > 7 && strings.EqualFold(v[:7], "bearer ") {
+		return strings.TrimSpace(v[7:])
+	}
+	if h.Options.QueryParameter != "-" {
+		return r.URL.Query().Get(h.Options.QueryParameter)
+	}
+	return ""
+}
+
+func (h *AuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	credential := h.credential(r)
+	if credential == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="gbfs"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	consumer, err := h.Options.Store.LookupConsumer(credential)
+	if errors.Is(err, ErrConsumerNotFound) || (err == nil && consumer == nil) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="gbfs", error="invalid_token"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	retryAfter := h.take(consumer)
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// take removes token from bucket of consumer and returns zero, or time
+// after which token will be available.
+func (h *AuthHandler) take(consumer *Consumer) time.Duration {
+	rate := consumer.RateLimit
+	if rate <= 0 {
+		rate = h.Options.RateLimit
+	}
+	burst := float64(consumer.Burst)
+	if burst <= 0 {
+		burst = float64(h.Options.Burst)
+	}
+	now := h.now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	usage, ok := h.usage[consumer.ID]
+	if !ok {
+		usage = &ConsumerUsage{
+			ConsumerID: consumer.ID,
+		}
+		h.usage[consumer.ID] = usage
+	}
+	usage.Requests++
+	usage.LastRequest = now
+	bucket, ok := h.buckets[consumer.ID]
+	if !ok {
+		bucket = &tokenBucket{
+			tokens: burst,
+			last:   now,
+		}
+		h.buckets[consumer.ID] = bucket
+	}
+	bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.last).Seconds()*rate)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		usage.Limited++
+		return time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+	}
+	bucket.tokens--
+	return 0
+}
+
+// Usage returns request counts of consumers since handler was created.
+func (h *AuthHandler) Usage() []*ConsumerUsage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	usage := []*ConsumerUsage{}
+	for _, v := range h.usage {
+		u := *v
+		usage = append(usage, &u)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].ConsumerID < usage[j].ConsumerID
+	})
+	return usage
+}
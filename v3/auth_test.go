@@ -0,0 +1,134 @@
+package gbfs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type failingConsumerStore struct{}
+
+func (failingConsumerStore) LookupConsumer(credential string) (*Consumer, error) {
+	return nil, errors.New("unavailable")
+}
+
+func testAuthHandler(t *testing.T, options AuthOptions) (*AuthHandler, *time.Time) {
+	t.Helper()
+	h, err := NewAuthHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	h.now = func() time.Time {
+		return now
+	}
+	return h, &now
+}
+
+func testAuthRequest(h http.Handler, target string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestAuthHandlerCredentials(t *testing.T) {
+	if _, err := NewAuthHandler(nil, AuthOptions{}); !errors.Is(err, ErrMissingConsumerStore) {
+		t.Fatalf("expected ErrMissingConsumerStore, got %v", err)
+	}
+	h, _ := testAuthHandler(t, AuthOptions{Store: MapConsumerStore{"key": {ID: "a"}}})
+	tests := []struct {
+		target   string
+		header   http.Header
+		expected int
+	}{
+		{"/gbfs.json", nil, http.StatusUnauthorized},
+		{"/gbfs.json", http.Header{"X-Api-Key": {"key"}}, http.StatusOK},
+		{"/gbfs.json", http.Header{"Authorization": {"Bearer key"}}, http.StatusOK},
+		{"/gbfs.json", http.Header{"Authorization": {"Basic key"}}, http.StatusUnauthorized},
+		{"/gbfs.json?api_key=key", nil, http.StatusOK},
+		{"/gbfs.json?api_key=other", nil, http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		if w := testAuthRequest(h, test.target, test.header); w.Code != test.expected {
+			t.Errorf("%s %v: expected status %d, got %d", test.target, test.header, test.expected, w.Code)
+		}
+	}
+	h, _ = testAuthHandler(t, AuthOptions{Store: MapConsumerStore{"key": {ID: "a"}}, QueryParameter: "-"})
+	if w := testAuthRequest(h, "/gbfs.json?api_key=key", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected query parameter to be ignored, got status %d", w.Code)
+	}
+	h, _ = testAuthHandler(t, AuthOptions{Store: failingConsumerStore{}})
+	if w := testAuthRequest(h, "/gbfs.json?api_key=key", nil); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 when store fails, got %d", w.Code)
+	}
+}
+
+func TestAuthHandlerTokenBucket(t *testing.T) {
+	h, now := testAuthHandler(t, AuthOptions{
+		Store: MapConsumerStore{
+			"a": {ID: "a"},
+			"b": {ID: "b", RateLimit: 10, Burst: 2},
+		},
+		RateLimit: 0.5,
+		Burst:     3,
+	})
+	request := func(key string) *httptest.ResponseRecorder {
+		return testAuthRequest(h, "/gbfs.json", http.Header{"X-Api-Key": {key}})
+	}
+	for i := 0; i < 3; i++ {
+		if w := request("a"); w.Code != http.StatusOK {
+			t.Fatalf("request %d within burst: expected status 200, got %d", i, w.Code)
+		}
+	}
+	w := request("a")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429 after burst, got %d", w.Code)
+	}
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter != "2" {
+		t.Errorf("expected Retry-After 2, got %s", retryAfter)
+	}
+	for i := 0; i < 2; i++ {
+		if w := request("b"); w.Code != http.StatusOK {
+			t.Fatalf("expected own bucket of consumer b, got status %d", w.Code)
+		}
+	}
+	if w := request("b"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected burst of consumer b to be 2, got status %d", w.Code)
+	}
+	*now = now.Add(time.Second)
+	if w := request("a"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected half token after 1 second, got status %d", w.Code)
+	}
+	*now = now.Add(time.Second)
+	if w := request("a"); w.Code != http.StatusOK {
+		t.Fatalf("expected token after 2 seconds, got status %d", w.Code)
+	}
+	for i := 0; i < 2; i++ {
+		if w := request("b"); w.Code != http.StatusOK {
+			t.Fatalf("expected bucket of consumer b refilled, got status %d", w.Code)
+		}
+	}
+	*now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		request("a")
+	}
+	if w := request("a"); w.Code != http.StatusTooManyRequests {
+		t.Fatal("expected refill to be capped by burst")
+	}
+	usage := h.Usage()
+	if len(usage) != 2 || usage[0].ConsumerID != "a" || usage[1].ConsumerID != "b" {
+		t.Fatalf("unexpected usage %v", usage)
+	}
+	if usage[0].Requests != 10 || usage[0].Limited != 3 || !usage[0].LastRequest.Equal(*now) {
+		t.Errorf("unexpected usage of consumer a %+v", usage[0])
+	}
+	if usage[1].Requests != 5 || usage[1].Limited != 1 {
+		t.Errorf("unexpected usage of consumer b %+v", usage[1])
+	}
+}
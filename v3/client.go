@@ -3,10 +3,13 @@ package gbfs
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"reflect"
 	"strconv"
 	"time"
+
+	"github.com/petoc/gbfs/v3/schema"
 )
 
 var (
@@ -27,10 +30,16 @@ type (
 		UserAgent        string
 		HTTPClient       *http.Client
 		Cache            Cache
+		// ValidateSchema validates raw response against embedded JSON Schema
+		// of feed before decoding. Violations are returned as schema.Errors.
+		ValidateSchema bool
 	}
 	ClientSubscribeOptions struct {
 		FeedNames []string
 		Handler   func(*Client, Feed, error)
+		// StreamURL of server stream handler. Feeds are received from stream
+		// instead of polling, until stream becomes unavailable.
+		StreamURL string
 	}
 )
 
@@ -91,13 +100,44 @@ func (c *Client) GetURL(url string, feed Feed) error {
 		}
 		return errors.New("invalid response status: " + strconv.Itoa(res.StatusCode))
 	}
-	err = json.NewDecoder(res.Body).Decode(feed)
+	if !c.Options.ValidateSchema {
+		return json.NewDecoder(res.Body).Decode(feed)
+	}
+	b, err := io.ReadAll(res.Body)
 	if err != nil {
 		return err
 	}
+	schemaErrs, err := validateSchema(feed.Name(), b)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, feed)
+	if err != nil {
+		return err
+	}
+	if len(schemaErrs) > 0 {
+		return schemaErrs
+	}
 	return nil
 }
 
+// validateSchema validates raw feed against embedded schema of version
+// declared in feed. Feeds of versions without embedded schema are skipped.
+func validateSchema(name string, b []byte) (schema.Errors, error) {
+	v := struct {
+		Version string `json:"version"`
+	}{}
+	_ = json.Unmarshal(b, &v)
+	if v.Version == "" {
+		v.Version = V30
+	}
+	errs, err := schema.Validate(v.Version, name, b)
+	if errors.Is(err, schema.ErrSchemaNotFound) {
+		return nil, nil
+	}
+	return errs, err
+}
+
 func (c *Client) Get(feed Feed) error {
 	cached, _ := cacheGet(c, feed)
 	if cached != nil {
@@ -139,6 +179,39 @@ func (c *Client) Get(feed Feed) error {
 	return nil
 }
 
+// Snapshot returns gbfs feed and all feeds listed in it keyed by feed name.
+// Feeds which failed to load are left out and their errors are joined.
+func (c *Client) Snapshot() (map[string]Feed, error) {
+	g := &FeedGbfs{}
+	err := c.Get(g)
+	if err != nil {
+		return nil, err
+	}
+	feeds := map[string]Feed{
+		g.Name(): g,
+	}
+	if g.Data == nil {
+		return feeds, nil
+	}
+	errs := []error{}
+	for _, feed := range g.Data.Feeds {
+		if feed == nil || feed.Name == nil {
+			continue
+		}
+		f := FeedStruct(*feed.Name)
+		if f == nil || f.Name() == FeedNameGbfs {
+			continue
+		}
+		err = c.Get(f)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		feeds[f.Name()] = f
+	}
+	return feeds, errors.Join(errs...)
+}
+
 func cloneValue(src, dst any) {
 	x := reflect.ValueOf(src)
 	if x.Kind() == reflect.Ptr {
@@ -158,6 +231,10 @@ func (c *Client) Subscribe(options ClientSubscribeOptions) error {
 	}
 	channel := make(chan any)
 	go (func() {
+		if options.StreamURL != "" {
+			err := c.stream(options, channel)
+			channel <- NewError("stream: ", err)
+		}
 		loops := []Feed{}
 		g := &FeedGbfs{}
 		err := c.Get(g)
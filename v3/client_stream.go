@@ -0,0 +1,124 @@
+package gbfs
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stream receives feeds from stream of server until stream becomes
+// unavailable. Dropped stream is reconnected, error is returned when
+// connection cannot be established.
+func (c *Client) stream(options ClientSubscribeOptions, channel chan any) error {
+	for {
+		connected, err := c.readStream(options, channel)
+		if !connected {
+			return err
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func (c *Client) readStream(options ClientSubscribeOptions, channel chan any) (bool, error) {
+	u, err := url.Parse(options.StreamURL)
+	if err != nil {
+		return false, err
+	}
+	query := u.Query()
+	query.Set("diff", "1")
+	if options.FeedNames != nil {
+		query.Set("feeds", strings.Join(options.FeedNames, ","))
+	}
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return false, err
+	}
+	userAgent := c.Options.UserAgent
+	if userAgent == "" {
+		userAgent = "gbfs-client/1.0"
+	}
+	req.Header.Add("User-Agent", userAgent)
+	req.Header.Add("Accept", "text/event-stream")
+	// stream is long-lived, timeout of http client does not apply
+	httpClient := &http.Client{
+		Transport: c.httpClient.Transport,
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return false, errors.New("invalid response status: " + strconv.Itoa(res.StatusCode))
+	}
+	docs := map[string]json.RawMessage{}
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	event, data := "", []string{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				c.streamMessage(event, strings.Join(data, "\n"), docs, channel)
+			}
+			event, data = "", data[:0]
+		case strings.HasPrefix(line, ":"):
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	return true, scanner.Err()
+}
+
+func (c *Client) streamMessage(event, data string, docs map[string]json.RawMessage, channel chan any) {
+	msg := &StreamMessage{}
+	err := json.Unmarshal([]byte(data), msg)
+	if err != nil {
+		channel <- NewError("stream: ", err)
+		return
+	}
+	doc := msg.Feed
+	if event == StreamEventPatch {
+		prev, ok := docs[msg.Name]
+		if !ok {
+			channel <- errors.New(msg.Name + ": stream: patch without feed")
+			return
+		}
+		doc, err = applyMergePatch(prev, msg.Patch)
+		if err != nil {
+			channel <- errors.New(msg.Name + ": stream: " + err.Error())
+			return
+		}
+	}
+	docs[msg.Name] = doc
+	f := FeedStruct(msg.Name)
+	if f == nil {
+		return
+	}
+	if c.Options.ValidateSchema {
+		schemaErrs, err := validateSchema(msg.Name, doc)
+		if err == nil && len(schemaErrs) > 0 {
+			err = schemaErrs
+		}
+		if err != nil {
+			channel <- NewError(msg.Name+": stream: ", err)
+			return
+		}
+	}
+	err = json.Unmarshal(doc, f)
+	if err != nil {
+		channel <- errors.New(msg.Name + ": stream: " + err.Error())
+		return
+	}
+	cacheSet(c, f)
+	channel <- f
+}
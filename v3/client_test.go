@@ -0,0 +1,30 @@
+package gbfs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientSnapshotNullFeeds(t *testing.T) {
+	for _, doc := range []string{
+		`{"last_updated":"2024-01-01T00:00:00Z","ttl":0,"version":"3.0","data":{"feeds":[null,{"name":null}]}}`,
+		`{"last_updated":"2024-01-01T00:00:00Z","ttl":0,"version":"3.0"}`,
+	} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(doc))
+		}))
+		c, err := NewClient(ClientOptions{AutoDiscoveryURL: ts.URL + "/gbfs.json"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		feeds, err := c.Snapshot()
+		ts.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(feeds) != 1 {
+			t.Fatalf("expected only gbfs feed, got %v", feeds)
+		}
+	}
+}
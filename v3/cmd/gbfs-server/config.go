@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/petoc/gbfs/v3"
+)
+
+type (
+	// config is configuration of server read from JSON file. Environment
+	// variables in form ${NAME} are expanded in addresses, paths, URLs,
+	// commands and headers after decoding.
+	config struct {
+		Listen       string           `json:"listen"`
+		AdminListen  string           `json:"admin_listen"`
+		TilesListen  string           `json:"tiles_listen"`
+		SystemID     string           `json:"system_id"`
+		RootDir      string           `json:"root_dir"`
+		BaseURL      string           `json:"base_url"`
+		BasePath     string           `json:"base_path"`
+		Version      string           `json:"version"`
+		DefaultTTL   int              `json:"default_ttl"`
+		V2BasePath   string           `json:"v2_base_path"`
+		ManifestPath string           `json:"manifest_path"`
+		Validation   string           `json:"validation"`
+		Versions     []*configVersion `json:"versions"`
+		Feeds        []*configFeed    `json:"feeds"`
+		// GeofencingBudget is size budget of geofencing_zones.
+		GeofencingBudget *configGeofencingBudget `json:"geofencing_budget"`
+		// dir is directory of configuration file, commands are run in it.
+		dir string
+	}
+	configVersion struct {
+		Version string `json:"version"`
+		URL     string `json:"url"`
+	}
+	configGeofencingBudget struct {
+		MaxBytes     int     `json:"max_bytes"`
+		Precision    int     `json:"precision"`
+		Method       string  `json:"method"`
+		Tolerance    float64 `json:"tolerance"`
+		MaxTolerance float64 `json:"max_tolerance"`
+	}
+	// configFeed is data source of feeds. Exactly one of File, Dir, Command
+	// and URL must be set. Name is required for Command and URL, feeds
+	// loaded from files are named by file name.
+	configFeed struct {
+		Name    string            `json:"name"`
+		Path    string            `json:"path"`
+		TTL     int               `json:"ttl"`
+		File    string            `json:"file"`
+		Dir     string            `json:"dir"`
+		Command []string          `json:"command"`
+		URL     string            `json:"url"`
+		Headers map[string]string `json:"headers"`
+		Timeout int               `json:"timeout"`
+	}
+)
+
+var validationModes = map[string]gbfs.ValidationMode{
+	"":       gbfs.ValidationModeOff,
+	"off":    gbfs.ValidationModeOff,
+	"warn":   gbfs.ValidationModeWarn,
+	"strict": gbfs.ValidationModeStrict,
+}
+
+// loadConfig reads configuration file. Relative paths are resolved against
+// directory of configuration file.
+func loadConfig(path string) (*config, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return nil, errors.New(path + ": YAML configuration is not supported, use JSON")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &config{
+		Listen:     "127.0.0.1:8080",
+		RootDir:    "public",
+		Version:    gbfs.V30,
+		DefaultTTL: 60,
+	}
+	err = json.Unmarshal(b, c)
+	if err != nil {
+		return nil, gbfs.NewError(path+": ", err)
+	}
+	c.expandEnv()
+	c.dir = filepath.Dir(path)
+	c.RootDir = resolvePath(c.dir, c.RootDir)
+	for _, f := range c.Feeds {
+		f.File = resolvePath(c.dir, f.File)
+		f.Dir = resolvePath(c.dir, f.Dir)
+	}
+	err = c.validate()
+	if err != nil {
+		return nil, gbfs.NewError(path+": ", err)
+	}
+	return c, nil
+}
+
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${NAME} in s with value of environment variable,
+// other uses of $ are kept.
+func expandEnv(s string) string {
+	return envPattern.ReplaceAllStringFunc(s, func(m string) string {
+		return os.Getenv(m[2 : len(m)-1])
+	})
+}
+
+// expandEnv expands environment variables in string fields, which are
+// likely to differ between deployments.
+func (c *config) expandEnv() {
+	for _, v := range []*string{&c.Listen, &c.AdminListen, &c.TilesListen, &c.SystemID, &c.RootDir, &c.BaseURL, &c.BasePath, &c.V2BasePath, &c.ManifestPath} {
+		*v = expandEnv(*v)
+	}
+	for _, v := range c.Versions {
+		v.URL = expandEnv(v.URL)
+	}
+	for _, f := range c.Feeds {
+		f.File = expandEnv(f.File)
+		f.Dir = expandEnv(f.Dir)
+		f.URL = expandEnv(f.URL)
+		for i, arg := range f.Command {
+			f.Command[i] = expandEnv(arg)
+		}
+		for k, v := range f.Headers {
+			f.Headers[k] = expandEnv(v)
+		}
+	}
+}
+
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func (c *config) validate() error {
+	if _, ok := validationModes[c.Validation]; !ok {
+		return errors.New("invalid validation mode " + c.Validation + ", expected off, warn or strict")
+	}
+	if b := c.GeofencingBudget; b != nil {
+		if b.MaxBytes <= 0 {
+			return errors.New("geofencing_budget: max_bytes must be positive")
+		}
+		if b.Method != "" && b.Method != gbfs.SimplifyDouglasPeucker && b.Method != gbfs.SimplifyVisvalingam {
+			return errors.New("geofencing_budget: invalid method " + b.Method + ", expected " + gbfs.SimplifyDouglasPeucker + " or " + gbfs.SimplifyVisvalingam)
+		}
+	}
+	if len(c.Feeds) == 0 {
+		return gbfs.ErrMissingFeedHandlers
+	}
+	for i, f := range c.Feeds {
+		sources := 0
+		for _, set := range []bool{f.File != "", f.Dir != "", len(f.Command) > 0, f.URL != ""} {
+			if set {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return errors.New("feeds[" + strconv.Itoa(i) + "]: exactly one of file, dir, command and url must be set")
+		}
+		if f.Name == "" && f.File != "" {
+			f.Name = strings.TrimSuffix(filepath.Base(f.File), filepath.Ext(f.File))
+		}
+		if f.Name == "" && f.Dir == "" {
+			return errors.New("feeds[" + strconv.Itoa(i) + "]: missing name")
+		}
+		if f.Name != "" && gbfs.FeedStruct(f.Name) == nil {
+			return errors.New("feeds[" + strconv.Itoa(i) + "]: unknown feed " + f.Name)
+		}
+		if f.Dir != "" && (f.Name != "" || f.Path != "") {
+			return errors.New("feeds[" + strconv.Itoa(i) + "]: name and path can not be set for dir")
+		}
+	}
+	return nil
+}
+
+// serverOptions returns options of server without feed handlers.
+func (c *config) serverOptions() gbfs.ServerOptions {
+	o := gbfs.ServerOptions{
+		SystemID:     c.SystemID,
+		RootDir:      c.RootDir,
+		BaseURL:      c.BaseURL,
+		BasePath:     c.BasePath,
+		Version:      c.Version,
+		DefaultTTL:   c.DefaultTTL,
+		V2BasePath:   c.V2BasePath,
+		ManifestPath: c.ManifestPath,
+		Validation:   validationModes[c.Validation],
+	}
+	if o.BasePath == "" {
+		o.BasePath = "v3/" + c.SystemID
+	}
+	if b := c.GeofencingBudget; b != nil {
+		o.GeofencingBudget = &gbfs.GeofencingBudget{
+			MaxBytes:     b.MaxBytes,
+			Precision:    b.Precision,
+			Method:       b.Method,
+			Tolerance:    b.Tolerance,
+			MaxTolerance: b.MaxTolerance,
+		}
+	}
+	for _, v := range c.Versions {
+		o.Versions = append(o.Versions, &gbfs.ServerVersion{
+			Version: v.Version,
+			URL:     v.URL,
+		})
+	}
+	return o
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigExpandEnv(t *testing.T) {
+	t.Setenv("GBFS_TEST_TOKEN", "secret")
+	t.Setenv("GBFS_TEST_HOST", "upstream")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gbfs-server.json")
+	err := os.WriteFile(path, []byte(`{
+		"system_id": "price$5",
+		"base_url": "http://127.0.0.1:8080",
+		"feeds": [
+			{"name": "station_status", "url": "https://${GBFS_TEST_HOST}/station_status.json", "headers": {"Authorization": "Bearer ${GBFS_TEST_TOKEN}", "X-Literal": "$GBFS_TEST_TOKEN"}}
+		]
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.SystemID != "price$5" {
+		t.Errorf("expected $ to be kept, got %s", c.SystemID)
+	}
+	f := c.Feeds[0]
+	if f.URL != "https://upstream/station_status.json" {
+		t.Errorf("unexpected url %s", f.URL)
+	}
+	if f.Headers["Authorization"] != "Bearer secret" || f.Headers["X-Literal"] != "$GBFS_TEST_TOKEN" {
+		t.Errorf("unexpected headers %v", f.Headers)
+	}
+}
+
+func TestLoadConfigYAML(t *testing.T) {
+	if _, err := loadConfig(filepath.Join(t.TempDir(), "gbfs-server.yaml")); err == nil {
+		t.Fatal("expected YAML configuration to be rejected")
+	}
+}
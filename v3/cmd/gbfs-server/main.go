@@ -0,0 +1,67 @@
+// Command gbfs-server publishes GBFS system described by JSON configuration
+// and serves published files. Feeds are loaded from static files,
+// directories, commands or upstream URLs.
+//
+//	gbfs-server -config gbfs-server.json
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/petoc/gbfs/v3"
+	"github.com/petoc/gbfs/v3/mvt"
+)
+
+func main() {
+	configPath := flag.String("config", "gbfs-server.json", "path to configuration file")
+	check := flag.Bool("check", false, "check configuration and exit")
+	flag.Parse()
+	c, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	options := c.serverOptions()
+	for _, f := range c.Feeds {
+		options.FeedHandlers = append(options.FeedHandlers, newSource(f, c.dir).feedHandler())
+	}
+	options.UpdateHandler = func(s *gbfs.Server, feed gbfs.Feed, path string, err error) {
+		if err != nil {
+			log.Printf("system=%s path=%s error=%s", s.Options.SystemID, path, err)
+			return
+		}
+		log.Printf("system=%s ttl=%d version=%s updated=%s", s.Options.SystemID, feed.GetTTL(), feed.GetVersion(), path)
+	}
+	options.PublishHandler = func(s *gbfs.Server, feed gbfs.Feed, path string, result *gbfs.PublishResult) {
+		if len(result.Warnings) > 0 {
+			log.Printf("system=%s path=%s warnings=%s", s.Options.SystemID, path, result.Warnings)
+		}
+	}
+	s, err := gbfs.NewServer(options)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *check {
+		return
+	}
+	fs, err := gbfs.NewFileServer(c.Listen, c.RootDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if c.AdminListen != "" {
+		go (func() {
+			log.Fatal(http.ListenAndServe(c.AdminListen, s.AdminHandler(gbfs.AdminOptions{})))
+		})()
+	}
+	if c.TilesListen != "" {
+		go (func() {
+			log.Fatal(http.ListenAndServe(c.TilesListen, mvt.NewHandler(s, mvt.Options{})))
+		})()
+	}
+	go (func() {
+		log.Fatal(s.Start())
+	})()
+	log.Printf("system=%s listen=%s", c.SystemID, c.Listen)
+	log.Fatal(fs.ListenAndServe())
+}
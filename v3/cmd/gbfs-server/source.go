@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/petoc/gbfs/v3"
+)
+
+const defaultSourceTimeout = 30
+
+// source loads feed from command or upstream URL. When source fails, feed
+// loaded last time is published again, so that failing upstream does not
+// stop feed handler.
+type source struct {
+	feed   *configFeed
+	dir    string
+	client *http.Client
+	last   []gbfs.Feed
+}
+
+func newSource(f *configFeed, dir string) *source {
+	timeout := f.Timeout
+	if timeout <= 0 {
+		timeout = defaultSourceTimeout
+	}
+	return &source{
+		feed: f,
+		dir:  dir,
+		client: &http.Client{
+			Timeout: time.Duration(timeout) * time.Second,
+		},
+	}
+}
+
+// feedHandler returns feed handler of source. Files and directories are
+// loaded and watched by feed handlers of gbfs package.
+func (src *source) feedHandler() *gbfs.FeedHandler {
+	options := gbfs.FileFeedHandlerOptions{
+		TTL:  src.feed.TTL,
+		Path: src.feed.Path,
+		Name: src.feed.Name,
+	}
+	switch {
+	case src.feed.File != "":
+		return gbfs.NewFileFeedHandler(src.feed.File, options)
+	case src.feed.Dir != "":
+		return gbfs.NewDirFeedHandler(src.feed.Dir, options)
+	}
+	return &gbfs.FeedHandler{
+		TTL:  src.feed.TTL,
+		Path: src.feed.Path,
+		Handler: func(s *gbfs.Server) ([]gbfs.Feed, error) {
+			feeds, err := src.load()
+			if err != nil {
+				log.Printf("system=%s source=%s error=%s", s.Options.SystemID, src.String(), err)
+				return src.last, nil
+			}
+			src.last = feeds
+			return feeds, nil
+		},
+	}
+}
+
+func (src *source) String() string {
+	f := src.feed
+	if f.URL != "" {
+		return f.URL
+	}
+	return strings.Join(f.Command, " ")
+}
+
+func (src *source) load() ([]gbfs.Feed, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if src.feed.URL != "" {
+		b, err = src.fetch()
+	} else {
+		b, err = src.run()
+	}
+	if err != nil {
+		return nil, err
+	}
+	feed, err := decodeFeed(src.feed.Name, b)
+	if err != nil {
+		return nil, err
+	}
+	return []gbfs.Feed{feed}, nil
+}
+
+// decodeFeed decodes feed document. Fields last_updated, ttl and version
+// are optional, they are set by server.
+func decodeFeed(name string, b []byte) (gbfs.Feed, error) {
+	feed := gbfs.FeedStruct(name)
+	if feed == nil {
+		return nil, errors.New("unknown feed " + name)
+	}
+	err := json.Unmarshal(b, feed)
+	if err != nil {
+		return nil, gbfs.NewError(name+": ", err)
+	}
+	return feed, nil
+}
+
+func (src *source) fetch() ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, src.feed.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range src.feed.Headers {
+		req.Header.Set(k, v)
+	}
+	res, err := src.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected status " + strconv.Itoa(res.StatusCode))
+	}
+	return io.ReadAll(res.Body)
+}
+
+// run runs command in directory of configuration and returns its standard
+// output.
+func (src *source) run() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), src.client.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, src.feed.Command[0], src.feed.Command[1:]...)
+	cmd.Dir = src.dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(err.Error() + ": " + msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	gbfsv2 "github.com/petoc/gbfs/v2"
+	"github.com/petoc/gbfs/v3"
+)
+
+func runConvert(args []string) error {
+	fs, o := newFlagSet("convert")
+	to := fs.String("to", "", "target version v2 or v3 (default other than version of source)")
+	out := fs.String("out", "", "output directory (required)")
+	baseURL := fs.String("base-url", "", "base URL of converted feeds, gbfs.json is written when set")
+	args, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		fmt.Fprintln(fs.Output(), "gbfs convert: missing -out directory")
+		return errUsage
+	}
+	snap, err := loadSnapshot(args[0], o, true)
+	if snap == nil {
+		return err
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gbfs convert: "+err.Error())
+	}
+	if *to == "" {
+		*to = "v2"
+		if snap.isV2() {
+			*to = "v3"
+		}
+	}
+	c := &converter{
+		out:     *out,
+		baseURL: strings.TrimSuffix(*baseURL, "/"),
+	}
+	switch {
+	case *to == "v3" && snap.isV2():
+		return c.toV3(snap)
+	case *to == "v2" && !snap.isV2():
+		return c.toV2(snap)
+	case *to == "v2" || *to == "v3":
+		return errors.New("snapshot is already " + *to)
+	}
+	fmt.Fprintln(fs.Output(), "gbfs convert: invalid target version "+*to)
+	return errUsage
+}
+
+type converter struct {
+	out     string
+	baseURL string
+}
+
+func (c *converter) write(path string, feed any) error {
+	b, err := json.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return err
+	}
+	path = filepath.Join(c.out, filepath.FromSlash(path))
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0644)
+}
+
+func (c *converter) toV3(snap *snapshot) error {
+	feeds := snap.toV3()
+	for _, l := range snap.languages() {
+		for name := range snap.v2[l] {
+			if name == gbfsv2.FeedNameGbfs || gbfs.FeedStruct(name) != nil || name == gbfsv2.FeedNameFreeBikeStatus {
+				continue
+			}
+			fmt.Fprintln(os.Stderr, "gbfs convert: "+l+"/"+name+": not converted, feed has no v3 equivalent")
+		}
+	}
+	g := &gbfs.FeedGbfs{
+		Data: &gbfs.FeedGbfsData{
+			Feeds: []*gbfs.FeedGbfsFeed{},
+		},
+	}
+	for _, name := range sortedNames(feeds) {
+		err := c.write(name+".json", feeds[name])
+		if err != nil {
+			return err
+		}
+		g.Data.Feeds = append(g.Data.Feeds, &gbfs.FeedGbfsFeed{
+			Name: gbfs.NewString(name),
+			URL:  gbfs.NewString(c.baseURL + "/" + name + ".json"),
+		})
+	}
+	if c.baseURL == "" {
+		return nil
+	}
+	g.SetLastUpdated(gbfs.Timestamp(time.Now().UTC().Format(time.RFC3339)))
+	g.SetTTL(0)
+	g.SetVersion(gbfs.V30)
+	return c.write(gbfs.FeedNameGbfs+".json", g)
+}
+
+func (c *converter) toV2(snap *snapshot) error {
+	languages := []string{}
+	for _, f := range snap.v3 {
+		for _, l := range gbfs.FeedLanguages(f) {
+			if !gbfs.InSlice(l, languages) {
+				languages = append(languages, l)
+			}
+		}
+	}
+	sort.Strings(languages)
+	if len(languages) == 0 {
+		languages = []string{""}
+	}
+	g := &gbfsv2.FeedGbfs{
+		Data: map[string]*gbfsv2.FeedGbfsLanguage{},
+	}
+	for _, l := range languages {
+		g.Data[l] = &gbfsv2.FeedGbfsLanguage{
+			Feeds: []*gbfsv2.FeedGbfsFeed{},
+		}
+	}
+	for _, name := range sortedNames(snap.v3) {
+		if name == gbfs.FeedNameGbfs || name == gbfs.FeedNameManifest {
+			continue
+		}
+		converted, err := gbfs.ConvertToV2(snap.v3[name], languages)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "gbfs convert: "+err.Error())
+			continue
+		}
+		for _, f := range converted {
+			targets := languages
+			if f.GetLanguage() != "" {
+				targets = []string{f.GetLanguage()}
+			}
+			for _, l := range targets {
+				path := f.Name() + ".json"
+				if l != "" {
+					path = l + "/" + path
+				}
+				err = c.write(path, f)
+				if err != nil {
+					return err
+				}
+				g.Data[l].Feeds = append(g.Data[l].Feeds, &gbfsv2.FeedGbfsFeed{
+					Name: gbfsv2.NewString(f.Name()),
+					URL:  gbfsv2.NewString(c.baseURL + "/" + path),
+				})
+			}
+		}
+	}
+	if c.baseURL == "" {
+		return nil
+	}
+	if languages[0] == "" {
+		return errors.New("gbfs.json not written, feeds do not have language")
+	}
+	g.SetLastUpdated(gbfsv2.Timestamp(time.Now().Unix()))
+	g.SetTTL(0)
+	g.SetVersion(gbfsv2.V23)
+	return c.write(gbfsv2.FeedNameGbfs+".json", g)
+}
+
+func sortedNames[T any](m map[string]T) []string {
+	names := []string{}
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/petoc/gbfs/v3"
+)
+
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeChanged = "changed"
+)
+
+// idKeys are keys identifying entities in arrays, in order of preference.
+var idKeys = []string{
+	"station_id",
+	"vehicle_id",
+	"bike_id",
+	"vehicle_type_id",
+	"plan_id",
+	"region_id",
+	"alert_id",
+	"system_id",
+	"name",
+	"version",
+	"language",
+}
+
+type change struct {
+	Feed string `json:"feed"`
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+func (c *change) String() string {
+	switch c.Kind {
+	case changeAdded:
+		return c.Feed + " " + c.Path + ": added " + jsonString(c.New)
+	case changeRemoved:
+		return c.Feed + " " + c.Path + ": removed"
+	}
+	return c.Feed + " " + c.Path + ": " + jsonString(c.Old) + " -> " + jsonString(c.New)
+}
+
+func jsonString(v any) string {
+	b, _ := json.Marshal(v)
+	if len(b) > 80 {
+		return string(b[:77]) + "..."
+	}
+	return string(b)
+}
+
+// differ compares generic JSON values and records changes. Arrays of
+// objects with identifier are compared by identifier, other arrays by
+// index.
+type differ struct {
+	ignore  map[string]bool
+	changes []*change
+}
+
+func newDiffer(ignore string) *differ {
+	d := &differ{
+		ignore: map[string]bool{},
+	}
+	for _, key := range strings.Split(ignore, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			d.ignore[key] = true
+		}
+	}
+	return d
+}
+
+func (d *differ) add(feed, path, kind string, old, new any) {
+	d.changes = append(d.changes, &change{feed, path, kind, old, new})
+}
+
+func (d *differ) diff(feed, path string, a, b any) {
+	switch at := a.(type) {
+	case map[string]any:
+		bt, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		for _, k := range unionKeys(at, bt) {
+			if d.ignore[k] {
+				continue
+			}
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			av, aok := at[k]
+			bv, bok := bt[k]
+			switch {
+			case !aok:
+				d.add(feed, p, changeAdded, nil, bv)
+			case !bok:
+				d.add(feed, p, changeRemoved, av, nil)
+			default:
+				d.diff(feed, p, av, bv)
+			}
+		}
+		return
+	case []any:
+		bt, ok := b.([]any)
+		if !ok {
+			break
+		}
+		d.diffArray(feed, path, at, bt)
+		return
+	}
+	if !jsonEqual(a, b) {
+		d.add(feed, path, changeChanged, a, b)
+	}
+}
+
+func (d *differ) diffArray(feed, path string, a, b []any) {
+	key := idKey(a, b)
+	if key == "" {
+		for i := 0; i < len(a) || i < len(b); i++ {
+			p := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(a):
+				d.add(feed, p, changeAdded, nil, b[i])
+			case i >= len(b):
+				d.add(feed, p, changeRemoved, a[i], nil)
+			default:
+				d.diff(feed, p, a[i], b[i])
+			}
+		}
+		return
+	}
+	am := map[string]any{}
+	for _, v := range a {
+		am[v.(map[string]any)[key].(string)] = v
+	}
+	bm := map[string]any{}
+	for _, v := range b {
+		bm[v.(map[string]any)[key].(string)] = v
+	}
+	for _, id := range unionKeys(am, bm) {
+		p := path + "[" + key + "=" + id + "]"
+		av, aok := am[id]
+		bv, bok := bm[id]
+		switch {
+		case !aok:
+			d.add(feed, p, changeAdded, nil, bv)
+		case !bok:
+			d.add(feed, p, changeRemoved, av, nil)
+		default:
+			d.diff(feed, p, av, bv)
+		}
+	}
+}
+
+// idKey returns key with unique string value in all objects of both arrays.
+func idKey(a, b []any) string {
+	if len(a) == 0 && len(b) == 0 {
+		return ""
+	}
+	for _, key := range idKeys {
+		if uniqueKey(a, key) && uniqueKey(b, key) {
+			return key
+		}
+	}
+	return ""
+}
+
+func uniqueKey(items []any, key string) bool {
+	seen := map[string]bool{}
+	for _, v := range items {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return false
+		}
+		id, ok := m[key].(string)
+		if !ok || seen[id] {
+			return false
+		}
+		seen[id] = true
+	}
+	return true
+}
+
+func unionKeys[T any](a, b map[string]T) []string {
+	keys := []string{}
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func jsonEqual(a, b any) bool {
+	ab, _ := json.Marshal(a)
+	bb, _ := json.Marshal(b)
+	return string(ab) == string(bb)
+}
+
+// summary counts changes of feed by kind. Changes of nested values of array
+// item are counted once as change of item.
+func summary(changes []*change) map[string]int {
+	counts := map[string]int{}
+	seen := map[string]bool{}
+	for _, c := range changes {
+		kind := c.Kind
+		entity := c.Path
+		if i := strings.Index(c.Path, "]"); i >= 0 && i < len(c.Path)-1 {
+			entity = c.Path[:i+1]
+			kind = changeChanged
+		}
+		if seen[entity] {
+			continue
+		}
+		seen[entity] = true
+		counts[kind]++
+	}
+	return counts
+}
+
+func formatSummary(counts map[string]int) string {
+	return fmt.Sprintf("%d changed, %d added, %d removed", counts[changeChanged], counts[changeAdded], counts[changeRemoved])
+}
+
+func runDiff(args []string) error {
+	fs, o := newFlagSet("diff")
+	ignore := fs.String("ignore", "last_updated,ttl", "comma separated keys ignored in comparison")
+	brief := fs.Bool("summary", false, "print only summary of changes per feed")
+	asJSON := fs.Bool("json", false, "print changes as JSON")
+	args, err := parse(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	snaps := make([]*snapshot, 2)
+	for i, source := range args {
+		snap, err := loadSnapshot(source, o, false)
+		if snap == nil {
+			return err
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "gbfs diff: "+source+": "+err.Error())
+		}
+		snaps[i] = snap
+	}
+	docs := make([]map[string]any, 2)
+	for i, snap := range snaps {
+		if snaps[0].isV2() != snaps[1].isV2() {
+			snap = &snapshot{version: gbfs.V30, v3: snap.toV3()}
+		}
+		docs[i], err = snap.docs()
+		if err != nil {
+			return err
+		}
+	}
+	d := newDiffer(*ignore)
+	for _, name := range unionKeys(docs[0], docs[1]) {
+		a, aok := docs[0][name]
+		b, bok := docs[1][name]
+		switch {
+		case !aok:
+			d.add(name, "", changeAdded, nil, nil)
+		case !bok:
+			d.add(name, "", changeRemoved, nil, nil)
+		default:
+			d.diff(name, "", a, b)
+		}
+	}
+	if *asJSON {
+		if d.changes == nil {
+			d.changes = []*change{}
+		}
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		err = e.Encode(d.changes)
+	} else {
+		printChanges(d.changes, *brief)
+	}
+	if err != nil {
+		return err
+	}
+	if len(d.changes) > 0 {
+		return exitError(1)
+	}
+	return nil
+}
+
+// printChanges prints changes followed by summary of every changed feed.
+func printChanges(changes []*change, brief bool) {
+	byFeed := map[string][]*change{}
+	for _, c := range changes {
+		if !brief {
+			if c.Path == "" {
+				fmt.Println(c.Feed + ": feed " + c.Kind)
+			} else {
+				fmt.Println(c.String())
+			}
+		}
+		byFeed[c.Feed] = append(byFeed[c.Feed], c)
+	}
+	for _, name := range sortedNames(byFeed) {
+		fmt.Println(name + ": " + formatSummary(summary(byFeed[name])))
+	}
+}
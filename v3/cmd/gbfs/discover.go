@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+func runDiscover(args []string) error {
+	fs, o := newFlagSet("discover")
+	asJSON := fs.Bool("json", false, "print feeds as JSON")
+	args, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	s, err := openSystem(args[0], o)
+	if err != nil {
+		return err
+	}
+	refs := s.feeds(true)
+	if *asJSON {
+		type feed struct {
+			Name     string `json:"name"`
+			URL      string `json:"url"`
+			Language string `json:"language,omitempty"`
+		}
+		r := struct {
+			Version string  `json:"version"`
+			Feeds   []*feed `json:"feeds"`
+		}{
+			Version: s.version,
+			Feeds:   []*feed{},
+		}
+		for _, ref := range refs {
+			r.Feeds = append(r.Feeds, &feed{ref.name, ref.url, ref.language})
+		}
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		return e.Encode(r)
+	}
+	fmt.Println("version " + s.version)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, ref := range refs {
+		if s.isV2() {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", ref.language, ref.name, ref.url)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\n", ref.name, ref.url)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+func runFetch(args []string) error {
+	fs, o := newFlagSet("fetch")
+	args, err := parse(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	s, err := openSystem(args[0], o)
+	if err != nil {
+		return err
+	}
+	ref := s.feed(args[1])
+	if ref == nil {
+		return errors.New(args[1] + ": feed not listed in gbfs.json")
+	}
+	var feed any
+	if s.isV2() {
+		feed, err = s.getV2(ref)
+	} else {
+		feed, err = s.getV3(ref)
+	}
+	if err != nil {
+		return err
+	}
+	e := json.NewEncoder(os.Stdout)
+	e.SetIndent("", "  ")
+	return e.Encode(feed)
+}
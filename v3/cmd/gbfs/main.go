@@ -0,0 +1,150 @@
+// Command gbfs inspects GBFS systems: lists and fetches feeds, validates
+// feeds, compares snapshots, converts between v2 and v3 and watches feeds
+// for changes. Systems of versions 1.0 to 2.3 and 3.0 are supported.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const usage = `Usage: gbfs <command> [flags] <arguments>
+
+Commands:
+  discover <url>          list feeds from gbfs.json
+  fetch <url> <feed>      fetch feed and print it
+  validate <url>          validate feeds against specification
+  diff <source> <source>  compare two snapshots
+  convert <source>        convert snapshot between v2 and v3
+  watch <url>             subscribe to feeds and print changes
+
+Source is URL of gbfs.json or directory with feed files. Run
+"gbfs <command> -h" for flags of command.
+`
+
+var errUsage = errors.New("invalid arguments")
+
+type command struct {
+	run  func(args []string) error
+	args string
+	help string
+}
+
+// commands are initialized in init, because flag sets of commands refer to
+// them.
+var commands map[string]*command
+
+func init() {
+	commands = map[string]*command{
+		"discover": {runDiscover, "<url>", "List feeds from gbfs.json."},
+		"fetch":    {runFetch, "<url> <feed>", "Fetch feed and print it as indented JSON."},
+		"validate": {runValidate, "<url>", "Validate required fields, values, references between feeds and JSON Schema of feeds.\nExits with status 1 when findings of -fail-on severity are found."},
+		"diff":     {runDiff, "<source> <source>", "Compare two snapshots. Entities are matched by their identifiers.\nSnapshots of different versions are compared after conversion to v3.\nExits with status 1 when snapshots differ."},
+		"convert":  {runConvert, "<source>", "Convert snapshot between v2 and v3 and write feeds to directory."},
+		"watch":    {runWatch, "<url>", "Subscribe to feeds and print summary of changes of every update."},
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "-h" || name == "-help" || name == "help" {
+		fmt.Print(usage)
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "gbfs: unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
+	}
+	err := cmd.run(os.Args[2:])
+	if err == nil {
+		return
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	var exit exitError
+	if errors.As(err, &exit) {
+		os.Exit(int(exit))
+	}
+	fmt.Fprintln(os.Stderr, "gbfs "+name+": "+err.Error())
+	os.Exit(1)
+}
+
+// exitError exits with status without printing message.
+type exitError int
+
+func (e exitError) Error() string {
+	return "exit status " + fmt.Sprint(int(e))
+}
+
+// options are flags shared by commands fetching feeds.
+type options struct {
+	language  string
+	userAgent string
+	timeout   time.Duration
+}
+
+func (o *options) httpClient() *http.Client {
+	return &http.Client{
+		Timeout: o.timeout,
+	}
+}
+
+// newFlagSet returns flag set of command with shared flags.
+func newFlagSet(name string) (*flag.FlagSet, *options) {
+	cmd := commands[name]
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gbfs %s [flags] %s\n\n%s\n\nFlags:\n", name, cmd.args, cmd.help)
+		fs.PrintDefaults()
+	}
+	o := &options{}
+	fs.StringVar(&o.language, "language", "", "language of v2 feeds (default first language in gbfs.json)")
+	fs.StringVar(&o.userAgent, "user-agent", "gbfs-cli/1.0", "user agent of requests")
+	fs.DurationVar(&o.timeout, "timeout", 10*time.Second, "timeout of requests")
+	return fs, o
+}
+
+// parse parses flags and checks number of positional arguments. Flags are
+// accepted also after arguments.
+func parse(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	positional := []string{}
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, errUsage
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if len(positional) != n {
+		fmt.Fprintf(fs.Output(), "gbfs %s: expected %d arguments, got %d\n", fs.Name(), n, len(positional))
+		fs.Usage()
+		return nil, errUsage
+	}
+	return positional, nil
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
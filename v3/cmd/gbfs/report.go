@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/petoc/gbfs/v3/validate"
+)
+
+func writeJSON(r *report) error {
+	e := json.NewEncoder(os.Stdout)
+	e.SetIndent("", "  ")
+	return e.Encode(r)
+}
+
+// feedFindings groups findings by feed. Feeds listed in gbfs.json are
+// included also without findings.
+func feedFindings(r *report) ([]string, map[string][]*finding) {
+	byFeed := map[string][]*finding{}
+	for _, ref := range r.Feeds {
+		byFeed[ref.name] = nil
+	}
+	for _, f := range r.Findings {
+		byFeed[f.Feed] = append(byFeed[f.Feed], f)
+	}
+	names := []string{}
+	for name := range byFeed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, byFeed
+}
+
+type (
+	junitTestSuites struct {
+		XMLName  xml.Name          `xml:"testsuites"`
+		Name     string            `xml:"name,attr"`
+		Tests    int               `xml:"tests,attr"`
+		Failures int               `xml:"failures,attr"`
+		Suites   []*junitTestSuite `xml:"testsuite"`
+	}
+	junitTestSuite struct {
+		Name      string           `xml:"name,attr"`
+		Tests     int              `xml:"tests,attr"`
+		Failures  int              `xml:"failures,attr"`
+		TestCases []*junitTestCase `xml:"testcase"`
+	}
+	junitTestCase struct {
+		Name      string        `xml:"name,attr"`
+		ClassName string        `xml:"classname,attr"`
+		Failure   *junitFailure `xml:"failure,omitempty"`
+		SystemOut string        `xml:"system-out,omitempty"`
+	}
+	junitFailure struct {
+		Message string `xml:"message,attr"`
+		Type    string `xml:"type,attr"`
+		Text    string `xml:",chardata"`
+	}
+)
+
+// writeJUnit writes test case for every feed. Feed with errors fails,
+// warnings are written to system-out.
+func writeJUnit(r *report) error {
+	suite := &junitTestSuite{
+		Name: r.URL,
+	}
+	names, byFeed := feedFindings(r)
+	for _, name := range names {
+		tc := &junitTestCase{
+			Name:      name,
+			ClassName: "gbfs." + r.Version,
+		}
+		errs := []string{}
+		warnings := []string{}
+		for _, f := range byFeed[name] {
+			line := f.Path + ": " + f.Message + " (" + f.Rule + ")"
+			if f.Severity == validate.SeverityError {
+				errs = append(errs, line)
+			} else {
+				warnings = append(warnings, line)
+			}
+		}
+		if len(errs) > 0 {
+			tc.Failure = &junitFailure{
+				Message: strconv.Itoa(len(errs)) + " errors",
+				Type:    validate.SeverityError,
+				Text:    strings.Join(errs, "\n"),
+			}
+			suite.Failures++
+		}
+		tc.SystemOut = strings.Join(warnings, "\n")
+		suite.TestCases = append(suite.TestCases, tc)
+		suite.Tests++
+	}
+	suites := &junitTestSuites{
+		Name:     "gbfs",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []*junitTestSuite{suite},
+	}
+	b, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(append([]byte(xml.Header), append(b, '\n')...))
+	return err
+}
+
+type (
+	sarifLog struct {
+		Schema  string      `json:"$schema"`
+		Version string      `json:"version"`
+		Runs    []*sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    *sarifTool     `json:"tool"`
+		Results []*sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver *sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string       `json:"name"`
+		InformationURI string       `json:"informationUri"`
+		Rules          []*sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID string `json:"id"`
+	}
+	sarifResult struct {
+		RuleID    string           `json:"ruleId"`
+		Level     string           `json:"level"`
+		Message   *sarifMessage    `json:"message"`
+		Locations []*sarifLocation `json:"locations"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation *sarifPhysicalLocation  `json:"physicalLocation"`
+		LogicalLocations []*sarifLogicalLocation `json:"logicalLocations,omitempty"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation *sarifArtifactLocation `json:"artifactLocation"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifLogicalLocation struct {
+		FullyQualifiedName string `json:"fullyQualifiedName"`
+	}
+)
+
+// writeSARIF writes findings as SARIF 2.1.0 results located by URL of feed
+// and JSON path of value.
+func writeSARIF(r *report) error {
+	run := &sarifRun{
+		Tool: &sarifTool{
+			Driver: &sarifDriver{
+				Name:           "gbfs",
+				InformationURI: "https://github.com/petoc/gbfs",
+				Rules:          []*sarifRule{},
+			},
+		},
+		Results: []*sarifResult{},
+	}
+	rules := map[string]bool{}
+	for _, f := range r.Findings {
+		if !rules[f.Rule] {
+			rules[f.Rule] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, &sarifRule{ID: f.Rule})
+		}
+		location := &sarifLocation{
+			PhysicalLocation: &sarifPhysicalLocation{
+				ArtifactLocation: &sarifArtifactLocation{URI: r.feedURL(f.Feed)},
+			},
+		}
+		if f.Path != "" {
+			location.LogicalLocations = []*sarifLogicalLocation{{FullyQualifiedName: f.Feed + "." + f.Path}}
+		}
+		run.Results = append(run.Results, &sarifResult{
+			RuleID:    f.Rule,
+			Level:     f.Severity,
+			Message:   &sarifMessage{Text: f.Feed + ": " + f.Message},
+			Locations: []*sarifLocation{location},
+		})
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+	e := json.NewEncoder(os.Stdout)
+	e.SetIndent("", "  ")
+	return e.Encode(&sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []*sarifRun{run},
+	})
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	gbfsv2 "github.com/petoc/gbfs/v2"
+	"github.com/petoc/gbfs/v3"
+)
+
+var languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+
+type (
+	// system is GBFS system discovered from gbfs.json. Systems of version
+	// 3.x are read with v3 client, older systems with v2 client.
+	system struct {
+		url       string
+		version   string
+		language  string
+		languages []string
+		refs      []*feedRef
+		v3        *gbfs.Client
+		v2        *gbfsv2.Client
+	}
+	// feedRef is feed listed in gbfs.json.
+	feedRef struct {
+		name     string
+		url      string
+		language string
+	}
+	// snapshot is set of feeds of system. Feeds of v2 systems are keyed by
+	// language and feed name.
+	snapshot struct {
+		version string
+		v3      map[string]gbfs.Feed
+		v2      map[string]map[string]gbfsv2.Feed
+	}
+)
+
+// openSystem fetches gbfs.json and detects version of system.
+func openSystem(url string, o *options) (*system, error) {
+	c, err := gbfs.NewClient(gbfs.ClientOptions{
+		AutoDiscoveryURL: url,
+		UserAgent:        o.userAgent,
+		HTTPClient:       o.httpClient(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	g := &gbfs.FeedGbfs{}
+	err = c.Get(g)
+	if err != nil {
+		return nil, err
+	}
+	s := &system{
+		url:     url,
+		version: g.GetVersion(),
+	}
+	if strings.HasPrefix(s.version, "3.") {
+		s.v3 = c
+		s.refs = append(s.refs, &feedRef{name: gbfs.FeedNameGbfs, url: url})
+		for _, f := range g.Data.Feeds {
+			if f.Name != nil && f.URL != nil && *f.Name != gbfs.FeedNameGbfs {
+				s.refs = append(s.refs, &feedRef{name: *f.Name, url: *f.URL})
+			}
+		}
+		return s, nil
+	}
+	if s.version == "" {
+		s.version = "1.0"
+	}
+	// v2 client requires language also for gbfs.json, language is selected
+	// after languages are known.
+	language := o.language
+	if language == "" {
+		language = "en"
+	}
+	s.v2, err = gbfsv2.NewClient(gbfsv2.ClientOptions{
+		AutoDiscoveryURL: url,
+		DefaultLanguage:  language,
+		UserAgent:        o.userAgent,
+		HTTPClient:       o.httpClient(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	g2 := &gbfsv2.FeedGbfs{}
+	err = s.v2.Get(g2)
+	if err != nil {
+		return nil, err
+	}
+	for l := range g2.Data {
+		s.languages = append(s.languages, l)
+	}
+	sort.Strings(s.languages)
+	if len(s.languages) == 0 {
+		return nil, errors.New("gbfs.json does not list any language")
+	}
+	s.language = o.language
+	if s.language == "" {
+		s.language = s.languages[0]
+	}
+	if !gbfs.InSlice(s.language, s.languages) {
+		return nil, errors.New("language " + s.language + " not found, available languages: " + strings.Join(s.languages, ", "))
+	}
+	s.v2.Options.DefaultLanguage = s.language
+	for _, l := range s.languages {
+		s.refs = append(s.refs, &feedRef{name: gbfsv2.FeedNameGbfs, url: url, language: l})
+		for _, f := range g2.Data[l].Feeds {
+			if f.Name != nil && f.URL != nil && *f.Name != gbfsv2.FeedNameGbfs {
+				s.refs = append(s.refs, &feedRef{name: *f.Name, url: *f.URL, language: l})
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s *system) isV2() bool {
+	return s.v2 != nil
+}
+
+// feeds returns feeds listed in gbfs.json in selected language, or in all
+// languages.
+func (s *system) feeds(all bool) []*feedRef {
+	refs := []*feedRef{}
+	for _, ref := range s.refs {
+		if all || ref.language == s.language {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+func (s *system) feed(name string) *feedRef {
+	for _, ref := range s.feeds(false) {
+		if ref.name == name {
+			return ref
+		}
+	}
+	return nil
+}
+
+func (s *system) getV3(ref *feedRef) (gbfs.Feed, error) {
+	f := gbfs.FeedStruct(ref.name)
+	if f == nil {
+		return nil, errors.New(ref.name + ": unknown feed")
+	}
+	err := s.v3.GetURL(ref.url, f)
+	if err != nil {
+		return f, gbfs.NewError(ref.name+": ", err)
+	}
+	return f, nil
+}
+
+func (s *system) getV2(ref *feedRef) (gbfsv2.Feed, error) {
+	f := gbfsv2.FeedStruct(ref.name)
+	if f == nil {
+		return nil, errors.New(ref.name + ": unknown feed")
+	}
+	f.SetLanguage(ref.language)
+	err := s.v2.GetURL(ref.url, f)
+	if err != nil {
+		return f, gbfs.NewError(ref.name+": ", err)
+	}
+	return f, nil
+}
+
+// snapshot fetches feeds of system. Feeds which failed to load are left out
+// and their errors are joined.
+func (s *system) snapshot(allLanguages bool) (*snapshot, error) {
+	snap := &snapshot{
+		version: s.version,
+	}
+	errs := []error{}
+	if !s.isV2() {
+		snap.v3 = map[string]gbfs.Feed{}
+		for _, ref := range s.feeds(false) {
+			f, err := s.getV3(ref)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			snap.v3[ref.name] = f
+		}
+		return snap, errors.Join(errs...)
+	}
+	snap.v2 = map[string]map[string]gbfsv2.Feed{}
+	for _, ref := range s.feeds(allLanguages) {
+		f, err := s.getV2(ref)
+		if err != nil {
+			errs = append(errs, gbfs.NewError(ref.language+"/", err))
+			continue
+		}
+		if snap.v2[ref.language] == nil {
+			snap.v2[ref.language] = map[string]gbfsv2.Feed{}
+		}
+		snap.v2[ref.language][ref.name] = f
+	}
+	return snap, errors.Join(errs...)
+}
+
+// loadSnapshot loads snapshot from URL of gbfs.json or from directory with
+// feed files. Files of v2 feeds are expected in directories named by
+// language, otherwise language flag is used.
+func loadSnapshot(source string, o *options, allLanguages bool) (*snapshot, error) {
+	if isURL(source) {
+		s, err := openSystem(source, o)
+		if err != nil {
+			return nil, err
+		}
+		return s.snapshot(allLanguages)
+	}
+	snap := &snapshot{}
+	err := filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		header := struct {
+			Version string `json:"version"`
+		}{}
+		err = json.Unmarshal(b, &header)
+		if err != nil {
+			return errors.New(path + ": " + err.Error())
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		if header.Version == "" {
+			header.Version = "1.0"
+		}
+		if snap.version != "" && strings.HasPrefix(snap.version, "3.") != strings.HasPrefix(header.Version, "3.") {
+			return errors.New(path + ": mixed versions in snapshot")
+		}
+		if snap.version == "" || name == gbfs.FeedNameGbfs {
+			snap.version = header.Version
+		}
+		if strings.HasPrefix(header.Version, "3.") {
+			f := gbfs.FeedStruct(name)
+			if f == nil {
+				return nil
+			}
+			err = json.Unmarshal(b, f)
+			if err != nil {
+				return errors.New(path + ": " + err.Error())
+			}
+			if snap.v3 == nil {
+				snap.v3 = map[string]gbfs.Feed{}
+			}
+			snap.v3[name] = f
+			return nil
+		}
+		f := gbfsv2.FeedStruct(name)
+		if f == nil {
+			return nil
+		}
+		err = json.Unmarshal(b, f)
+		if err != nil {
+			return errors.New(path + ": " + err.Error())
+		}
+		language := filepath.Base(filepath.Dir(path))
+		if !languagePattern.MatchString(language) {
+			language = o.language
+		}
+		if !allLanguages && o.language != "" && language != o.language {
+			return nil
+		}
+		f.SetLanguage(language)
+		if snap.v2 == nil {
+			snap.v2 = map[string]map[string]gbfsv2.Feed{}
+		}
+		if snap.v2[language] == nil {
+			snap.v2[language] = map[string]gbfsv2.Feed{}
+		}
+		snap.v2[language][name] = f
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if snap.v3 == nil && snap.v2 == nil {
+		return nil, errors.New(source + ": no feeds found")
+	}
+	if len(snap.v2) > 1 && snap.v2[""] != nil {
+		// feeds outside of language directories, e.g. gbfs.json, are shared
+		// by all languages
+		for l, feeds := range snap.v2 {
+			for name, f := range snap.v2[""] {
+				if _, ok := feeds[name]; !ok && l != "" {
+					feeds[name] = f
+				}
+			}
+		}
+		delete(snap.v2, "")
+	}
+	if !allLanguages && len(snap.v2) > 1 {
+		language := snap.languages()[0]
+		snap.v2 = map[string]map[string]gbfsv2.Feed{
+			language: snap.v2[language],
+		}
+	}
+	return snap, nil
+}
+
+func (s *snapshot) isV2() bool {
+	return s.v2 != nil
+}
+
+func (s *snapshot) languages() []string {
+	languages := []string{}
+	for l := range s.v2 {
+		languages = append(languages, l)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// toV3 returns feeds of snapshot, feeds of v2 snapshot are converted to v3
+// and merged across languages. Feeds without v3 equivalent are left out.
+func (s *snapshot) toV3() map[string]gbfs.Feed {
+	if !s.isV2() {
+		return s.v3
+	}
+	byName := map[string][]gbfsv2.Feed{}
+	for _, l := range s.languages() {
+		for name, f := range s.v2[l] {
+			byName[name] = append(byName[name], f)
+		}
+	}
+	feeds := map[string]gbfs.Feed{}
+	for _, list := range byName {
+		f, err := gbfs.ConvertFromV2(list)
+		if err != nil {
+			continue
+		}
+		feeds[f.Name()] = f
+	}
+	return feeds
+}
+
+// docs returns feeds of snapshot decoded to generic JSON values keyed by
+// feed name, prefixed with language for v2 snapshot with more languages.
+func (s *snapshot) docs() (map[string]any, error) {
+	feeds := map[string]any{}
+	for name, f := range s.v3 {
+		feeds[name] = f
+	}
+	for _, l := range s.languages() {
+		for name, f := range s.v2[l] {
+			if len(s.v2) > 1 {
+				name = l + "/" + name
+			}
+			feeds[name] = f
+		}
+	}
+	docs := map[string]any{}
+	for name, f := range feeds {
+		b, err := json.Marshal(f)
+		if err != nil {
+			return nil, errors.New(name + ": " + err.Error())
+		}
+		var doc any
+		err = json.Unmarshal(b, &doc)
+		if err != nil {
+			return nil, errors.New(name + ": " + err.Error())
+		}
+		docs[name] = doc
+	}
+	return docs, nil
+}
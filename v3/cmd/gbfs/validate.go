@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	gbfsv2 "github.com/petoc/gbfs/v2"
+	schemav2 "github.com/petoc/gbfs/v2/schema"
+	validatev2 "github.com/petoc/gbfs/v2/validate"
+	"github.com/petoc/gbfs/v3"
+	"github.com/petoc/gbfs/v3/schema"
+	"github.com/petoc/gbfs/v3/validate"
+)
+
+const ruleFetch = "fetch"
+
+type (
+	// finding is finding of v2 or v3 validator.
+	finding struct {
+		Severity string `json:"severity"`
+		Feed     string `json:"feed"`
+		Path     string `json:"path"`
+		Rule     string `json:"rule"`
+		Message  string `json:"message"`
+	}
+	report struct {
+		URL      string     `json:"url"`
+		Version  string     `json:"version"`
+		Language string     `json:"language,omitempty"`
+		Feeds    []*feedRef `json:"-"`
+		Findings []*finding `json:"findings"`
+		Errors   int        `json:"errors"`
+		Warnings int        `json:"warnings"`
+	}
+)
+
+func (r *report) add(f *finding) {
+	r.Findings = append(r.Findings, f)
+	switch f.Severity {
+	case validate.SeverityError:
+		r.Errors++
+	case validate.SeverityWarning:
+		r.Warnings++
+	}
+}
+
+func (r *report) addV3(findings validate.Findings) {
+	for _, f := range findings {
+		r.add(&finding{f.Severity, f.Feed, f.Path, f.Rule, f.Message})
+	}
+}
+
+func (r *report) addV2(findings validatev2.Findings) {
+	for _, f := range findings {
+		r.add(&finding{f.Severity, f.Feed, f.Path, f.Rule, f.Message})
+	}
+}
+
+func (r *report) addFetchError(name string, err error) {
+	r.add(&finding{
+		Severity: validate.SeverityError,
+		Feed:     name,
+		Rule:     ruleFetch,
+		Message:  err.Error(),
+	})
+}
+
+// feedURL returns URL of feed, or URL of gbfs.json for unknown feeds.
+func (r *report) feedURL(name string) string {
+	for _, ref := range r.Feeds {
+		if ref.name == name {
+			return ref.url
+		}
+	}
+	return r.URL
+}
+
+var writers = map[string]func(*report) error{
+	"text":  writeText,
+	"json":  writeJSON,
+	"junit": writeJUnit,
+	"sarif": writeSARIF,
+}
+
+func runValidate(args []string) error {
+	fs, o := newFlagSet("validate")
+	format := fs.String("format", "text", "output format: text, json, junit or sarif")
+	noSchema := fs.Bool("no-schema", false, "skip validation against JSON Schema")
+	failOn := fs.String("fail-on", validate.SeverityError, "exit with status 1 on findings of severity: error, warning or none")
+	args, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	write, ok := writers[*format]
+	if !ok {
+		fmt.Fprintln(fs.Output(), "gbfs validate: invalid format "+*format)
+		return errUsage
+	}
+	if *failOn != validate.SeverityError && *failOn != validate.SeverityWarning && *failOn != "none" {
+		fmt.Fprintln(fs.Output(), "gbfs validate: invalid severity "+*failOn)
+		return errUsage
+	}
+	s, err := openSystem(args[0], o)
+	if err != nil {
+		return err
+	}
+	r := &report{
+		URL:      s.url,
+		Version:  s.version,
+		Language: s.language,
+		Feeds:    s.feeds(false),
+		Findings: []*finding{},
+	}
+	if s.isV2() {
+		s.v2.Options.ValidateSchema = !*noSchema
+		if s.v2.Options.ValidateSchema && !gbfs.InSlice(s.version, schemav2.Versions()) {
+			// client rejects feeds of versions without embedded schemas
+			s.v2.Options.ValidateSchema = false
+			r.add(&finding{
+				Severity: validate.SeverityWarning,
+				Feed:     gbfsv2.FeedNameGbfs,
+				Path:     "version",
+				Rule:     validatev2.RuleVersion,
+				Message:  "no schema for version " + s.version + ", schema validation skipped",
+			})
+		}
+		validateV2(s, r)
+	} else {
+		s.v3.Options.ValidateSchema = !*noSchema
+		validateV3(s, r)
+	}
+	err = write(r)
+	if err != nil {
+		return err
+	}
+	if r.Errors > 0 && *failOn != "none" || r.Warnings > 0 && *failOn == validate.SeverityWarning {
+		return exitError(1)
+	}
+	return nil
+}
+
+func validateV3(s *system, r *report) {
+	feeds := map[string]gbfs.Feed{}
+	for _, ref := range r.Feeds {
+		f, err := s.getV3(ref)
+		var schemaErrs schema.Errors
+		if errors.As(err, &schemaErrs) {
+			r.addV3(validate.SchemaFindings(ref.name, schemaErrs))
+		} else if err != nil {
+			r.addFetchError(ref.name, err)
+			continue
+		}
+		feeds[ref.name] = f
+		r.addV3(validate.Feed(f, s.version))
+	}
+	r.addV3(validate.System(feeds))
+}
+
+func validateV2(s *system, r *report) {
+	feeds := map[string]gbfsv2.Feed{}
+	for _, ref := range r.Feeds {
+		f, err := s.getV2(ref)
+		var schemaErrs schemav2.Errors
+		if errors.As(err, &schemaErrs) {
+			r.addV2(validatev2.SchemaFindings(ref.name, schemaErrs))
+		} else if err != nil {
+			r.addFetchError(ref.name, err)
+			continue
+		}
+		feeds[ref.name] = f
+		r.addV2(validatev2.Feed(f, s.version))
+	}
+	r.addV2(validatev2.System(feeds))
+}
+
+func writeText(r *report) error {
+	for _, f := range r.Findings {
+		path := f.Path
+		if path != "" {
+			path = " " + path
+		}
+		fmt.Fprintf(os.Stdout, "%s: %s%s: %s (%s)\n", f.Severity, f.Feed, path, f.Message, f.Rule)
+	}
+	fmt.Fprintf(os.Stdout, "%s (%s): %d feeds, %d errors, %d warnings\n", r.URL, r.Version, len(r.Feeds), r.Errors, r.Warnings)
+	return nil
+}
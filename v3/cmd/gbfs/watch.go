@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	gbfsv2 "github.com/petoc/gbfs/v2"
+	"github.com/petoc/gbfs/v3"
+)
+
+// watcher prints changes between consecutive versions of feeds.
+type watcher struct {
+	ignore  string
+	verbose bool
+	prev    map[string]any
+}
+
+func (w *watcher) printf(format string, a ...any) {
+	fmt.Printf(time.Now().Format("15:04:05")+" "+format+"\n", a...)
+}
+
+func (w *watcher) update(name string, feed any) {
+	b, err := json.Marshal(feed)
+	if err != nil {
+		w.error(err)
+		return
+	}
+	var doc any
+	err = json.Unmarshal(b, &doc)
+	if err != nil {
+		w.error(err)
+		return
+	}
+	prev, ok := w.prev[name]
+	w.prev[name] = doc
+	lastUpdated := ""
+	if m, ok := doc.(map[string]any); ok {
+		lastUpdated = jsonString(m["last_updated"])
+	}
+	if !ok {
+		w.printf("%s: loaded, last_updated %s", name, lastUpdated)
+		return
+	}
+	d := newDiffer(w.ignore)
+	d.diff(name, "", prev, doc)
+	if len(d.changes) == 0 {
+		return
+	}
+	w.printf("%s: %s, last_updated %s", name, formatSummary(summary(d.changes)), lastUpdated)
+	if w.verbose {
+		for _, c := range d.changes {
+			fmt.Println("  " + c.String())
+		}
+	}
+}
+
+func (w *watcher) error(err error) {
+	fmt.Fprintln(os.Stderr, time.Now().Format("15:04:05")+" "+err.Error())
+}
+
+func runWatch(args []string) error {
+	fs, o := newFlagSet("watch")
+	feeds := fs.String("feeds", "", "comma separated names of watched feeds (default all)")
+	stream := fs.String("stream", "", "URL of server stream handler, feeds are polled when stream is unavailable (v3 only)")
+	ignore := fs.String("ignore", "last_updated,ttl", "comma separated keys ignored in comparison")
+	verbose := fs.Bool("v", false, "print every change")
+	args, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	s, err := openSystem(args[0], o)
+	if err != nil {
+		return err
+	}
+	var names []string
+	if *feeds != "" {
+		names = strings.Split(*feeds, ",")
+	}
+	w := &watcher{
+		ignore:  *ignore,
+		verbose: *verbose,
+		prev:    map[string]any{},
+	}
+	if s.isV2() {
+		return s.v2.Subscribe(gbfsv2.ClientSubscribeOptions{
+			Languages: []string{s.language},
+			FeedNames: names,
+			Handler: func(c *gbfsv2.Client, f gbfsv2.Feed, err error) {
+				if err != nil {
+					w.error(err)
+					return
+				}
+				w.update(f.Name(), f)
+			},
+		})
+	}
+	return s.v3.Subscribe(gbfs.ClientSubscribeOptions{
+		FeedNames: names,
+		StreamURL: *stream,
+		Handler: func(c *gbfs.Client, f gbfs.Feed, err error) {
+			if err != nil {
+				w.error(err)
+				return
+			}
+			w.update(f.Name(), f)
+		},
+	})
+}
@@ -0,0 +1,549 @@
+package gbfs
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	gbfsv2 "github.com/petoc/gbfs/v2"
+)
+
+// ConvertFromV2 converts v2 feed published in one or more languages to v3.0
+// feed. Texts of all languages are merged into localized strings. Entities
+// are matched by position, so feeds of all languages are expected to list
+// them in same order. Feed free_bike_status is converted to vehicle_status.
+func ConvertFromV2(feeds []gbfsv2.Feed) (Feed, error) {
+	if len(feeds) == 0 {
+		return nil, ErrUnsupportedConversion
+	}
+	var r Feed
+	languages := []string{}
+	for _, feed := range feeds {
+		if feed == nil {
+			continue
+		}
+		f, err := ConvertFeedFromV2(feed)
+		if err != nil {
+			return nil, err
+		}
+		if language := feed.GetLanguage(); language != "" && !InSlice(language, languages) {
+			languages = append(languages, language)
+		}
+		if r == nil {
+			r = f
+			continue
+		}
+		if f.Name() != r.Name() {
+			return nil, NewError(f.Name()+": ", ErrUnsupportedConversion)
+		}
+		mergeLocalized(reflect.ValueOf(r), reflect.ValueOf(f))
+	}
+	if r == nil {
+		return nil, ErrUnsupportedConversion
+	}
+	if f, ok := r.(*FeedSystemInformation); ok && f.Data != nil && len(languages) > 0 {
+		f.Data.Languages = languages
+	}
+	return r, nil
+}
+
+// ConvertFeedFromV2 converts single v2 feed to v3.0 feed, texts are
+// converted to localized strings in language of feed.
+func ConvertFeedFromV2(feed gbfsv2.Feed) (Feed, error) {
+	var f Feed
+	language := feed.GetLanguage()
+	switch v := feed.(type) {
+	case *gbfsv2.FeedGbfsVersions:
+		f = gbfsVersionsFromV2(v)
+	case *gbfsv2.FeedSystemInformation:
+		f = systemInformationFromV2(v, language)
+	case *gbfsv2.FeedVehicleTypes:
+		f = vehicleTypesFromV2(v, language)
+	case *gbfsv2.FeedStationInformation:
+		f = stationInformationFromV2(v, language)
+	case *gbfsv2.FeedStationStatus:
+		f = stationStatusFromV2(v)
+	case *gbfsv2.FeedFreeBikeStatus:
+		f = freeBikeStatusFromV2(v)
+	case *gbfsv2.FeedSystemRegions:
+		f = systemRegionsFromV2(v, language)
+	case *gbfsv2.FeedSystemPricingPlans:
+		f = systemPricingPlansFromV2(v, language)
+	case *gbfsv2.FeedSystemAlerts:
+		f = systemAlertsFromV2(v, language)
+	case *gbfsv2.FeedGeofencingZones:
+		f = geofencingZonesFromV2(v, language)
+	default:
+		return nil, NewError(feed.Name()+": ", ErrUnsupportedConversion)
+	}
+	f.SetLastUpdated(*timestampFromV2(feed.GetLastUpdated()))
+	f.SetTTL(feed.GetTTL())
+	f.SetVersion(V30)
+	return f, nil
+}
+
+var localizedStringsType = reflect.TypeOf([]*LocalizedString{})
+
+// mergeLocalized appends localized strings of src to localized strings of
+// dst of same type.
+func mergeLocalized(dst, src reflect.Value) {
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() || src.IsNil() {
+			return
+		}
+		mergeLocalized(dst.Elem(), src.Elem())
+	case reflect.Struct:
+		for i := 0; i < dst.NumField(); i++ {
+			if dst.Type().Field(i).IsExported() {
+				mergeLocalized(dst.Field(i), src.Field(i))
+			}
+		}
+	case reflect.Slice:
+		if dst.Type() == localizedStringsType {
+			dst.Set(reflect.AppendSlice(dst, src))
+			return
+		}
+		for i := 0; i < dst.Len() && i < src.Len(); i++ {
+			mergeLocalized(dst.Index(i), src.Index(i))
+		}
+	}
+}
+
+func localizedFromV2(v *string, language string) []*LocalizedString {
+	if v == nil {
+		return nil
+	}
+	return []*LocalizedString{NewLocalizedString(*v, language)}
+}
+
+func timestampFromV2(t gbfsv2.Timestamp) *Timestamp {
+	return NewTimestamp(time.Unix(int64(t), 0).UTC().Format(time.RFC3339))
+}
+
+func timestampPtrFromV2(t *gbfsv2.Timestamp) *Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestampFromV2(*t)
+}
+
+func idFromV2(v *gbfsv2.ID) *ID {
+	if v == nil {
+		return nil
+	}
+	return NewID(string(*v))
+}
+
+func idsFromV2(v []*gbfsv2.ID) []*ID {
+	if v == nil {
+		return nil
+	}
+	ids := []*ID{}
+	for _, id := range v {
+		if id != nil {
+			ids = append(ids, idFromV2(id))
+		}
+	}
+	return ids
+}
+
+func booleanFromV2(v *gbfsv2.Boolean) *Boolean {
+	if v == nil {
+		return nil
+	}
+	return NewBoolean(bool(*v))
+}
+
+func coordinateFromV2(v *gbfsv2.Coordinate) *Coordinate {
+	if v == nil {
+		return nil
+	}
+	return NewCoordinate(v.Float64)
+}
+
+func lowerStrings(v []string) []string {
+	if v == nil {
+		return nil
+	}
+	r := []string{}
+	for _, s := range v {
+		r = append(r, strings.ToLower(s))
+	}
+	return r
+}
+
+func rentalURIsFromV2(v *gbfsv2.RentalURIs) *RentalURIs {
+	if v == nil {
+		return nil
+	}
+	return &RentalURIs{
+		Android: v.Android,
+		IOS:     v.IOS,
+		Web:     v.Web,
+	}
+}
+
+func rentalAppFromV2(v *gbfsv2.RentalApp) *RentalApp {
+	if v == nil {
+		return nil
+	}
+	return &RentalApp{
+		StoreURI:     v.StoreURI,
+		DiscoveryURI: v.DiscoveryURI,
+	}
+}
+
+func geometryFromV2(v *gbfsv2.GeoJSONGeometry) *GeoJSONGeometry {
+	if v == nil {
+		return nil
+	}
+	return &GeoJSONGeometry{
+		Type:        v.Type,
+		Coordinates: v.Coordinates,
+		Properties:  v.Properties,
+	}
+}
+
+func gbfsVersionsFromV2(f *gbfsv2.FeedGbfsVersions) *FeedGbfsVersions {
+	r := &FeedGbfsVersions{}
+	if f.Data == nil {
+		return r
+	}
+	r.Data = &FeedGbfsVersionsData{
+		Versions: []*FeedGbfsVersionsVersion{},
+	}
+	for _, v := range f.Data.Versions {
+		if v == nil {
+			continue
+		}
+		r.Data.Versions = append(r.Data.Versions, &FeedGbfsVersionsVersion{
+			Version: v.Version,
+			URL:     v.URL,
+		})
+	}
+	return r
+}
+
+func systemInformationFromV2(f *gbfsv2.FeedSystemInformation, language string) *FeedSystemInformation {
+	r := &FeedSystemInformation{}
+	d := f.Data
+	if d == nil {
+		return r
+	}
+	if d.Language != nil {
+		language = *d.Language
+	}
+	r.Data = &FeedSystemInformationData{
+		SystemID:                    idFromV2(d.SystemID),
+		Name:                        localizedFromV2(d.Name, language),
+		ShortName:                   localizedFromV2(d.ShortName, language),
+		Operator:                    localizedFromV2(d.Operator, language),
+		URL:                         d.URL,
+		PurchaseURL:                 d.PurchaseURL,
+		StartDate:                   d.StartDate,
+		PhoneNumber:                 d.PhoneNumber,
+		Email:                       d.Email,
+		FeedContactEmail:            d.FeedContactEmail,
+		Timezone:                    d.Timezone,
+		LicenseID:                   d.LicenseID,
+		LicenseURL:                  d.LicenseURL,
+		AttributionOrganizationName: localizedFromV2(d.AttributionOrganizationName, language),
+		AttributionURL:              d.AttributionURL,
+	}
+	if language != "" {
+		r.Data.Languages = []string{language}
+	}
+	if d.RentalApps != nil {
+		r.Data.RentalApps = &RentalApps{
+			Android: rentalAppFromV2(d.RentalApps.Android),
+			IOS:     rentalAppFromV2(d.RentalApps.IOS),
+		}
+	}
+	return r
+}
+
+func vehicleTypesFromV2(f *gbfsv2.FeedVehicleTypes, language string) *FeedVehicleTypes {
+	r := &FeedVehicleTypes{}
+	if f.Data == nil {
+		return r
+	}
+	r.Data = &FeedVehicleTypesData{
+		VehicleTypes: []*FeedVehicleTypesVehicleType{},
+	}
+	for _, v := range f.Data.VehicleTypes {
+		if v == nil {
+			continue
+		}
+		vehicleType := &FeedVehicleTypesVehicleType{
+			VehicleTypeID:  idFromV2(v.VehicleTypeID),
+			FormFactor:     v.FormFactor,
+			PropulsionType: v.PropulsionType,
+			MaxRangeMeters: v.MaxRangeMeters,
+			Name:           localizedFromV2(v.Name, language),
+		}
+		if v.FormFactor != nil && *v.FormFactor == "scooter" {
+			vehicleType.FormFactor = NewString(FormFactorScooterStanding)
+		}
+		r.Data.VehicleTypes = append(r.Data.VehicleTypes, vehicleType)
+	}
+	return r
+}
+
+func vehicleTypesCapacityFromV2(v map[gbfsv2.ID]int64) []*VehicleTypesCapacity {
+	if v == nil {
+		return nil
+	}
+	ids := []string{}
+	for id := range v {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+	r := []*VehicleTypesCapacity{}
+	for _, id := range ids {
+		r = append(r, NewVehicleTypesCapacity([]string{id}, v[gbfsv2.ID(id)]))
+	}
+	return r
+}
+
+func stationInformationFromV2(f *gbfsv2.FeedStationInformation, language string) *FeedStationInformation {
+	r := &FeedStationInformation{}
+	if f.Data == nil {
+		return r
+	}
+	r.Data = &FeedStationInformationData{
+		Stations: []*FeedStationInformationStation{},
+	}
+	for _, v := range f.Data.Stations {
+		if v == nil {
+			continue
+		}
+		r.Data.Stations = append(r.Data.Stations, &FeedStationInformationStation{
+			StationID:            idFromV2(v.StationID),
+			Name:                 localizedFromV2(v.Name, language),
+			ShortName:            localizedFromV2(v.ShortName, language),
+			Lat:                  coordinateFromV2(v.Lat),
+			Lon:                  coordinateFromV2(v.Lon),
+			Address:              v.Address,
+			CrossStreet:          v.CrossStreet,
+			RegionID:             idFromV2(v.RegionID),
+			PostCode:             v.PostCode,
+			RentalMethods:        lowerStrings(v.RentalMethods),
+			IsVirtualStation:     booleanFromV2(v.IsVirtualStation),
+			StationArea:          geometryFromV2(v.StationArea),
+			Capacity:             v.Capacity,
+			VehicleTypesCapacity: vehicleTypesCapacityFromV2(v.VehicleCapacity),
+			VehicleDocksCapacity: vehicleTypesCapacityFromV2(v.VehicleTypeCapacity),
+			IsValetStation:       booleanFromV2(v.IsValetStation),
+			RentalURIs:           rentalURIsFromV2(v.RentalURIs),
+		})
+	}
+	return r
+}
+
+func stationStatusFromV2(f *gbfsv2.FeedStationStatus) *FeedStationStatus {
+	r := &FeedStationStatus{}
+	if f.Data == nil {
+		return r
+	}
+	r.Data = &FeedStationStatusData{
+		Stations: []*FeedStationStatusStation{},
+	}
+	for _, v := range f.Data.Stations {
+		if v == nil {
+			continue
+		}
+		station := &FeedStationStatusStation{
+			StationID:            idFromV2(v.StationID),
+			NumVehiclesAvailable: v.NumBikesAvailable,
+			NumVehiclesDisabled:  v.NumBikesDisabled,
+			NumDocksAvailable:    v.NumDocksAvailable,
+			NumDocksDisabled:     v.NumDocksDisabled,
+			IsInstalled:          booleanFromV2(v.IsInstalled),
+			IsRenting:            booleanFromV2(v.IsRenting),
+			IsReturning:          booleanFromV2(v.IsReturning),
+			LastReported:         timestampPtrFromV2(v.LastReported),
+		}
+		for _, t := range v.VehicleTypesAvailable {
+			if t == nil {
+				continue
+			}
+			station.VehicleTypesAvailable = append(station.VehicleTypesAvailable, &VehicleTypeCapacity{
+				VehicleTypeID: idFromV2(t.VehicleTypeID),
+				Count:         t.Count,
+			})
+		}
+		for _, d := range v.VehicleDocksAvailable {
+			if d == nil {
+				continue
+			}
+			station.VehicleDocksAvailable = append(station.VehicleDocksAvailable, &VehicleTypesCapacity{
+				VehicleTypeIDs: idsFromV2(d.VehicleTypeIDs),
+				Count:          d.Count,
+			})
+		}
+		r.Data.Stations = append(r.Data.Stations, station)
+	}
+	return r
+}
+
+func freeBikeStatusFromV2(f *gbfsv2.FeedFreeBikeStatus) *FeedVehicleStatus {
+	r := &FeedVehicleStatus{}
+	if f.Data == nil {
+		return r
+	}
+	r.Data = &FeedVehicleStatusData{
+		Vehicles: []*FeedVehicleStatusVehicle{},
+	}
+	for _, v := range f.Data.Bikes {
+		if v == nil {
+			continue
+		}
+		r.Data.Vehicles = append(r.Data.Vehicles, &FeedVehicleStatusVehicle{
+			VehicleID:          idFromV2(v.BikeID),
+			Lat:                coordinateFromV2(v.Lat),
+			Lon:                coordinateFromV2(v.Lon),
+			IsReserved:         booleanFromV2(v.IsReserved),
+			IsDisabled:         booleanFromV2(v.IsDisabled),
+			RentalURIs:         rentalURIsFromV2(v.RentalURIs),
+			VehicleTypeID:      idFromV2(v.VehicleTypeID),
+			LastReported:       timestampPtrFromV2(v.LastReported),
+			CurrentRangeMeters: v.CurrentRangeMeters,
+		})
+	}
+	return r
+}
+
+func systemRegionsFromV2(f *gbfsv2.FeedSystemRegions, language string) *FeedSystemRegions {
+	r := &FeedSystemRegions{}
+	if f.Data == nil {
+		return r
+	}
+	r.Data = &FeedSystemRegionsData{
+		Regions: []*FeedSystemRegionsRegion{},
+	}
+	for _, v := range f.Data.Regions {
+		if v == nil {
+			continue
+		}
+		r.Data.Regions = append(r.Data.Regions, &FeedSystemRegionsRegion{
+			RegionID: idFromV2(v.RegionID),
+			Name:     localizedFromV2(v.Name, language),
+		})
+	}
+	return r
+}
+
+func systemPricingPlansFromV2(f *gbfsv2.FeedSystemPricingPlans, language string) *FeedSystemPricingPlans {
+	r := &FeedSystemPricingPlans{}
+	if f.Data == nil {
+		return r
+	}
+	r.Data = &FeedSystemPricingPlansData{
+		Plans: []*FeedSystemPricingPlansPricingPlan{},
+	}
+	for _, v := range f.Data.Plans {
+		if v == nil {
+			continue
+		}
+		plan := &FeedSystemPricingPlansPricingPlan{
+			PlanID:      idFromV2(v.PlanID),
+			URL:         v.URL,
+			Name:        localizedFromV2(v.Name, language),
+			Currency:    v.Currency,
+			IsTaxable:   booleanFromV2(v.IsTaxable),
+			Description: localizedFromV2(v.Description, language),
+		}
+		if v.Price != nil {
+			plan.Price = NewPrice(v.Price.Float64)
+		}
+		r.Data.Plans = append(r.Data.Plans, plan)
+	}
+	return r
+}
+
+func systemAlertsFromV2(f *gbfsv2.FeedSystemAlerts, language string) *FeedSystemAlerts {
+	r := &FeedSystemAlerts{}
+	if f.Data == nil {
+		return r
+	}
+	r.Data = &FeedSystemAlertsData{
+		Alerts: []*FeedSystemAlertsAlert{},
+	}
+	for _, v := range f.Data.Alerts {
+		if v == nil {
+			continue
+		}
+		alert := &FeedSystemAlertsAlert{
+			AlertID:     idFromV2(v.AlertID),
+			StationIDs:  idsFromV2(v.StationIDs),
+			RegionIDs:   idsFromV2(v.RegionIDs),
+			URL:         localizedFromV2(v.URL, language),
+			Summary:     localizedFromV2(v.Summary, language),
+			Description: localizedFromV2(v.Description, language),
+			LastUpdated: timestampPtrFromV2(v.LastUpdated),
+		}
+		if v.Type != nil {
+			alert.Type = NewString(strings.ToLower(*v.Type))
+		}
+		for _, t := range v.Times {
+			if t == nil {
+				continue
+			}
+			alert.Times = append(alert.Times, &FeedSystemAlertsAlertTime{
+				Start: timestampPtrFromV2(t.Start),
+				End:   timestampPtrFromV2(t.End),
+			})
+		}
+		r.Data.Alerts = append(r.Data.Alerts, alert)
+	}
+	return r
+}
+
+func geofencingZonesFromV2(f *gbfsv2.FeedGeofencingZones, language string) *FeedGeofencingZones {
+	r := &FeedGeofencingZones{}
+	if f.Data == nil {
+		return r
+	}
+	features := []*FeedGeofencingZonesGeoJSONFeature{}
+	if f.Data.GeofencingZones != nil {
+		for _, v := range f.Data.GeofencingZones.Features {
+			if v == nil {
+				continue
+			}
+			var properties *FeedGeofencingZonesGeoJSONFeatureProperties
+			if v.Properties != nil {
+				properties = &FeedGeofencingZonesGeoJSONFeatureProperties{
+					Name:  localizedFromV2(v.Properties.Name, language),
+					Start: timestampPtrFromV2(v.Properties.Start),
+					End:   timestampPtrFromV2(v.Properties.End),
+				}
+				for _, rule := range v.Properties.Rules {
+					if rule == nil {
+						continue
+					}
+					properties.Rules = append(properties.Rules, geofencingZonesRuleFromV2(rule))
+				}
+			}
+			features = append(features, NewFeedGeofencingZonesGeoJSONFeature(geometryFromV2(v.Geometry), properties))
+		}
+	}
+	r.Data = &FeedGeofencingZonesData{
+		GeofencingZones: NewFeedGeofencingZonesGeoJSONFeatureCollection(features),
+		GlobalRules:     []*FeedGeofencingZonesRule{},
+	}
+	return r
+}
+
+// geofencingZonesRuleFromV2 applies ride_allowed of v2 to both start and
+// end of ride.
+func geofencingZonesRuleFromV2(v *gbfsv2.FeedGeofencingZonesGeoJSONFeaturePropertiesRule) *FeedGeofencingZonesRule {
+	return &FeedGeofencingZonesRule{
+		VehicleTypeIDs:     idsFromV2(v.VehicleTypeIDs),
+		RideStartAllowed:   booleanFromV2(v.RideAllowed),
+		RideEndAllowed:     booleanFromV2(v.RideAllowed),
+		RideThroughAllowed: booleanFromV2(v.RideThroughAllowed),
+		MaximumSpeedKph:    v.MaximumSpeedKph,
+	}
+}
@@ -0,0 +1,289 @@
+package gbfs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	gbfsv2 "github.com/petoc/gbfs/v2"
+)
+
+func testStamp(f Feed) Feed {
+	f.SetLastUpdated(*NewTimestamp("2024-01-02T03:04:05Z"))
+	f.SetTTL(60)
+	f.SetVersion(V30)
+	return f
+}
+
+func testConvertFeeds() []Feed {
+	names := func(en, sk string) []*LocalizedString {
+		return []*LocalizedString{NewLocalizedString(en, "en"), NewLocalizedString(sk, "sk")}
+	}
+	return []Feed{
+		testStamp(&FeedSystemInformation{Data: &FeedSystemInformationData{
+			SystemID:  NewID("system"),
+			Languages: []string{"en", "sk"},
+			Name:      names("Bikes", "Bicykle"),
+			Timezone:  NewString("Europe/Bratislava"),
+		}}),
+		testStamp(&FeedVehicleTypes{Data: &FeedVehicleTypesData{
+			VehicleTypes: []*FeedVehicleTypesVehicleType{
+				{VehicleTypeID: NewID("bike"), FormFactor: NewString(FormFactorBicycle), PropulsionType: NewString(PropulsionTypeHuman), Name: names("Bike", "Bicykel")},
+			},
+		}}),
+		testStamp(&FeedStationInformation{Data: &FeedStationInformationData{
+			Stations: []*FeedStationInformationStation{
+				{StationID: NewID("s1"), Name: names("Main", "Hlavna"), Lat: NewCoordinate(48.1), Lon: NewCoordinate(17.1), Capacity: NewInt64(10)},
+				{StationID: NewID("s2"), Name: names("Park", "Park"), Lat: NewCoordinate(48.2), Lon: NewCoordinate(17.2)},
+			},
+		}}),
+		testStamp(&FeedStationStatus{Data: &FeedStationStatusData{
+			Stations: []*FeedStationStatusStation{
+				{
+					StationID:             NewID("s1"),
+					NumVehiclesAvailable:  NewInt64(2),
+					VehicleTypesAvailable: []*VehicleTypeCapacity{NewVehicleTypeCapacity("bike", 2)},
+					NumDocksAvailable:     NewInt64(8),
+					IsInstalled:           NewBoolean(true),
+					IsRenting:             NewBoolean(true),
+					IsReturning:           NewBoolean(false),
+					LastReported:          NewTimestamp("2024-01-02T03:00:00Z"),
+				},
+			},
+		}}),
+		testStamp(&FeedVehicleStatus{Data: &FeedVehicleStatusData{
+			Vehicles: []*FeedVehicleStatusVehicle{
+				{VehicleID: NewID("v1"), Lat: NewCoordinate(48.15), Lon: NewCoordinate(17.15), IsReserved: NewBoolean(false), IsDisabled: NewBoolean(false), VehicleTypeID: NewID("bike")},
+			},
+		}}),
+		testStamp(&FeedSystemRegions{Data: &FeedSystemRegionsData{
+			Regions: []*FeedSystemRegionsRegion{{RegionID: NewID("r1"), Name: names("Center", "Centrum")}},
+		}}),
+		testStamp(&FeedSystemPricingPlans{Data: &FeedSystemPricingPlansData{
+			Plans: []*FeedSystemPricingPlansPricingPlan{
+				{PlanID: NewID("p1"), Name: names("Basic", "Zakladny"), Currency: NewString("EUR"), Price: NewPrice(1.5), IsTaxable: NewBoolean(false), Description: names("Flat", "Pausal")},
+			},
+		}}),
+	}
+}
+
+func testJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	for _, feed := range testConvertFeeds() {
+		v2, err := ConvertToV2(feed, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", feed.Name(), err)
+		}
+		expected := 1
+		if localizedFeed(feed.Name()) {
+			expected = 2
+		}
+		if len(v2) != expected {
+			t.Fatalf("%s: expected %d v2 feeds, got %d", feed.Name(), expected, len(v2))
+		}
+		for _, f := range v2 {
+			if f.GetVersion() != gbfsv2.V23 {
+				t.Errorf("%s: expected version %s, got %s", f.Name(), gbfsv2.V23, f.GetVersion())
+			}
+			if int64(f.GetLastUpdated()) != time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Unix() {
+				t.Errorf("%s: expected POSIX last_updated, got %d", f.Name(), f.GetLastUpdated())
+			}
+		}
+		back, err := ConvertFromV2(v2)
+		if err != nil {
+			t.Fatalf("%s: %v", feed.Name(), err)
+		}
+		if a, b := testJSON(t, feed), testJSON(t, back); a != b {
+			t.Errorf("%s: round trip changed feed\n%s\n%s", feed.Name(), a, b)
+		}
+	}
+}
+
+func TestConvertToV2Languages(t *testing.T) {
+	feed := testConvertFeeds()[2]
+	v2, err := ConvertToV2(feed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, language := range []string{"en", "sk"} {
+		f, ok := v2[i].(*gbfsv2.FeedStationInformation)
+		if !ok || f.GetLanguage() != language {
+			t.Fatalf("expected station_information in %s, got %v", language, v2[i].GetLanguage())
+		}
+		name := *f.Data.Stations[0].Name
+		if expected := map[string]string{"en": "Main", "sk": "Hlavna"}[language]; name != expected {
+			t.Errorf("%s: expected name %s, got %s", language, expected, name)
+		}
+	}
+	vehicles, err := ConvertToV2(testConvertFeeds()[4], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vehicles[0].Name() != gbfsv2.FeedNameFreeBikeStatus {
+		t.Errorf("expected free_bike_status, got %s", vehicles[0].Name())
+	}
+}
+
+func TestConvertToV2PricingPlans(t *testing.T) {
+	feed := testStamp(&FeedSystemPricingPlans{Data: &FeedSystemPricingPlansData{
+		Plans: []*FeedSystemPricingPlansPricingPlan{
+			{
+				PlanID:        NewID("p1"),
+				Name:          []*LocalizedString{NewLocalizedString("Per minute", "en")},
+				Currency:      NewString("EUR"),
+				Price:         NewPrice(0),
+				IsTaxable:     NewBoolean(false),
+				Description:   []*LocalizedString{NewLocalizedString("0.20 EUR per minute", "en")},
+				PerMinPricing: &PerUnitPricing{Start: NewInt64(0), Rate: NewFloat64(0.2), Interval: NewInt64(1)},
+				PerKmPricing:  &PerUnitPricing{Start: NewInt64(0), Rate: NewFloat64(0.5), Interval: NewInt64(1)},
+			},
+		},
+	}})
+	v2, err := ConvertToV2(feed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := testJSON(t, v2[0])
+	if strings.Contains(s, "per_min_pricing") || strings.Contains(s, "per_km_pricing") {
+		t.Errorf("expected per unit pricing to be dropped, got %s", s)
+	}
+	if !strings.Contains(s, `"price":0`) {
+		t.Errorf("expected flat price, got %s", s)
+	}
+}
+
+func TestConvertNullElements(t *testing.T) {
+	feeds := []Feed{
+		&FeedVehicleTypes{Data: &FeedVehicleTypesData{VehicleTypes: []*FeedVehicleTypesVehicleType{nil}}},
+		&FeedStationInformation{Data: &FeedStationInformationData{Stations: []*FeedStationInformationStation{nil}}},
+		&FeedStationStatus{Data: &FeedStationStatusData{Stations: []*FeedStationStatusStation{
+			nil,
+			{StationID: NewID("s1"), VehicleTypesAvailable: []*VehicleTypeCapacity{nil}, VehicleDocksAvailable: []*VehicleTypesCapacity{nil}},
+		}}},
+		&FeedVehicleStatus{Data: &FeedVehicleStatusData{Vehicles: []*FeedVehicleStatusVehicle{nil, {VehicleID: NewID("docked")}}}},
+		&FeedSystemRegions{Data: &FeedSystemRegionsData{Regions: []*FeedSystemRegionsRegion{nil}}},
+		&FeedSystemPricingPlans{Data: &FeedSystemPricingPlansData{Plans: []*FeedSystemPricingPlansPricingPlan{nil}}},
+		&FeedSystemAlerts{Data: &FeedSystemAlertsData{Alerts: []*FeedSystemAlertsAlert{nil, {AlertID: NewID("a1"), Times: []*FeedSystemAlertsAlertTime{nil}}}}},
+		&FeedGeofencingZones{Data: &FeedGeofencingZonesData{GeofencingZones: NewFeedGeofencingZonesGeoJSONFeatureCollection([]*FeedGeofencingZonesGeoJSONFeature{
+			nil,
+			NewFeedGeofencingZonesGeoJSONFeature(nil, &FeedGeofencingZonesGeoJSONFeatureProperties{Rules: []*FeedGeofencingZonesRule{nil}}),
+		})}},
+		&FeedGbfsVersions{Data: &FeedGbfsVersionsData{Versions: []*FeedGbfsVersionsVersion{nil}}},
+	}
+	for _, feed := range feeds {
+		v2, err := ConvertToV2(feed, []string{"en"})
+		if err != nil {
+			t.Fatalf("%s: %v", feed.Name(), err)
+		}
+		if strings.Contains(testJSON(t, v2), "null]") {
+			t.Errorf("%s: expected null elements to be skipped, got %s", feed.Name(), testJSON(t, v2))
+		}
+		if _, ok := v2[0].(*gbfsv2.FeedFreeBikeStatus); ok && strings.Contains(testJSON(t, v2), "docked") {
+			t.Error("expected vehicle without position to be left out")
+		}
+	}
+	for _, b := range []string{
+		`{"data":{"stations":[null,{"station_id":"s1","vehicle_types_available":[null]}]}}`,
+		`{"data":{"bikes":[null]}}`,
+		`{"data":{"plans":[null]}}`,
+		`{"data":{"alerts":[null,{"alert_id":"a1","times":[null]}]}}`,
+	} {
+		var f gbfsv2.Feed
+		switch {
+		case strings.Contains(b, "bikes"):
+			f = &gbfsv2.FeedFreeBikeStatus{}
+		case strings.Contains(b, "plans"):
+			f = &gbfsv2.FeedSystemPricingPlans{}
+		case strings.Contains(b, "alerts"):
+			f = &gbfsv2.FeedSystemAlerts{}
+		default:
+			f = &gbfsv2.FeedStationStatus{}
+		}
+		if err := json.Unmarshal([]byte(b), f); err != nil {
+			t.Fatal(err)
+		}
+		r, err := ConvertFromV2([]gbfsv2.Feed{nil, f})
+		if err != nil {
+			t.Fatalf("%s: %v", f.Name(), err)
+		}
+		if strings.Contains(testJSON(t, r), "null]") {
+			t.Errorf("%s: expected null elements to be skipped, got %s", f.Name(), testJSON(t, r))
+		}
+	}
+}
+
+func TestServerV2Rendition(t *testing.T) {
+	store := NewMemoryFeedStore()
+	feeds := testConvertFeeds()
+	s, err := NewServer(ServerOptions{
+		SystemID:   "system",
+		Store:      store,
+		BaseURL:    "http://127.0.0.1:8080",
+		BasePath:   "v3",
+		V2BasePath: "v2",
+		DefaultTTL: 60,
+		UpdateHandler: func(s *Server, f Feed, path string, err error) {
+		},
+		FeedHandlers: []*FeedHandler{
+			{
+				Handler: func(s *Server) ([]Feed, error) {
+					return feeds, nil
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	var gbfs []byte
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		gbfs, err = store.Get("v2/gbfs.json")
+		if err == nil && strings.Contains(string(gbfs), "free_bike_status") && strings.Contains(string(gbfs), `"sk"`) {
+			break
+		}
+	}
+	f := &gbfsv2.FeedGbfs{}
+	if err := json.Unmarshal(gbfs, f); err != nil {
+		t.Fatalf("v2 gbfs.json was not published: %v", err)
+	}
+	for _, language := range []string{"en", "sk"} {
+		l, ok := f.Data[language]
+		if !ok {
+			t.Fatalf("expected gbfs.json keyed by %s, got %s", language, gbfs)
+		}
+		urls := []string{}
+		for _, feed := range l.Feeds {
+			urls = append(urls, *feed.URL)
+		}
+		if !InSlice("http://127.0.0.1:8080/v2/"+language+"/system_information.json", urls) {
+			t.Errorf("%s: expected system_information in %v", language, urls)
+		}
+	}
+	b, err := store.Get("v2/sk/system_information.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	information := &gbfsv2.FeedSystemInformation{}
+	if err := json.Unmarshal(b, information); err != nil {
+		t.Fatal(err)
+	}
+	if *information.Data.Name != "Bicykle" || information.GetLastUpdated() <= 0 {
+		t.Errorf("unexpected v2 system_information %s", b)
+	}
+	if _, err := store.Get("v2/free_bike_status.json"); err != nil {
+		t.Errorf("expected free_bike_status without language, got %v", err)
+	}
+	if b, err := store.Get("v2/gbfs_versions.json"); err != nil || !strings.Contains(string(b), "v3/gbfs.json") {
+		t.Errorf("expected gbfs_versions linking v3, got %s, %v", b, err)
+	}
+}
@@ -0,0 +1,623 @@
+package gbfs
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	gbfsv2 "github.com/petoc/gbfs/v2"
+)
+
+var ErrUnsupportedConversion = errors.New("unsupported conversion")
+
+// ConvertToV2 converts feed to v2.3 feeds. Feeds with localized strings are
+// converted once for every language, other feeds are converted without
+// language. When no languages are given, they are collected from feed.
+func ConvertToV2(feed Feed, languages []string) ([]gbfsv2.Feed, error) {
+	if !localizedFeed(feed.Name()) {
+		f, err := ConvertFeedToV2(feed, "")
+		if err != nil {
+			return nil, err
+		}
+		return []gbfsv2.Feed{f}, nil
+	}
+	if len(languages) == 0 {
+		languages = FeedLanguages(feed)
+	}
+	if len(languages) == 0 {
+		languages = []string{""}
+	}
+	feeds := []gbfsv2.Feed{}
+	for _, language := range languages {
+		f, err := ConvertFeedToV2(feed, language)
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, nil
+}
+
+func localizedFeed(name string) bool {
+	return InSlice(name, []string{
+		FeedNameGeofencingZones,
+		FeedNameStationInformation,
+		FeedNameSystemAlerts,
+		FeedNameSystemInformation,
+		FeedNameSystemPricingPlans,
+		FeedNameSystemRegions,
+		FeedNameVehicleTypes,
+	})
+}
+
+// FeedLanguages returns sorted languages used by localized strings of feed.
+func FeedLanguages(feed Feed) []string {
+	if f, ok := feed.(*FeedSystemInformation); ok && f.Data != nil && len(f.Data.Languages) > 0 {
+		return f.Data.Languages
+	}
+	seen := map[string]bool{}
+	add := func(ls []*LocalizedString) {
+		for _, l := range ls {
+			if l != nil && l.Language != "" {
+				seen[l.Language] = true
+			}
+		}
+	}
+	switch f := feed.(type) {
+	case *FeedSystemInformation:
+		if f.Data != nil {
+			add(f.Data.Name)
+		}
+	case *FeedVehicleTypes:
+		if f.Data != nil {
+			for _, v := range f.Data.VehicleTypes {
+				if v == nil {
+					continue
+				}
+				add(v.Name)
+			}
+		}
+	case *FeedStationInformation:
+		if f.Data != nil {
+			for _, v := range f.Data.Stations {
+				if v == nil {
+					continue
+				}
+				add(v.Name)
+			}
+		}
+	case *FeedSystemRegions:
+		if f.Data != nil {
+			for _, v := range f.Data.Regions {
+				if v == nil {
+					continue
+				}
+				add(v.Name)
+			}
+		}
+	case *FeedSystemPricingPlans:
+		if f.Data != nil {
+			for _, v := range f.Data.Plans {
+				if v == nil {
+					continue
+				}
+				add(v.Name)
+			}
+		}
+	case *FeedSystemAlerts:
+		if f.Data != nil {
+			for _, v := range f.Data.Alerts {
+				if v == nil {
+					continue
+				}
+				add(v.Summary)
+			}
+		}
+	case *FeedGeofencingZones:
+		if f.Data != nil && f.Data.GeofencingZones != nil {
+			for _, v := range f.Data.GeofencingZones.Features {
+				if v == nil {
+					continue
+				}
+				if v.Properties != nil {
+					add(v.Properties.Name)
+				}
+			}
+		}
+	}
+	languages := []string{}
+	for language := range seen {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// ConvertFeedToV2 converts feed to single v2.3 feed, localized strings are
+// resolved for given language. Feed vehicle_status is converted to
+// free_bike_status.
+func ConvertFeedToV2(feed Feed, language string) (gbfsv2.Feed, error) {
+	var f gbfsv2.Feed
+	switch v := feed.(type) {
+	case *FeedGbfsVersions:
+		f = gbfsVersionsToV2(v)
+	case *FeedSystemInformation:
+		f = systemInformationToV2(v, language)
+	case *FeedVehicleTypes:
+		f = vehicleTypesToV2(v, language)
+	case *FeedStationInformation:
+		f = stationInformationToV2(v, language)
+	case *FeedStationStatus:
+		f = stationStatusToV2(v)
+	case *FeedVehicleStatus:
+		f = vehicleStatusToV2(v)
+	case *FeedSystemRegions:
+		f = systemRegionsToV2(v, language)
+	case *FeedSystemPricingPlans:
+		f = systemPricingPlansToV2(v, language)
+	case *FeedSystemAlerts:
+		f = systemAlertsToV2(v, language)
+	case *FeedGeofencingZones:
+		f = geofencingZonesToV2(v, language)
+	default:
+		return nil, NewError(feed.Name()+": ", ErrUnsupportedConversion)
+	}
+	if language != "" {
+		f.SetLanguage(language)
+	}
+	if lastUpdated := timestampToV2(feed.GetLastUpdated()); lastUpdated != nil {
+		f.SetLastUpdated(*lastUpdated)
+	}
+	f.SetTTL(feed.GetTTL())
+	f.SetVersion(gbfsv2.V23)
+	return f, nil
+}
+
+func localizedText(ls []*LocalizedString, language string) *string {
+	for _, l := range ls {
+		if l != nil && l.Language == language {
+			return NewString(l.Text)
+		}
+	}
+	for _, l := range ls {
+		if l != nil {
+			return NewString(l.Text)
+		}
+	}
+	return nil
+}
+
+func timestampToV2(t Timestamp) *gbfsv2.Timestamp {
+	tt, err := t.Time()
+	if err != nil {
+		return nil
+	}
+	return gbfsv2.NewTimestamp(tt.Unix())
+}
+
+func timestampPtrToV2(t *Timestamp) *gbfsv2.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestampToV2(*t)
+}
+
+func idToV2(v *ID) *gbfsv2.ID {
+	if v == nil {
+		return nil
+	}
+	return gbfsv2.NewID(string(*v))
+}
+
+func idsToV2(v []*ID) []*gbfsv2.ID {
+	if v == nil {
+		return nil
+	}
+	ids := []*gbfsv2.ID{}
+	for _, id := range v {
+		if id != nil {
+			ids = append(ids, idToV2(id))
+		}
+	}
+	return ids
+}
+
+func booleanToV2(v *Boolean) *gbfsv2.Boolean {
+	if v == nil {
+		return nil
+	}
+	return gbfsv2.NewBoolean(bool(*v))
+}
+
+func coordinateToV2(v *Coordinate) *gbfsv2.Coordinate {
+	if v == nil {
+		return nil
+	}
+	return gbfsv2.NewCoordinate(v.Float64)
+}
+
+func upperStrings(v []string) []string {
+	if v == nil {
+		return nil
+	}
+	r := []string{}
+	for _, s := range v {
+		r = append(r, strings.ToUpper(s))
+	}
+	return r
+}
+
+func rentalURIsToV2(v *RentalURIs) *gbfsv2.RentalURIs {
+	if v == nil {
+		return nil
+	}
+	return &gbfsv2.RentalURIs{
+		Android: v.Android,
+		IOS:     v.IOS,
+		Web:     v.Web,
+	}
+}
+
+func rentalAppToV2(v *RentalApp) *gbfsv2.RentalApp {
+	if v == nil {
+		return nil
+	}
+	return &gbfsv2.RentalApp{
+		StoreURI:     v.StoreURI,
+		DiscoveryURI: v.DiscoveryURI,
+	}
+}
+
+func geometryToV2(v *GeoJSONGeometry) *gbfsv2.GeoJSONGeometry {
+	if v == nil {
+		return nil
+	}
+	return &gbfsv2.GeoJSONGeometry{
+		Type:        v.Type,
+		Coordinates: v.Coordinates,
+		Properties:  v.Properties,
+	}
+}
+
+func gbfsVersionsToV2(f *FeedGbfsVersions) *gbfsv2.FeedGbfsVersions {
+	r := &gbfsv2.FeedGbfsVersions{}
+	if f.Data == nil {
+		return r
+	}
+	r.Data = &gbfsv2.FeedGbfsVersionsData{
+		Versions: []*gbfsv2.FeedGbfsVersionsVersion{},
+	}
+	for _, v := range f.Data.Versions {
+		if v == nil {
+			continue
+		}
+		r.Data.Versions = append(r.Data.Versions, &gbfsv2.FeedGbfsVersionsVersion{
+			Version: v.Version,
+			URL:     v.URL,
+		})
+	}
+	return r
+}
+
+func systemInformationToV2(f *FeedSystemInformation, language string) *gbfsv2.FeedSystemInformation {
+	r := &gbfsv2.FeedSystemInformation{}
+	d := f.Data
+	if d == nil {
+		return r
+	}
+	r.Data = &gbfsv2.FeedSystemInformationData{
+		SystemID:                    idToV2(d.SystemID),
+		Name:                        localizedText(d.Name, language),
+		ShortName:                   localizedText(d.ShortName, language),
+		Operator:                    localizedText(d.Operator, language),
+		URL:                         d.URL,
+		PurchaseURL:                 d.PurchaseURL,
+		StartDate:                   d.StartDate,
+		PhoneNumber:                 d.PhoneNumber,
+		Email:                       d.Email,
+		FeedContactEmail:            d.FeedContactEmail,
+		Timezone:                    d.Timezone,
+		LicenseID:                   d.LicenseID,
+		LicenseURL:                  d.LicenseURL,
+		AttributionOrganizationName: localizedText(d.AttributionOrganizationName, language),
+		AttributionURL:              d.AttributionURL,
+	}
+	if language != "" {
+		r.Data.Language = NewString(language)
+	}
+	if d.RentalApps != nil {
+		r.Data.RentalApps = &gbfsv2.RentalApps{
+			Android: rentalAppToV2(d.RentalApps.Android),
+			IOS:     rentalAppToV2(d.RentalApps.IOS),
+		}
+	}
+	return r
+}
+
+func vehicleTypesToV2(f *FeedVehicleTypes, language string) *gbfsv2.FeedVehicleTypes {
+	r := &gbfsv2.FeedVehicleTypes{}
+	if f.Data == nil {
+		return r
+	}
+	r.Data = &gbfsv2.FeedVehicleTypesData{
+		VehicleTypes: []*gbfsv2.FeedVehicleTypesVehicleType{},
+	}
+	for _, v := range f.Data.VehicleTypes {
+		if v == nil {
+			continue
+		}
+		r.Data.VehicleTypes = append(r.Data.VehicleTypes, &gbfsv2.FeedVehicleTypesVehicleType{
+			VehicleTypeID:  idToV2(v.VehicleTypeID),
+			FormFactor:     v.FormFactor,
+			PropulsionType: v.PropulsionType,
+			MaxRangeMeters: v.MaxRangeMeters,
+			Name:           localizedText(v.Name, language),
+		})
+	}
+	return r
+}
+
+func vehicleTypesCapacityToV2(v []*VehicleTypesCapacity) map[gbfsv2.ID]int64 {
+	if v == nil {
+		return nil
+	}
+	m := map[gbfsv2.ID]int64{}
+	for _, c := range v {
+		if c == nil || c.Count == nil {
+			continue
+		}
+		for _, id := range c.VehicleTypeIDs {
+			if id != nil {
+				m[gbfsv2.ID(*id)] += *c.Count
+			}
+		}
+	}
+	return m
+}
+
+func stationInformationToV2(f *FeedStationInformation, language string) *gbfsv2.FeedStationInformation {
+	r := &gbfsv2.FeedStationInformation{}
+	if f.Data == nil {
+		return r
+	}
+	r.Data = &gbfsv2.FeedStationInformationData{
+		Stations: []*gbfsv2.FeedStationInformationStation{},
+	}
+	for _, v := range f.Data.Stations {
+		if v == nil {
+			continue
+		}
+		r.Data.Stations = append(r.Data.Stations, &gbfsv2.FeedStationInformationStation{
+			StationID:           idToV2(v.StationID),
+			Name:                localizedText(v.Name, language),
+			ShortName:           localizedText(v.ShortName, language),
+			Lat:                 coordinateToV2(v.Lat),
+			Lon:                 coordinateToV2(v.Lon),
+			Address:             v.Address,
+			CrossStreet:         v.CrossStreet,
+			RegionID:            idToV2(v.RegionID),
+			PostCode:            v.PostCode,
+			RentalMethods:       upperStrings(v.RentalMethods),
+			IsVirtualStation:    booleanToV2(v.IsVirtualStation),
+			StationArea:         geometryToV2(v.StationArea),
+			Capacity:            v.Capacity,
+			VehicleCapacity:     vehicleTypesCapacityToV2(v.VehicleTypesCapacity),
+			VehicleTypeCapacity: vehicleTypesCapacityToV2(v.VehicleDocksCapacity),
+			IsValetStation:      booleanToV2(v.IsValetStation),
+			RentalURIs:          rentalURIsToV2(v.RentalURIs),
+		})
+	}
+	return r
+}
+
+func stationStatusToV2(f *FeedStationStatus) *gbfsv2.FeedStationStatus {
+	r := &gbfsv2.FeedStationStatus{}
+	if f.Data == nil {
+		return r
+	}
+	r.Data = &gbfsv2.FeedStationStatusData{
+		Stations: []*gbfsv2.FeedStationStatusStation{},
+	}
+	for _, v := range f.Data.Stations {
+		if v == nil {
+			continue
+		}
+		station := &gbfsv2.FeedStationStatusStation{
+			StationID:         idToV2(v.StationID),
+			NumBikesAvailable: v.NumVehiclesAvailable,
+			NumBikesDisabled:  v.NumVehiclesDisabled,
+			NumDocksAvailable: v.NumDocksAvailable,
+			NumDocksDisabled:  v.NumDocksDisabled,
+			IsInstalled:       booleanToV2(v.IsInstalled),
+			IsRenting:         booleanToV2(v.IsRenting),
+			IsReturning:       booleanToV2(v.IsReturning),
+			LastReported:      timestampPtrToV2(v.LastReported),
+		}
+		for _, t := range v.VehicleTypesAvailable {
+			if t == nil {
+				continue
+			}
+			station.VehicleTypesAvailable = append(station.VehicleTypesAvailable, &gbfsv2.FeedStationStatusVehicleType{
+				VehicleTypeID: idToV2(t.VehicleTypeID),
+				Count:         t.Count,
+			})
+		}
+		for _, d := range v.VehicleDocksAvailable {
+			if d == nil {
+				continue
+			}
+			station.VehicleDocksAvailable = append(station.VehicleDocksAvailable, &gbfsv2.FeedStationStatusVehicleDock{
+				VehicleTypeIDs: idsToV2(d.VehicleTypeIDs),
+				Count:          d.Count,
+			})
+		}
+		r.Data.Stations = append(r.Data.Stations, station)
+	}
+	return r
+}
+
+func vehicleStatusToV2(f *FeedVehicleStatus) *gbfsv2.FeedFreeBikeStatus {
+	r := &gbfsv2.FeedFreeBikeStatus{}
+	if f.Data == nil {
+		return r
+	}
+	r.Data = &gbfsv2.FeedFreeBikeStatusData{
+		Bikes: []*gbfsv2.FeedFreeBikeStatusBike{},
+	}
+	for _, v := range f.Data.Vehicles {
+		// free_bike_status of v2 model has no station_id, vehicles at
+		// stations without position are left out
+		if v == nil || v.Lat == nil || v.Lon == nil {
+			continue
+		}
+		r.Data.Bikes = append(r.Data.Bikes, &gbfsv2.FeedFreeBikeStatusBike{
+			BikeID:             idToV2(v.VehicleID),
+			Lat:                coordinateToV2(v.Lat),
+			Lon:                coordinateToV2(v.Lon),
+			IsReserved:         booleanToV2(v.IsReserved),
+			IsDisabled:         booleanToV2(v.IsDisabled),
+			RentalURIs:         rentalURIsToV2(v.RentalURIs),
+			VehicleTypeID:      idToV2(v.VehicleTypeID),
+			LastReported:       timestampPtrToV2(v.LastReported),
+			CurrentRangeMeters: v.CurrentRangeMeters,
+		})
+	}
+	return r
+}
+
+func systemRegionsToV2(f *FeedSystemRegions, language string) *gbfsv2.FeedSystemRegions {
+	r := &gbfsv2.FeedSystemRegions{}
+	if f.Data == nil {
+		return r
+	}
+	r.Data = &gbfsv2.FeedSystemRegionsData{
+		Regions: []*gbfsv2.FeedSystemRegionsRegion{},
+	}
+	for _, v := range f.Data.Regions {
+		if v == nil {
+			continue
+		}
+		r.Data.Regions = append(r.Data.Regions, &gbfsv2.FeedSystemRegionsRegion{
+			RegionID: idToV2(v.RegionID),
+			Name:     localizedText(v.Name, language),
+		})
+	}
+	return r
+}
+
+// systemPricingPlansToV2 keeps only flat price of plans, v2 model has no
+// per_km_pricing, per_min_pricing and surge_pricing.
+func systemPricingPlansToV2(f *FeedSystemPricingPlans, language string) *gbfsv2.FeedSystemPricingPlans {
+	r := &gbfsv2.FeedSystemPricingPlans{}
+	if f.Data == nil {
+		return r
+	}
+	r.Data = &gbfsv2.FeedSystemPricingPlansData{
+		Plans: []*gbfsv2.FeedSystemPricingPlansPricingPlan{},
+	}
+	for _, v := range f.Data.Plans {
+		if v == nil {
+			continue
+		}
+		plan := &gbfsv2.FeedSystemPricingPlansPricingPlan{
+			PlanID:      idToV2(v.PlanID),
+			URL:         v.URL,
+			Name:        localizedText(v.Name, language),
+			Currency:    v.Currency,
+			IsTaxable:   booleanToV2(v.IsTaxable),
+			Description: localizedText(v.Description, language),
+		}
+		if v.Price != nil {
+			plan.Price = gbfsv2.NewPrice(v.Price.Float64)
+		}
+		r.Data.Plans = append(r.Data.Plans, plan)
+	}
+	return r
+}
+
+func systemAlertsToV2(f *FeedSystemAlerts, language string) *gbfsv2.FeedSystemAlerts {
+	r := &gbfsv2.FeedSystemAlerts{}
+	if f.Data == nil {
+		return r
+	}
+	r.Data = &gbfsv2.FeedSystemAlertsData{
+		Alerts: []*gbfsv2.FeedSystemAlertsAlert{},
+	}
+	for _, v := range f.Data.Alerts {
+		if v == nil {
+			continue
+		}
+		alert := &gbfsv2.FeedSystemAlertsAlert{
+			AlertID:     idToV2(v.AlertID),
+			StationIDs:  idsToV2(v.StationIDs),
+			RegionIDs:   idsToV2(v.RegionIDs),
+			URL:         localizedText(v.URL, language),
+			Summary:     localizedText(v.Summary, language),
+			Description: localizedText(v.Description, language),
+			LastUpdated: timestampPtrToV2(v.LastUpdated),
+		}
+		if v.Type != nil {
+			alert.Type = NewString(strings.ToUpper(*v.Type))
+		}
+		for _, t := range v.Times {
+			if t == nil {
+				continue
+			}
+			alert.Times = append(alert.Times, &gbfsv2.FeedSystemAlertsAlertTime{
+				Start: timestampPtrToV2(t.Start),
+				End:   timestampPtrToV2(t.End),
+			})
+		}
+		r.Data.Alerts = append(r.Data.Alerts, alert)
+	}
+	return r
+}
+
+func geofencingZonesToV2(f *FeedGeofencingZones, language string) *gbfsv2.FeedGeofencingZones {
+	r := &gbfsv2.FeedGeofencingZones{}
+	if f.Data == nil {
+		return r
+	}
+	features := []*gbfsv2.FeedGeofencingZonesGeoJSONFeature{}
+	if f.Data.GeofencingZones != nil {
+		for _, v := range f.Data.GeofencingZones.Features {
+			if v == nil {
+				continue
+			}
+			var properties *gbfsv2.FeedGeofencingZonesGeoJSONFeatureProperties
+			if v.Properties != nil {
+				properties = &gbfsv2.FeedGeofencingZonesGeoJSONFeatureProperties{
+					Name:  localizedText(v.Properties.Name, language),
+					Start: timestampPtrToV2(v.Properties.Start),
+					End:   timestampPtrToV2(v.Properties.End),
+				}
+				for _, rule := range v.Properties.Rules {
+					if rule == nil {
+						continue
+					}
+					properties.Rules = append(properties.Rules, geofencingZonesRuleToV2(rule))
+				}
+			}
+			features = append(features, gbfsv2.NewFeedGeofencingZonesGeoJSONFeature(geometryToV2(v.Geometry), properties))
+		}
+	}
+	r.Data = &gbfsv2.FeedGeofencingZonesData{
+		GeofencingZones: gbfsv2.NewFeedGeofencingZonesGeoJSONFeatureCollection(features),
+	}
+	return r
+}
+
+// geofencingZonesRuleToV2 allows ride in v2 only when both start and end of
+// ride are allowed in v3.
+func geofencingZonesRuleToV2(v *FeedGeofencingZonesRule) *gbfsv2.FeedGeofencingZonesGeoJSONFeaturePropertiesRule {
+	rule := &gbfsv2.FeedGeofencingZonesGeoJSONFeaturePropertiesRule{
+		VehicleTypeIDs:     idsToV2(v.VehicleTypeIDs),
+		RideThroughAllowed: booleanToV2(v.RideThroughAllowed),
+		MaximumSpeedKph:    v.MaximumSpeedKph,
+	}
+	if v.RideStartAllowed != nil || v.RideEndAllowed != nil {
+		allowed := (v.RideStartAllowed == nil || bool(*v.RideStartAllowed)) && (v.RideEndAllowed == nil || bool(*v.RideEndAllowed))
+		rule.RideAllowed = gbfsv2.NewBoolean(allowed)
+	}
+	return rule
+}
@@ -81,7 +81,7 @@ func feedHandlers() []*gbfs.FeedHandler {
 								Lon:     gbfs.NewCoordinate(21.1234),
 								Address: gbfs.NewString("Ulica 123"),
 								StationArea: gbfs.NewGeoJSONGeometryMultiPolygon(
-									[][][][]float64{
+									gbfs.GeoJSONMultiPolygon{
 										{
 											{
 												{16.8331891, 47.7314286},
@@ -259,7 +259,7 @@ func feedHandlers() []*gbfs.FeedHandler {
 							[]*gbfs.FeedGeofencingZonesGeoJSONFeature{
 								gbfs.NewFeedGeofencingZonesGeoJSONFeature(
 									gbfs.NewGeoJSONGeometryMultiPolygon(
-										[][][][]float64{
+										gbfs.GeoJSONMultiPolygon{
 											{
 												{
 													{16.8331891, 47.7314286},
@@ -396,9 +396,13 @@ func ParkingTypeAll() []string {
 }
 
 type GeoJSONGeometry struct {
-	Type        string `json:"type"`
-	Coordinates any    `json:"coordinates"`
-	Properties  any    `json:"properties,omitempty"`
+	Type string `json:"type"`
+	// Coordinates stay untyped, because their shape depends on Type and
+	// decoded feeds hold them as []any. Constructors set GeoJSONPoint,
+	// GeoJSONPolygon or GeoJSONMultiPolygon, use Point, Polygon and
+	// MultiPolygon to read them typed.
+	Coordinates any `json:"coordinates"`
+	Properties  any `json:"properties,omitempty"`
 }
 
 type GeoJSONFeature struct {
@@ -427,7 +431,23 @@ func NewGeoJSONFeature(geometry *GeoJSONGeometry, properties any) *GeoJSONFeatur
 	}
 }
 
-func NewGeoJSONGeometryMultiPolygon(coordinates any, properties any) *GeoJSONGeometry {
+func NewGeoJSONGeometryPoint(coordinates GeoJSONPoint, properties any) *GeoJSONGeometry {
+	return &GeoJSONGeometry{
+		Type:        "Point",
+		Coordinates: coordinates,
+		Properties:  properties,
+	}
+}
+
+func NewGeoJSONGeometryPolygon(coordinates GeoJSONPolygon, properties any) *GeoJSONGeometry {
+	return &GeoJSONGeometry{
+		Type:        "Polygon",
+		Coordinates: coordinates,
+		Properties:  properties,
+	}
+}
+
+func NewGeoJSONGeometryMultiPolygon(coordinates GeoJSONMultiPolygon, properties any) *GeoJSONGeometry {
 	return &GeoJSONGeometry{
 		Type:        "MultiPolygon",
 		Coordinates: coordinates,
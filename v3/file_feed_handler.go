@@ -0,0 +1,235 @@
+package gbfs
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrUnknownFeedFile = errors.New("file is not named by feed")
+
+type (
+	// FileFeedHandlerOptions configure feed handlers loading feeds from
+	// files. Files are checked for changes every WatchInterval, default 1
+	// second, negative interval disables watching. Changed files are loaded
+	// and published immediately. Name is name of feed loaded from file,
+	// default file name without extension.
+	FileFeedHandlerOptions struct {
+		TTL           int
+		Path          string
+		Name          string
+		WatchInterval time.Duration
+	}
+	fileFeedHandler struct {
+		handler  *FeedHandler
+		source   string
+		files    func() []string
+		load     func() ([]Feed, error)
+		interval time.Duration
+		once     sync.Once
+		last     []Feed
+	}
+)
+
+// NewFileFeedHandler returns feed handler publishing feed from JSON file.
+// Feed document may omit last_updated, ttl and version. File with
+// extension .geojson is read as feature collection of geofencing_zones.
+func NewFileFeedHandler(path string, options FileFeedHandlerOptions) *FeedHandler {
+	name := options.Name
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	h := &fileFeedHandler{
+		source: path,
+		files: func() []string {
+			return []string{path}
+		},
+		load: func() ([]Feed, error) {
+			feed, err := LoadFeedFile(name, path)
+			if err != nil {
+				return nil, err
+			}
+			return []Feed{feed}, nil
+		},
+	}
+	return h.feedHandler(options)
+}
+
+// NewDirFeedHandler returns feed handler publishing feeds from JSON files
+// in directory named by feed names, e.g. system_information.json or
+// geofencing_zones.geojson. Other files are ignored.
+func NewDirFeedHandler(dir string, options FileFeedHandlerOptions) *FeedHandler {
+	h := &fileFeedHandler{
+		source: dir,
+		files: func() []string {
+			files, _ := feedFiles(dir)
+			return files
+		},
+		load: func() ([]Feed, error) {
+			files, err := feedFiles(dir)
+			if err != nil {
+				return nil, err
+			}
+			feeds := []Feed{}
+			names := map[string]bool{}
+			for _, file := range files {
+				name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+				if names[name] {
+					return nil, errors.New(file + ": duplicate feed " + name)
+				}
+				names[name] = true
+				feed, err := LoadFeedFile(name, file)
+				if err != nil {
+					return nil, err
+				}
+				feeds = append(feeds, feed)
+			}
+			return feeds, nil
+		},
+	}
+	return h.feedHandler(options)
+}
+
+// LoadFeedFile loads feed of given name from JSON or GeoJSON file.
+func LoadFeedFile(name, path string) (Feed, error) {
+	feed := FeedStruct(name)
+	if feed == nil {
+		return nil, NewError(path+": ", ErrUnknownFeedFile)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Ext(path) == ".geojson" {
+		f, ok := feed.(*FeedGeofencingZones)
+		if !ok {
+			return nil, errors.New(path + ": geojson file can contain only " + FeedNameGeofencingZones)
+		}
+		f.Data = &FeedGeofencingZonesData{
+			GlobalRules: []*FeedGeofencingZonesRule{},
+		}
+		err = json.Unmarshal(b, &f.Data.GeofencingZones)
+	} else {
+		err = json.Unmarshal(b, feed)
+	}
+	if err != nil {
+		return nil, NewError(path+": ", err)
+	}
+	return feed, nil
+}
+
+// feedFiles returns sorted JSON and GeoJSON files of directory named by
+// feed names.
+func feedFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".json" && ext != ".geojson") {
+			continue
+		}
+		if FeedStruct(strings.TrimSuffix(e.Name(), ext)) == nil {
+			continue
+		}
+		files = append(files, filepath.Join(dir, e.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (h *fileFeedHandler) feedHandler(options FileFeedHandlerOptions) *FeedHandler {
+	h.interval = options.WatchInterval
+	if h.interval == 0 {
+		h.interval = time.Second
+	}
+	h.handler = &FeedHandler{
+		TTL:     options.TTL,
+		Path:    options.Path,
+		Handler: h.run,
+	}
+	return h.handler
+}
+
+// run loads and validates feeds. When files can not be loaded or feeds
+// have validation errors, error is passed to UpdateHandler and previously
+// loaded feeds are published again.
+func (h *fileFeedHandler) run(s *Server) ([]Feed, error) {
+	if h.interval > 0 {
+		h.once.Do(func() {
+			go h.watch(s, h.fingerprint())
+		})
+	}
+	feeds, err := h.load()
+	if err == nil {
+		for _, feed := range feeds {
+			err = h.check(s, feed)
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		s.Options.UpdateHandler(s, nil, h.source, err)
+		return h.last, nil
+	}
+	h.last = feeds
+	return feeds, nil
+}
+
+// check validates loaded feed. Fields set by server on publication are
+// filled before validation, last_updated is not required.
+func (h *fileFeedHandler) check(s *Server, feed Feed) error {
+	if feed.GetTTL() == 0 {
+		feed.SetTTL(h.handler.TTL)
+	}
+	feed.SetVersion(s.Options.Version)
+	errs := ValidationErrors{}
+	for _, e := range s.checkFeed(feed) {
+		if e.Path != "last_updated" {
+			errs = append(errs, e)
+		}
+	}
+	if errs.HasErrors() {
+		return NewError(feed.Name()+": validation failed, previous feed kept: ", errs)
+	}
+	return nil
+}
+
+// fingerprint returns names, sizes and modification times of files.
+func (h *fileFeedHandler) fingerprint() string {
+	parts := []string{}
+	for _, file := range h.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, file+":"+strconv.FormatInt(info.Size(), 10)+":"+strconv.FormatInt(info.ModTime().UnixNano(), 10))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// watch polls files and runs feed handler when they change.
+func (h *fileFeedHandler) watch(s *Server, last string) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		current := h.fingerprint()
+		if current == last {
+			continue
+		}
+		last = current
+		err := s.refreshHandler(h.handler)
+		if errors.Is(err, ErrFeedHandlerNotRunning) {
+			return
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package gbfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const testSystemInformation = `{"data":{"system_id":"system_id","languages":["en"],"name":[{"text":"%s","language":"en"}],"opening_hours":"24/7","feed_contact_email":"a@b.c","timezone":"Europe/Bratislava"}}`
+
+func testSystemInformationFile(name string) string {
+	return fmt.Sprintf(testSystemInformation, name)
+}
+
+func TestLoadFeedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "geofencing_zones.geojson")
+	writeTestFile(t, path, `{"type":"FeatureCollection","features":[]}`)
+	feed, err := LoadFeedFile(FeedNameGeofencingZones, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := feed.(*FeedGeofencingZones); f.Data.GeofencingZones == nil || f.Data.GlobalRules == nil {
+		t.Fatal("expected geofencing zones from geojson file")
+	}
+	if _, err := LoadFeedFile(FeedNameStationStatus, path); err == nil {
+		t.Error("expected error for geojson file of other feed")
+	}
+	if _, err := LoadFeedFile("unknown", path); !errors.Is(err, ErrUnknownFeedFile) {
+		t.Errorf("expected ErrUnknownFeedFile, got %v", err)
+	}
+}
+
+func TestDirFeedHandlerKeepsLastGoodFeeds(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "system_information.json")
+	writeTestFile(t, path, testSystemInformationFile("Bikes"))
+	writeTestFile(t, filepath.Join(dir, "notes.json"), "ignored")
+	errs := []error{}
+	s, err := NewServer(ServerOptions{
+		SystemID:   "system_id",
+		Store:      NewMemoryFeedStore(),
+		BaseURL:    "http://127.0.0.1:8080",
+		DefaultTTL: 60,
+		UpdateHandler: func(s *Server, f Feed, path string, err error) {
+			errs = append(errs, err)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewDirFeedHandler(dir, FileFeedHandlerOptions{TTL: 30, WatchInterval: -1})
+	feeds, err := h.Handler(s)
+	if err != nil || len(feeds) != 1 || len(errs) != 0 {
+		t.Fatalf("expected single feed, got %v, %v, %v", feeds, err, errs)
+	}
+	if feeds[0].GetTTL() != 30 {
+		t.Errorf("expected ttl of handler, got %d", feeds[0].GetTTL())
+	}
+	for _, content := range []string{"{", `{"data":{"system_id":"system_id"}}`} {
+		writeTestFile(t, path, content)
+		last, err := h.Handler(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(last) != 1 || last[0] != feeds[0] {
+			t.Errorf("%s: expected last good feed, got %v", content, last)
+		}
+	}
+	if len(errs) != 2 || errs[0] == nil || errs[1] == nil {
+		t.Fatalf("expected errors passed to UpdateHandler, got %v", errs)
+	}
+	var validationErrs ValidationErrors
+	if !errors.As(errs[1], &validationErrs) {
+		t.Errorf("expected validation errors, got %v", errs[1])
+	}
+	writeTestFile(t, path, testSystemInformationFile("Bikes"))
+	h = NewFileFeedHandler(path, FileFeedHandlerOptions{WatchInterval: -1})
+	feeds, err = h.Handler(s)
+	if err != nil || len(feeds) != 1 {
+		t.Fatalf("expected single feed, got %v, %v", feeds, err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if last, _ := h.Handler(s); len(last) != 1 || last[0] != feeds[0] {
+		t.Errorf("expected last good feed after file was removed, got %v", last)
+	}
+	if !errors.Is(errs[2], os.ErrNotExist) {
+		t.Errorf("expected read error passed to UpdateHandler, got %v", errs[2])
+	}
+}
+
+func TestFileFeedHandlerWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "info.json")
+	writeTestFile(t, path, testSystemInformationFile("Bikes"))
+	names := make(chan string, 10)
+	s, err := NewServer(ServerOptions{
+		SystemID:   "system_id",
+		Store:      NewMemoryFeedStore(),
+		BaseURL:    "http://127.0.0.1:8080",
+		DefaultTTL: 60,
+		FeedHandlers: []*FeedHandler{
+			NewFileFeedHandler(path, FileFeedHandlerOptions{
+				Name:          FeedNameSystemInformation,
+				TTL:           3600,
+				WatchInterval: 10 * time.Millisecond,
+			}),
+		},
+		UpdateHandler: func(s *Server, f Feed, path string, err error) {
+			if info, ok := f.(*FeedSystemInformation); ok && err == nil {
+				names <- info.Data.Name[0].Text
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	for _, name := range []string{"Bikes", "Bicycles"} {
+		select {
+		case published := <-names:
+			if published != name {
+				t.Fatalf("expected %s to be published, got %s", name, published)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s was not published", name)
+		}
+		writeTestFile(t, path, testSystemInformationFile("Bicycles"))
+	}
+}
@@ -0,0 +1,91 @@
+package gbfs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	all := func(extension string) bool {
+		return true
+	}
+	tests := []struct {
+		header   string
+		exists   func(extension string) bool
+		expected string
+	}{
+		{"", all, ""},
+		{"gzip", all, "gzip"},
+		{"gzip, br", all, "br"},
+		{"gzip;q=1, br;q=0.5", all, "gzip"},
+		{"br;q=0, gzip", all, "gzip"},
+		{"*", all, "br"},
+		{"*, br;q=0", all, "zstd"},
+		{"identity", all, ""},
+		{"GZIP, br", func(extension string) bool { return extension == ".gz" }, "gzip"},
+		{"br", func(extension string) bool { return false }, ""},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/gbfs.json", nil)
+		if test.header != "" {
+			r.Header.Set("Accept-Encoding", test.header)
+		}
+		encoding, _ := negotiateEncoding(r, test.exists)
+		if encoding != test.expected {
+			t.Errorf("%q: expected encoding %q, got %q", test.header, test.expected, encoding)
+		}
+	}
+}
+
+func testServeEncoded(t *testing.T, h http.Handler, acceptEncoding, expectedEncoding, expectedBody string) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/v3/gbfs.json", nil)
+	r.Header.Set("Accept-Encoding", acceptEncoding)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("%q: expected status 200, got %d", acceptEncoding, w.Code)
+	}
+	if encoding := w.Header().Get("Content-Encoding"); encoding != expectedEncoding {
+		t.Errorf("%q: expected Content-Encoding %q, got %q", acceptEncoding, expectedEncoding, encoding)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("%q: expected json Content-Type, got %q", acceptEncoding, contentType)
+	}
+	if vary := w.Header().Get("Vary"); vary != "Accept-Encoding" {
+		t.Errorf("%q: expected Vary header, got %q", acceptEncoding, vary)
+	}
+	if w.Body.String() != expectedBody {
+		t.Errorf("%q: expected body %q, got %q", acceptEncoding, expectedBody, w.Body.String())
+	}
+}
+
+func TestFileHandlerServesPrecompressedSiblings(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"gbfs.json":    "plain",
+		"gbfs.json.gz": "gzip",
+		"gbfs.json.br": "br",
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "v3"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	store := NewMemoryFeedStore()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, "v3", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Put("v3/"+name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, h := range []http.Handler{NewFileHandler(dir), store} {
+		testServeEncoded(t, h, "", "", "plain")
+		testServeEncoded(t, h, "gzip", "gzip", "gzip")
+		testServeEncoded(t, h, "gzip, br", "br", "br")
+		testServeEncoded(t, h, "zstd", "", "plain")
+	}
+}
@@ -0,0 +1,624 @@
+package gbfs
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownStation = errors.New("unknown station")
+	ErrUnknownVehicle = errors.New("unknown vehicle")
+	ErrMissingEventID = errors.New("missing event id")
+)
+
+type (
+	// FleetState keeps state of stations and vehicles built from fleet
+	// events and produces station_status and vehicle_status feeds. It is safe
+	// for concurrent use.
+	FleetState struct {
+		mu       sync.RWMutex
+		stations map[ID]*fleetStation
+		vehicles map[ID]*fleetVehicle
+	}
+	fleetStation struct {
+		capacity             *int64
+		vehicleTypesCapacity []*VehicleTypesCapacity
+		vehicleDocksCapacity []*VehicleTypesCapacity
+		numDocksDisabled     int64
+		isInstalled          bool
+		isRenting            bool
+		isReturning          bool
+		lastReported         time.Time
+	}
+	fleetVehicle struct {
+		vehicleTypeID      ID
+		stationID          ID
+		homeStationID      ID
+		pricingPlanID      ID
+		lat                *float64
+		lon                *float64
+		isReserved         bool
+		isDisabled         bool
+		inRental           bool
+		currentFuelPercent *float64
+		currentRangeMeters *float64
+		rentalURIs         *RentalURIs
+		lastReported       time.Time
+	}
+	// FleetEvent changes state of stations or vehicles.
+	FleetEvent interface {
+		applyTo(s *FleetState) error
+	}
+)
+
+type (
+	// EventStationUpdated adds station or updates it. Nil fields keep
+	// current values, new station is installed, renting and returning.
+	EventStationUpdated struct {
+		StationID            ID
+		Capacity             *int64
+		VehicleTypesCapacity []*VehicleTypesCapacity
+		VehicleDocksCapacity []*VehicleTypesCapacity
+		NumDocksDisabled     *int64
+		IsInstalled          *bool
+		IsRenting            *bool
+		IsReturning          *bool
+		Time                 time.Time
+	}
+	// EventStationRemoved removes station, vehicles docked at station are
+	// left without position.
+	EventStationRemoved struct {
+		StationID ID
+	}
+	// EventVehicleAdded adds vehicle or replaces its static attributes.
+	// Vehicle is placed at station, when StationID is set, otherwise at
+	// given position.
+	EventVehicleAdded struct {
+		VehicleID     ID
+		VehicleTypeID ID
+		HomeStationID ID
+		PricingPlanID ID
+		RentalURIs    *RentalURIs
+		StationID     ID
+		Lat           *float64
+		Lon           *float64
+		Time          time.Time
+	}
+	// EventVehicleRemoved removes vehicle from fleet.
+	EventVehicleRemoved struct {
+		VehicleID ID
+		Time      time.Time
+	}
+	// EventRentalStarted takes vehicle out of station or street, vehicles
+	// in rental are not published.
+	EventRentalStarted struct {
+		VehicleID ID
+		Time      time.Time
+	}
+	// EventRentalEnded returns vehicle to station, when StationID is set,
+	// otherwise to given position.
+	EventRentalEnded struct {
+		VehicleID ID
+		StationID ID
+		Lat       *float64
+		Lon       *float64
+		Time      time.Time
+	}
+	// EventVehicleDocked places vehicle at station outside of rental, e.g.
+	// during rebalancing.
+	EventVehicleDocked struct {
+		VehicleID ID
+		StationID ID
+		Time      time.Time
+	}
+	// EventVehicleUndocked takes vehicle out of station outside of rental.
+	EventVehicleUndocked struct {
+		VehicleID ID
+		Lat       *float64
+		Lon       *float64
+		Time      time.Time
+	}
+	// EventVehicleMoved reports position of vehicle, which is not docked.
+	EventVehicleMoved struct {
+		VehicleID ID
+		Lat       float64
+		Lon       float64
+		Time      time.Time
+	}
+	// EventBatteryReported reports charge and remaining range of vehicle.
+	// Nil fields keep current values.
+	EventBatteryReported struct {
+		VehicleID          ID
+		CurrentFuelPercent *float64
+		CurrentRangeMeters *float64
+		Time               time.Time
+	}
+	// EventVehicleDisabled marks vehicle as disabled or enabled.
+	EventVehicleDisabled struct {
+		VehicleID  ID
+		IsDisabled bool
+		Time       time.Time
+	}
+	// EventVehicleReserved marks vehicle as reserved or not reserved.
+	EventVehicleReserved struct {
+		VehicleID  ID
+		IsReserved bool
+		Time       time.Time
+	}
+)
+
+func NewFleetState() *FleetState {
+	return &FleetState{
+		stations: make(map[ID]*fleetStation),
+		vehicles: make(map[ID]*fleetVehicle),
+	}
+}
+
+// Apply applies events in order. Processing stops at first event, which
+// cannot be applied, events applied before are kept.
+func (s *FleetState) Apply(events ...FleetEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range events {
+		err := e.applyTo(s)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadStationInformation adds stations of station_information feed, which
+// are not known yet, and updates capacities of known stations.
+func (s *FleetState) LoadStationInformation(feed *FeedStationInformation) error {
+	if feed == nil || feed.Data == nil {
+		return nil
+	}
+	events := []FleetEvent{}
+	for _, v := range feed.Data.Stations {
+		if v == nil || v.StationID == nil {
+			continue
+		}
+		events = append(events, &EventStationUpdated{
+			StationID:            *v.StationID,
+			Capacity:             v.Capacity,
+			VehicleTypesCapacity: v.VehicleTypesCapacity,
+			VehicleDocksCapacity: v.VehicleDocksCapacity,
+		})
+	}
+	return s.Apply(events...)
+}
+
+func eventTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now()
+	}
+	return t
+}
+
+// latest returns later of last reported time and time of event, so that
+// events delivered out of order do not move last reported time backwards.
+func latest(lastReported, t time.Time) time.Time {
+	if t.After(lastReported) {
+		return t
+	}
+	return lastReported
+}
+
+func (s *FleetState) station(id ID) (*fleetStation, error) {
+	if id == "" {
+		return nil, ErrMissingEventID
+	}
+	station, ok := s.stations[id]
+	if !ok {
+		return nil, NewError(string(id)+": ", ErrUnknownStation)
+	}
+	return station, nil
+}
+
+func (s *FleetState) vehicle(id ID) (*fleetVehicle, error) {
+	if id == "" {
+		return nil, ErrMissingEventID
+	}
+	vehicle, ok := s.vehicles[id]
+	if !ok {
+		return nil, NewError(string(id)+": ", ErrUnknownVehicle)
+	}
+	return vehicle, nil
+}
+
+// touchStation updates last reported time of station vehicle was taken
+// from or placed to.
+func (s *FleetState) touchStation(id ID, t time.Time) {
+	if station, ok := s.stations[id]; ok {
+		station.lastReported = latest(station.lastReported, t)
+	}
+}
+
+// place puts vehicle at station or at position.
+func (s *FleetState) place(vehicle *fleetVehicle, stationID ID, lat, lon *float64, t time.Time) error {
+	if stationID != "" {
+		if _, err := s.station(stationID); err != nil {
+			return err
+		}
+	}
+	s.touchStation(vehicle.stationID, t)
+	vehicle.stationID = stationID
+	vehicle.lat = nil
+	vehicle.lon = nil
+	if stationID == "" && lat != nil && lon != nil {
+		vehicle.lat = NewFloat64(*lat)
+		vehicle.lon = NewFloat64(*lon)
+	}
+	s.touchStation(stationID, t)
+	vehicle.lastReported = latest(vehicle.lastReported, t)
+	return nil
+}
+
+func (e *EventStationUpdated) applyTo(s *FleetState) error {
+	if e.StationID == "" {
+		return ErrMissingEventID
+	}
+	station, ok := s.stations[e.StationID]
+	if !ok {
+		station = &fleetStation{
+			isInstalled: true,
+			isRenting:   true,
+			isReturning: true,
+		}
+		s.stations[e.StationID] = station
+	}
+	if e.Capacity != nil {
+		station.capacity = NewInt64(*e.Capacity)
+	}
+	if e.VehicleTypesCapacity != nil {
+		station.vehicleTypesCapacity = e.VehicleTypesCapacity
+	}
+	if e.VehicleDocksCapacity != nil {
+		station.vehicleDocksCapacity = e.VehicleDocksCapacity
+	}
+	if e.NumDocksDisabled != nil {
+		station.numDocksDisabled = *e.NumDocksDisabled
+	}
+	if e.IsInstalled != nil {
+		station.isInstalled = *e.IsInstalled
+	}
+	if e.IsRenting != nil {
+		station.isRenting = *e.IsRenting
+	}
+	if e.IsReturning != nil {
+		station.isReturning = *e.IsReturning
+	}
+	station.lastReported = latest(station.lastReported, eventTime(e.Time))
+	return nil
+}
+
+func (e *EventStationRemoved) applyTo(s *FleetState) error {
+	if _, err := s.station(e.StationID); err != nil {
+		return err
+	}
+	delete(s.stations, e.StationID)
+	for _, v := range s.vehicles {
+		if v.stationID == e.StationID {
+			v.stationID = ""
+		}
+	}
+	return nil
+}
+
+func (e *EventVehicleAdded) applyTo(s *FleetState) error {
+	if e.VehicleID == "" {
+		return ErrMissingEventID
+	}
+	if e.StationID != "" {
+		if _, err := s.station(e.StationID); err != nil {
+			return err
+		}
+	}
+	vehicle, ok := s.vehicles[e.VehicleID]
+	if !ok {
+		vehicle = &fleetVehicle{}
+	}
+	vehicle.vehicleTypeID = e.VehicleTypeID
+	vehicle.homeStationID = e.HomeStationID
+	vehicle.pricingPlanID = e.PricingPlanID
+	vehicle.rentalURIs = e.RentalURIs
+	vehicle.inRental = false
+	err := s.place(vehicle, e.StationID, e.Lat, e.Lon, eventTime(e.Time))
+	if err != nil {
+		return err
+	}
+	s.vehicles[e.VehicleID] = vehicle
+	return nil
+}
+
+func (e *EventVehicleRemoved) applyTo(s *FleetState) error {
+	vehicle, err := s.vehicle(e.VehicleID)
+	if err != nil {
+		return err
+	}
+	s.touchStation(vehicle.stationID, eventTime(e.Time))
+	delete(s.vehicles, e.VehicleID)
+	return nil
+}
+
+func (e *EventRentalStarted) applyTo(s *FleetState) error {
+	vehicle, err := s.vehicle(e.VehicleID)
+	if err != nil {
+		return err
+	}
+	err = s.place(vehicle, "", nil, nil, eventTime(e.Time))
+	if err != nil {
+		return err
+	}
+	vehicle.inRental = true
+	vehicle.isReserved = false
+	return nil
+}
+
+func (e *EventRentalEnded) applyTo(s *FleetState) error {
+	vehicle, err := s.vehicle(e.VehicleID)
+	if err != nil {
+		return err
+	}
+	err = s.place(vehicle, e.StationID, e.Lat, e.Lon, eventTime(e.Time))
+	if err != nil {
+		return err
+	}
+	vehicle.inRental = false
+	return nil
+}
+
+func (e *EventVehicleDocked) applyTo(s *FleetState) error {
+	vehicle, err := s.vehicle(e.VehicleID)
+	if err != nil {
+		return err
+	}
+	if e.StationID == "" {
+		return ErrMissingEventID
+	}
+	return s.place(vehicle, e.StationID, nil, nil, eventTime(e.Time))
+}
+
+func (e *EventVehicleUndocked) applyTo(s *FleetState) error {
+	vehicle, err := s.vehicle(e.VehicleID)
+	if err != nil {
+		return err
+	}
+	return s.place(vehicle, "", e.Lat, e.Lon, eventTime(e.Time))
+}
+
+func (e *EventVehicleMoved) applyTo(s *FleetState) error {
+	vehicle, err := s.vehicle(e.VehicleID)
+	if err != nil {
+		return err
+	}
+	if vehicle.stationID != "" {
+		return nil
+	}
+	vehicle.lat = NewFloat64(e.Lat)
+	vehicle.lon = NewFloat64(e.Lon)
+	vehicle.lastReported = latest(vehicle.lastReported, eventTime(e.Time))
+	return nil
+}
+
+func (e *EventBatteryReported) applyTo(s *FleetState) error {
+	vehicle, err := s.vehicle(e.VehicleID)
+	if err != nil {
+		return err
+	}
+	if e.CurrentFuelPercent != nil {
+		vehicle.currentFuelPercent = NewFloat64(*e.CurrentFuelPercent)
+	}
+	if e.CurrentRangeMeters != nil {
+		vehicle.currentRangeMeters = NewFloat64(*e.CurrentRangeMeters)
+	}
+	vehicle.lastReported = latest(vehicle.lastReported, eventTime(e.Time))
+	return nil
+}
+
+func (e *EventVehicleDisabled) applyTo(s *FleetState) error {
+	vehicle, err := s.vehicle(e.VehicleID)
+	if err != nil {
+		return err
+	}
+	vehicle.isDisabled = e.IsDisabled
+	vehicle.lastReported = latest(vehicle.lastReported, eventTime(e.Time))
+	s.touchStation(vehicle.stationID, vehicle.lastReported)
+	return nil
+}
+
+func (e *EventVehicleReserved) applyTo(s *FleetState) error {
+	vehicle, err := s.vehicle(e.VehicleID)
+	if err != nil {
+		return err
+	}
+	vehicle.isReserved = e.IsReserved
+	vehicle.lastReported = latest(vehicle.lastReported, eventTime(e.Time))
+	s.touchStation(vehicle.stationID, vehicle.lastReported)
+	return nil
+}
+
+func sortedIDs[T any](m map[ID]T) []ID {
+	ids := make([]ID, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+func formatTimestamp(t time.Time) *Timestamp {
+	return NewTimestamp(t.Format(time.RFC3339))
+}
+
+// StationStatusFeed returns station_status feed of current state. Docked
+// vehicles, which are neither disabled nor reserved, are available. Docks
+// are counted only for stations with known capacity, each docked vehicle
+// occupies dock of first vehicle_docks_capacity group accepting its type.
+// Disabled docks are not available, available docks of group do not exceed
+// available docks of station.
+func (s *FleetState) StationStatusFeed() *FeedStationStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	docked := map[ID][]*fleetVehicle{}
+	for _, id := range sortedIDs(s.vehicles) {
+		v := s.vehicles[id]
+		if v.stationID != "" && !v.inRental {
+			docked[v.stationID] = append(docked[v.stationID], v)
+		}
+	}
+	stations := []*FeedStationStatusStation{}
+	for _, id := range sortedIDs(s.stations) {
+		station := s.stations[id]
+		var available, disabled int64
+		types := map[ID]int64{}
+		typeIDs := []ID{}
+		addType := func(id ID) {
+			if _, ok := types[id]; !ok && id != "" {
+				types[id] = 0
+				typeIDs = append(typeIDs, id)
+			}
+		}
+		for _, c := range station.vehicleTypesCapacity {
+			for _, t := range c.VehicleTypeIDs {
+				if t != nil {
+					addType(*t)
+				}
+			}
+		}
+		for _, v := range docked[id] {
+			addType(v.vehicleTypeID)
+			switch {
+			case v.isDisabled:
+				disabled++
+			case !v.isReserved:
+				available++
+				if v.vehicleTypeID != "" {
+					types[v.vehicleTypeID]++
+				}
+			}
+		}
+		status := &FeedStationStatusStation{
+			StationID:            NewID(string(id)),
+			NumVehiclesAvailable: NewInt64(available),
+			NumVehiclesDisabled:  NewInt64(disabled),
+			IsInstalled:          NewBoolean(station.isInstalled),
+			IsRenting:            NewBoolean(station.isRenting),
+			IsReturning:          NewBoolean(station.isReturning),
+			LastReported:         formatTimestamp(eventTime(station.lastReported)),
+		}
+		sort.Slice(typeIDs, func(i, j int) bool {
+			return typeIDs[i] < typeIDs[j]
+		})
+		for _, t := range typeIDs {
+			status.VehicleTypesAvailable = append(status.VehicleTypesAvailable, NewVehicleTypeCapacity(string(t), types[t]))
+		}
+		if station.capacity != nil {
+			docksAvailable := max(*station.capacity-int64(len(docked[id]))-station.numDocksDisabled, 0)
+			status.NumDocksAvailable = NewInt64(docksAvailable)
+			status.NumDocksDisabled = NewInt64(station.numDocksDisabled)
+			status.VehicleDocksAvailable = vehicleDocksAvailable(station.vehicleDocksCapacity, docked[id], docksAvailable)
+		}
+		stations = append(stations, status)
+	}
+	return &FeedStationStatus{
+		Data: &FeedStationStatusData{
+			Stations: stations,
+		},
+	}
+}
+
+func vehicleDocksAvailable(capacity []*VehicleTypesCapacity, docked []*fleetVehicle, docksAvailable int64) []*VehicleTypesCapacity {
+	if len(capacity) == 0 {
+		return nil
+	}
+	counts := make([]int64, len(capacity))
+	for i, c := range capacity {
+		if c.Count != nil {
+			counts[i] = *c.Count
+		}
+	}
+	for _, v := range docked {
+	groups:
+		for i, c := range capacity {
+			for _, t := range c.VehicleTypeIDs {
+				if t != nil && *t == v.vehicleTypeID && counts[i] > 0 {
+					counts[i]--
+					break groups
+				}
+			}
+		}
+	}
+	docks := []*VehicleTypesCapacity{}
+	for i, c := range capacity {
+		docks = append(docks, &VehicleTypesCapacity{
+			VehicleTypeIDs: c.VehicleTypeIDs,
+			Count:          NewInt64(min(counts[i], docksAvailable)),
+		})
+	}
+	return docks
+}
+
+// VehicleStatusFeed returns vehicle_status feed of current state. Vehicles
+// in rental and vehicles without station and position are not listed.
+func (s *FleetState) VehicleStatusFeed() *FeedVehicleStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	vehicles := []*FeedVehicleStatusVehicle{}
+	for _, id := range sortedIDs(s.vehicles) {
+		v := s.vehicles[id]
+		if v.inRental || (v.stationID == "" && v.lat == nil) {
+			continue
+		}
+		vehicle := &FeedVehicleStatusVehicle{
+			VehicleID:          NewID(string(id)),
+			IsReserved:         NewBoolean(v.isReserved),
+			IsDisabled:         NewBoolean(v.isDisabled),
+			RentalURIs:         v.rentalURIs,
+			LastReported:       formatTimestamp(eventTime(v.lastReported)),
+			CurrentRangeMeters: v.currentRangeMeters,
+			CurrentFuelPercent: v.currentFuelPercent,
+		}
+		if v.lat != nil {
+			vehicle.Lat = NewCoordinate(*v.lat)
+			vehicle.Lon = NewCoordinate(*v.lon)
+		}
+		if v.vehicleTypeID != "" {
+			vehicle.VehicleTypeID = NewID(string(v.vehicleTypeID))
+		}
+		if v.stationID != "" {
+			vehicle.StationID = NewID(string(v.stationID))
+		}
+		if v.homeStationID != "" {
+			vehicle.HomeStationID = NewID(string(v.homeStationID))
+		}
+		if v.pricingPlanID != "" {
+			vehicle.PricingPlanID = NewID(string(v.pricingPlanID))
+		}
+		vehicles = append(vehicles, vehicle)
+	}
+	return &FeedVehicleStatus{
+		Data: &FeedVehicleStatusData{
+			Vehicles: vehicles,
+		},
+	}
+}
+
+// FeedHandler returns handler publishing station_status and vehicle_status
+// feeds of current state.
+func (s *FleetState) FeedHandler(ttl int) *FeedHandler {
+	return &FeedHandler{
+		TTL: ttl,
+		Handler: func(*Server) ([]Feed, error) {
+			return []Feed{
+				s.StationStatusFeed(),
+				s.VehicleStatusFeed(),
+			}, nil
+		},
+	}
+}
@@ -0,0 +1,70 @@
+package gbfs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFleetStateVehicleAddedUnknownStation(t *testing.T) {
+	s := NewFleetState()
+	lat, lon := 48.1, 17.1
+	err := s.Apply(&EventVehicleAdded{VehicleID: "v1", VehicleTypeID: "bike", Lat: &lat, Lon: &lon})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Apply(&EventVehicleAdded{VehicleID: "v1", VehicleTypeID: "scooter", StationID: "missing"})
+	if !errors.Is(err, ErrUnknownStation) {
+		t.Fatalf("expected ErrUnknownStation, got %v", err)
+	}
+	vehicles := s.VehicleStatusFeed().Data.Vehicles
+	if len(vehicles) != 1 || *vehicles[0].VehicleTypeID != "bike" || vehicles[0].Lat.Float64 != 48.1 {
+		t.Fatal("failed event changed existing vehicle")
+	}
+}
+
+func TestFleetStateDocksDisabled(t *testing.T) {
+	s := NewFleetState()
+	err := s.Apply(
+		&EventStationUpdated{
+			StationID:        "s1",
+			Capacity:         NewInt64(4),
+			NumDocksDisabled: NewInt64(2),
+			VehicleDocksCapacity: []*VehicleTypesCapacity{
+				{VehicleTypeIDs: []*ID{NewID("bike")}, Count: NewInt64(4)},
+			},
+		},
+		&EventVehicleAdded{VehicleID: "v1", VehicleTypeID: "bike", StationID: "s1"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	station := s.StationStatusFeed().Data.Stations[0]
+	if *station.NumDocksAvailable != 1 {
+		t.Fatalf("expected 1 dock available, got %d", *station.NumDocksAvailable)
+	}
+	if c := *station.VehicleDocksAvailable[0].Count; c != 1 {
+		t.Fatalf("expected 1 dock of group available, got %d", c)
+	}
+}
+
+func TestFleetStateLastReportedMonotonic(t *testing.T) {
+	s := NewFleetState()
+	t1 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	err := s.Apply(
+		&EventStationUpdated{StationID: "s1", Time: t1},
+		&EventVehicleAdded{VehicleID: "v1", StationID: "s1", Time: t1},
+		&EventBatteryReported{VehicleID: "v1", CurrentFuelPercent: NewFloat64(0.5), Time: t1.Add(-time.Hour)},
+		&EventStationUpdated{StationID: "s1", IsRenting: new(bool), Time: t1.Add(-time.Hour)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := t1.Format(time.RFC3339)
+	if lr := *s.VehicleStatusFeed().Data.Vehicles[0].LastReported; string(lr) != expected {
+		t.Fatalf("expected vehicle last_reported %s, got %s", expected, lr)
+	}
+	if lr := *s.StationStatusFeed().Data.Stations[0].LastReported; string(lr) != expected {
+		t.Fatalf("expected station last_reported %s, got %s", expected, lr)
+	}
+}
@@ -0,0 +1,159 @@
+package gbfs
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+var ErrGeofencingBudgetExceeded = errors.New("geofencing zones exceed size budget")
+
+type (
+	// GeofencingZoneSize is serialized size of zone of geofencing_zones feed.
+	GeofencingZoneSize struct {
+		Index     int    `json:"index"`
+		Name      string `json:"name,omitempty"`
+		Bytes     int    `json:"bytes"`
+		Positions int    `json:"positions"`
+	}
+	// GeofencingBudget reduces geofencing_zones feed, which exceeds MaxBytes
+	// when serialized. Coordinates are rounded to Precision decimal places
+	// first (default 6), then geometries are simplified by Method (default
+	// SimplifyDouglasPeucker) with tolerance doubled from Tolerance (default
+	// 1 meter) up to MaxTolerance (default 100 meters), until feed fits.
+	GeofencingBudget struct {
+		MaxBytes     int
+		Precision    int
+		Method       string
+		Tolerance    float64
+		MaxTolerance float64
+	}
+)
+
+// GeofencingZoneSizes returns serialized size and number of positions of
+// every zone of feed.
+func GeofencingZoneSizes(feed *FeedGeofencingZones) []*GeofencingZoneSize {
+	sizes := []*GeofencingZoneSize{}
+	if feed == nil || feed.Data == nil || feed.Data.GeofencingZones == nil {
+		return sizes
+	}
+	for i, zone := range feed.Data.GeofencingZones.Features {
+		size := &GeofencingZoneSize{
+			Index: i,
+		}
+		if zone.Properties != nil && len(zone.Properties.Name) > 0 {
+			size.Name = zone.Properties.Name[0].Text
+		}
+		b, err := json.Marshal(zone)
+		if err == nil {
+			size.Bytes = len(b)
+		}
+		polygons, _ := zone.Geometry.MultiPolygon()
+		for _, polygon := range polygons {
+			for _, ring := range polygon {
+				size.Positions += len(ring)
+			}
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes
+}
+
+// ApplyFeed applies budget to geofencing_zones feed, other feeds are
+// returned unchanged.
+func (b *GeofencingBudget) ApplyFeed(feed Feed) (Feed, error) {
+	f, ok := feed.(*FeedGeofencingZones)
+	if !ok || f == nil {
+		return feed, nil
+	}
+	return b.Apply(f)
+}
+
+// Apply returns feed, when it fits into budget, or its reduced copy.
+// When feed does not fit even after simplification with MaxTolerance,
+// smallest copy is returned with error wrapping
+// ErrGeofencingBudgetExceeded.
+func (b *GeofencingBudget) Apply(feed *FeedGeofencingZones) (*FeedGeofencingZones, error) {
+	size, err := feedSize(feed)
+	if err != nil || b.MaxBytes <= 0 || size <= b.MaxBytes {
+		return feed, err
+	}
+	precision := b.Precision
+	if precision <= 0 {
+		precision = 6
+	}
+	tolerance := b.Tolerance
+	if tolerance <= 0 {
+		tolerance = 1
+	}
+	maxTolerance := b.MaxTolerance
+	if maxTolerance <= 0 {
+		maxTolerance = 100
+	}
+	rounded, err := copyGeofencingZones(feed)
+	if err != nil {
+		return feed, err
+	}
+	mapGeofencingGeometries(rounded, func(p GeoJSONMultiPolygon) GeoJSONMultiPolygon {
+		return p.Round(precision)
+	})
+	reduced := rounded
+	for t := tolerance; ; t *= 2 {
+		size, err = feedSize(reduced)
+		if err != nil || size <= b.MaxBytes || t > maxTolerance {
+			break
+		}
+		reduced, err = copyGeofencingZones(rounded)
+		if err != nil {
+			return feed, err
+		}
+		mapGeofencingGeometries(reduced, func(p GeoJSONMultiPolygon) GeoJSONMultiPolygon {
+			return p.Simplify(b.Method, t)
+		})
+	}
+	if err != nil {
+		return feed, err
+	}
+	if size > b.MaxBytes {
+		return reduced, NewError(strconv.Itoa(size)+" bytes after simplification, budget "+strconv.Itoa(b.MaxBytes)+" bytes: ", ErrGeofencingBudgetExceeded)
+	}
+	return reduced, nil
+}
+
+func feedSize(feed any) (int, error) {
+	b, err := json.Marshal(feed)
+	return len(b), err
+}
+
+func copyGeofencingZones(feed *FeedGeofencingZones) (*FeedGeofencingZones, error) {
+	b, err := json.Marshal(feed)
+	if err != nil {
+		return nil, err
+	}
+	c := &FeedGeofencingZones{}
+	err = json.Unmarshal(b, c)
+	return c, err
+}
+
+// mapGeofencingGeometries replaces Polygon and MultiPolygon geometries of
+// zones, type of geometry is kept.
+func mapGeofencingGeometries(feed *FeedGeofencingZones, fn func(GeoJSONMultiPolygon) GeoJSONMultiPolygon) {
+	if feed.Data == nil || feed.Data.GeofencingZones == nil {
+		return
+	}
+	for _, zone := range feed.Data.GeofencingZones.Features {
+		if zone.Geometry == nil {
+			continue
+		}
+		p, err := zone.Geometry.MultiPolygon()
+		if err != nil {
+			continue
+		}
+		switch zone.Geometry.Type {
+		case "Polygon":
+			zone.Geometry.Coordinates = fn(p)[0]
+		case "MultiPolygon":
+			zone.Geometry.Coordinates = fn(p)
+		}
+	}
+}
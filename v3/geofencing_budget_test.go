@@ -0,0 +1,133 @@
+package gbfs
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func testGeofencingZones() *FeedGeofencingZones {
+	feed := &FeedGeofencingZones{}
+	feed.SetLastUpdated(*NewTimestamp("2024-01-01T00:00:00Z"))
+	feed.SetTTL(60)
+	feed.SetVersion(V30)
+	feed.Data = &FeedGeofencingZonesData{
+		GeofencingZones: NewFeedGeofencingZonesGeoJSONFeatureCollection([]*FeedGeofencingZonesGeoJSONFeature{
+			NewFeedGeofencingZonesGeoJSONFeature(
+				NewGeoJSONGeometryPolygon(GeoJSONPolygon{
+					testRing(17.1, 48.1, 2000, 2, 1000, false),
+					testRing(17.1, 48.1, 1990, 2, 1000, true),
+				}, nil),
+				&FeedGeofencingZonesGeoJSONFeatureProperties{Name: []*LocalizedString{NewLocalizedString("Ring", "en")}},
+			),
+			NewFeedGeofencingZonesGeoJSONFeature(
+				NewGeoJSONGeometryMultiPolygon(GeoJSONMultiPolygon{
+					{testRing(17.2, 48.2, 500, 3, 500, false)},
+					{testRing(17.3, 48.2, 500, 3, 500, false)},
+				}, nil),
+				&FeedGeofencingZonesGeoJSONFeatureProperties{Name: []*LocalizedString{NewLocalizedString("Parks", "en")}},
+			),
+		}),
+	}
+	return feed
+}
+
+func TestGeofencingBudget(t *testing.T) {
+	feed := testGeofencingZones()
+	original, err := feedSize(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions := 0
+	for _, size := range GeofencingZoneSizes(feed) {
+		positions += size.Positions
+	}
+	if positions != 2*1001+2*501 {
+		t.Fatalf("expected %d positions, got %d", 2*1001+2*501, positions)
+	}
+	for _, method := range []string{SimplifyDouglasPeucker, SimplifyVisvalingam} {
+		budget := &GeofencingBudget{MaxBytes: original / 10, Method: method}
+		reduced, err := budget.Apply(feed)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		size, err := feedSize(reduced)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size > budget.MaxBytes {
+			t.Errorf("%s: expected at most %d bytes, got %d", method, budget.MaxBytes, size)
+		}
+		for i, zone := range reduced.Data.GeofencingZones.Features {
+			expected := feed.Data.GeofencingZones.Features[i].Geometry.Type
+			if zone.Geometry.Type != expected {
+				t.Errorf("%s: expected %s, got %s", method, expected, zone.Geometry.Type)
+			}
+			if err := zone.Geometry.Validate(); err != nil {
+				t.Errorf("%s: zone %d: %v", method, i, err)
+			}
+			polygons, err := zone.Geometry.MultiPolygon()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, polygon := range polygons {
+				for j := 1; j < len(polygon); j++ {
+					if ringsCross(polygon[0], polygon[j]) {
+						t.Errorf("%s: zone %d: hole %d crosses exterior ring", method, i, j)
+					}
+				}
+			}
+		}
+	}
+	if size, _ := feedSize(feed); size != original {
+		t.Error("expected original feed to be unchanged")
+	}
+}
+
+func TestGeofencingBudgetFits(t *testing.T) {
+	feed := testGeofencingZones()
+	size, err := feedSize(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, budget := range []*GeofencingBudget{{}, {MaxBytes: size}} {
+		reduced, err := budget.Apply(feed)
+		if err != nil || reduced != feed {
+			t.Errorf("expected feed within budget to be returned unchanged, got %v", err)
+		}
+	}
+	f, err := (&GeofencingBudget{MaxBytes: 1}).ApplyFeed(&FeedSystemAlerts{})
+	if err != nil || f == nil {
+		t.Errorf("expected other feeds to be returned unchanged, got %v", err)
+	}
+}
+
+func TestGeofencingBudgetExceeded(t *testing.T) {
+	feed := testGeofencingZones()
+	original, err := feedSize(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	budget := &GeofencingBudget{MaxBytes: 1000}
+	reduced, err := budget.Apply(feed)
+	if !errors.Is(err, ErrGeofencingBudgetExceeded) {
+		t.Fatalf("expected ErrGeofencingBudgetExceeded, got %v", err)
+	}
+	size, _ := feedSize(reduced)
+	if size >= original {
+		t.Errorf("expected reduced feed smaller than %d bytes, got %d", original, size)
+	}
+	b, err := json.Marshal(reduced)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &FeedGeofencingZones{}
+	if err := json.Unmarshal(b, decoded); err != nil {
+		t.Fatal(err)
+	}
+	for i, zone := range decoded.Data.GeofencingZones.Features {
+		if err := zone.Geometry.Validate(); err != nil {
+			t.Errorf("zone %d: %v", i, err)
+		}
+	}
+}
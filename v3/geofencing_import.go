@@ -0,0 +1,374 @@
+package gbfs
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnsupportedImportFormat = errors.New("unsupported import format")
+	ErrInvalidGeofencingRule   = errors.New("invalid geofencing rule")
+)
+
+type (
+	// GeofencingImportOptions configure import of geofencing zones from
+	// plain GeoJSON and KML. NameProperties map languages to properties
+	// with name of zone, StartProperty and EndProperty are properties with
+	// RFC3339 time or date, when zone is in effect.
+	GeofencingImportOptions struct {
+		NameProperties map[string]string
+		StartProperty  string
+		EndProperty    string
+		// Rules are added to zones matched by rule mapping, in order.
+		Rules []*GeofencingRuleMapping
+		// GlobalRules apply outside of all zones.
+		GlobalRules []*FeedGeofencingZonesRule
+	}
+	// GeofencingRuleMapping adds copy of Rule to zones with all properties
+	// of Match equal, empty Match matches all zones. Properties map fields
+	// of rule by their JSON names (ride_start_allowed, ride_end_allowed,
+	// ride_through_allowed, maximum_speed_kph, station_parking,
+	// vehicle_type_ids) to properties of zone, property values override
+	// Rule. Booleans can be true/false, yes/no or 1/0, vehicle type ids
+	// array or comma separated string.
+	GeofencingRuleMapping struct {
+		Match      map[string]string
+		Rule       FeedGeofencingZonesRule
+		Properties map[string]string
+	}
+	// geofencingSource is polygon zone read from source file.
+	geofencingSource struct {
+		label      string
+		polygons   GeoJSONMultiPolygon
+		properties map[string]any
+		err        error
+	}
+)
+
+// ImportGeofencingZonesFile imports geofencing zones from GeoJSON
+// (.geojson, .json) or KML (.kml) file.
+func ImportGeofencingZonesFile(path string, options GeofencingImportOptions) (*FeedGeofencingZones, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".geojson", ".json":
+		return ImportGeofencingZonesGeoJSON(b, options)
+	case ".kml":
+		return ImportGeofencingZonesKML(b, options)
+	}
+	return nil, NewError(path+": ", ErrUnsupportedImportFormat)
+}
+
+// ImportGeofencingZonesGeoJSON imports zones from GeoJSON feature
+// collection, feature or geometry. Polygons are converted to
+// MultiPolygons, rings are closed and oriented by right-hand rule and
+// altitude of positions is dropped. Zones with invalid geometry or rules
+// are left out and reported in returned error together with imported
+// feed.
+func ImportGeofencingZonesGeoJSON(b []byte, options GeofencingImportOptions) (*FeedGeofencingZones, error) {
+	doc := &geoJSONDocument{}
+	err := json.Unmarshal(b, doc)
+	if err != nil {
+		return nil, err
+	}
+	sources := []*geofencingSource{}
+	switch doc.Type {
+	case "FeatureCollection":
+		for i, f := range doc.Features {
+			sources = append(sources, f.source("features["+strconv.Itoa(i)+"]"))
+		}
+	case "Feature":
+		sources = append(sources, doc.geoJSONDocumentFeature.source("feature"))
+	default:
+		src := &geofencingSource{label: "geometry"}
+		src.polygons, src.err = geoJSONPolygons(doc.Type, doc.Coordinates, doc.Geometries)
+		sources = append(sources, src)
+	}
+	return importGeofencingZones(sources, options)
+}
+
+type (
+	geoJSONDocument struct {
+		geoJSONDocumentFeature
+		Features    []*geoJSONDocumentFeature  `json:"features"`
+		Coordinates json.RawMessage            `json:"coordinates"`
+		Geometries  []*geoJSONDocumentGeometry `json:"geometries"`
+	}
+	geoJSONDocumentFeature struct {
+		Type       string                   `json:"type"`
+		Geometry   *geoJSONDocumentGeometry `json:"geometry"`
+		Properties map[string]any           `json:"properties"`
+	}
+	geoJSONDocumentGeometry struct {
+		Type        string                     `json:"type"`
+		Coordinates json.RawMessage            `json:"coordinates"`
+		Geometries  []*geoJSONDocumentGeometry `json:"geometries"`
+	}
+)
+
+func (f *geoJSONDocumentFeature) source(label string) *geofencingSource {
+	src := &geofencingSource{
+		label:      label,
+		properties: f.Properties,
+	}
+	if f.Geometry == nil {
+		src.err = NewError("missing geometry: ", ErrInvalidGeometry)
+		return src
+	}
+	src.polygons, src.err = geoJSONPolygons(f.Geometry.Type, f.Geometry.Coordinates, f.Geometry.Geometries)
+	return src
+}
+
+// geoJSONPolygons returns polygons of Polygon, MultiPolygon or
+// GeometryCollection of them.
+func geoJSONPolygons(geometryType string, coordinates json.RawMessage, geometries []*geoJSONDocumentGeometry) (GeoJSONMultiPolygon, error) {
+	switch geometryType {
+	case "Polygon", "MultiPolygon":
+		var c any
+		err := json.Unmarshal(coordinates, &c)
+		if err != nil {
+			return nil, NewError("invalid coordinates: ", ErrInvalidGeometry)
+		}
+		return (&GeoJSONGeometry{Type: geometryType, Coordinates: dropAltitude(c)}).MultiPolygon()
+	case "GeometryCollection":
+		polygons := GeoJSONMultiPolygon{}
+		for _, g := range geometries {
+			p, err := geoJSONPolygons(g.Type, g.Coordinates, g.Geometries)
+			if err != nil {
+				return nil, err
+			}
+			polygons = append(polygons, p...)
+		}
+		return polygons, nil
+	}
+	return nil, NewError("unsupported geometry type "+strconv.Quote(geometryType)+": ", ErrInvalidGeometry)
+}
+
+// dropAltitude removes altitude from positions of decoded coordinates.
+func dropAltitude(v any) any {
+	items, ok := v.([]any)
+	if !ok {
+		return v
+	}
+	if len(items) > 2 {
+		if _, ok := items[0].(float64); ok {
+			return items[:2]
+		}
+	}
+	for i, item := range items {
+		items[i] = dropAltitude(item)
+	}
+	return items
+}
+
+// importGeofencingZones builds feed from valid sources and joins errors of
+// invalid ones.
+func importGeofencingZones(sources []*geofencingSource, options GeofencingImportOptions) (*FeedGeofencingZones, error) {
+	features := []*FeedGeofencingZonesGeoJSONFeature{}
+	errs := []error{}
+	for _, src := range sources {
+		if src.err == nil {
+			src.polygons, src.err = src.polygons.Normalize()
+		}
+		var properties *FeedGeofencingZonesGeoJSONFeatureProperties
+		if src.err == nil {
+			properties, src.err = options.zoneProperties(src.properties)
+		}
+		if src.err != nil {
+			errs = append(errs, NewError(src.label+": ", src.err))
+			continue
+		}
+		features = append(features, NewFeedGeofencingZonesGeoJSONFeature(
+			NewGeoJSONGeometryMultiPolygon(src.polygons, nil),
+			properties,
+		))
+	}
+	globalRules := []*FeedGeofencingZonesRule{}
+	for i, rule := range options.GlobalRules {
+		if err := checkGeofencingRule(rule); err != nil {
+			errs = append(errs, NewError("global_rules["+strconv.Itoa(i)+"]: ", err))
+			continue
+		}
+		globalRules = append(globalRules, rule)
+	}
+	feed := &FeedGeofencingZones{
+		Data: &FeedGeofencingZonesData{
+			GeofencingZones: NewFeedGeofencingZonesGeoJSONFeatureCollection(features),
+			GlobalRules:     globalRules,
+		},
+	}
+	return feed, errors.Join(errs...)
+}
+
+func (o *GeofencingImportOptions) zoneProperties(properties map[string]any) (*FeedGeofencingZonesGeoJSONFeatureProperties, error) {
+	zone := &FeedGeofencingZonesGeoJSONFeatureProperties{
+		Rules: []*FeedGeofencingZonesRule{},
+	}
+	for _, language := range sortedKeys(o.NameProperties) {
+		if name := propertyString(properties[o.NameProperties[language]]); name != "" {
+			zone.Name = append(zone.Name, NewLocalizedString(name, language))
+		}
+	}
+	var err error
+	zone.Start, err = propertyTimestamp(properties, o.StartProperty)
+	if err != nil {
+		return nil, err
+	}
+	zone.End, err = propertyTimestamp(properties, o.EndProperty)
+	if err != nil {
+		return nil, err
+	}
+	for _, mapping := range o.Rules {
+		if !mapping.matches(properties) {
+			continue
+		}
+		rule, err := mapping.rule(properties)
+		if err == nil {
+			err = checkGeofencingRule(rule)
+		}
+		if err != nil {
+			return nil, err
+		}
+		zone.Rules = append(zone.Rules, rule)
+	}
+	return zone, nil
+}
+
+func (m *GeofencingRuleMapping) matches(properties map[string]any) bool {
+	for k, v := range m.Match {
+		if propertyString(properties[k]) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// rule returns copy of rule with fields read from properties.
+func (m *GeofencingRuleMapping) rule(properties map[string]any) (*FeedGeofencingZonesRule, error) {
+	rule := m.Rule
+	rule.VehicleTypeIDs = append([]*ID{}, m.Rule.VehicleTypeIDs...)
+	for _, field := range sortedKeys(m.Properties) {
+		v, ok := properties[m.Properties[field]]
+		if !ok || v == nil || propertyString(v) == "" {
+			continue
+		}
+		var err error
+		switch field {
+		case "ride_start_allowed":
+			rule.RideStartAllowed, err = propertyBoolean(v)
+		case "ride_end_allowed":
+			rule.RideEndAllowed, err = propertyBoolean(v)
+		case "ride_through_allowed":
+			rule.RideThroughAllowed, err = propertyBoolean(v)
+		case "station_parking":
+			rule.StationParking, err = propertyBoolean(v)
+		case "maximum_speed_kph":
+			var speed int64
+			speed, err = strconv.ParseInt(propertyString(v), 10, 64)
+			rule.MaximumSpeedKph = &speed
+		case "vehicle_type_ids":
+			rule.VehicleTypeIDs = propertyIDs(v)
+		default:
+			return nil, errors.New("unknown rule field " + field)
+		}
+		if err != nil {
+			return nil, errors.New("property " + m.Properties[field] + ": invalid value " + strconv.Quote(propertyString(v)) + " of " + field)
+		}
+	}
+	if len(rule.VehicleTypeIDs) == 0 {
+		rule.VehicleTypeIDs = nil
+	}
+	return &rule, nil
+}
+
+// checkGeofencingRule returns error, when required fields of rule are not
+// set.
+func checkGeofencingRule(rule *FeedGeofencingZonesRule) error {
+	if rule == nil {
+		return NewError("null rule: ", ErrInvalidGeofencingRule)
+	}
+	missing := []string{}
+	if rule.RideStartAllowed == nil {
+		missing = append(missing, "ride_start_allowed")
+	}
+	if rule.RideEndAllowed == nil {
+		missing = append(missing, "ride_end_allowed")
+	}
+	if rule.RideThroughAllowed == nil {
+		missing = append(missing, "ride_through_allowed")
+	}
+	if len(missing) > 0 {
+		return NewError("missing "+strings.Join(missing, ", ")+": ", ErrInvalidGeofencingRule)
+	}
+	return nil
+}
+
+// propertyString returns property value as string, numbers are formatted
+// without exponent.
+func propertyString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func propertyBoolean(v any) (*Boolean, error) {
+	switch strings.ToLower(propertyString(v)) {
+	case "true", "yes", "1":
+		return NewBoolean(true), nil
+	case "false", "no", "0":
+		return NewBoolean(false), nil
+	}
+	return nil, errors.New("invalid boolean")
+}
+
+func propertyIDs(v any) []*ID {
+	values := []string{}
+	if list, ok := v.([]any); ok {
+		for _, item := range list {
+			values = append(values, propertyString(item))
+		}
+	} else {
+		values = strings.Split(propertyString(v), ",")
+	}
+	ids := []*ID{}
+	for _, id := range values {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, NewID(id))
+		}
+	}
+	return ids
+}
+
+func propertyTimestamp(properties map[string]any, property string) (*Timestamp, error) {
+	if property == "" {
+		return nil, nil
+	}
+	s := propertyString(properties[property])
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, s)
+	}
+	if err != nil {
+		return nil, errors.New("property " + property + ": invalid time " + strconv.Quote(s))
+	}
+	return NewTimestamp(t.UTC().Format(time.RFC3339)), nil
+}
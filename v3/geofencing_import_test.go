@@ -0,0 +1,160 @@
+package gbfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testImportOptions() GeofencingImportOptions {
+	return GeofencingImportOptions{
+		NameProperties: map[string]string{"en": "name_en", "sk": "name"},
+		StartProperty:  "valid_from",
+		Rules: []*GeofencingRuleMapping{
+			{
+				Match: map[string]string{"zone_type": "no_parking"},
+				Rule: FeedGeofencingZonesRule{
+					RideStartAllowed:   NewBoolean(false),
+					RideEndAllowed:     NewBoolean(false),
+					RideThroughAllowed: NewBoolean(true),
+				},
+				Properties: map[string]string{"maximum_speed_kph": "speed_limit", "vehicle_type_ids": "vehicle_types", "ride_through_allowed": "through"},
+			},
+		},
+		GlobalRules: []*FeedGeofencingZonesRule{
+			{RideStartAllowed: NewBoolean(true), RideEndAllowed: NewBoolean(true), RideThroughAllowed: NewBoolean(true)},
+		},
+	}
+}
+
+func TestImportGeofencingZonesGeoJSON(t *testing.T) {
+	doc := `{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{"name":"Centrum","name_en":"Center","zone_type":"no_parking","speed_limit":15,"vehicle_types":"bike, scooter","through":"no","valid_from":"2024-05-01"},
+		 "geometry":{"type":"Polygon","coordinates":[[[17.10,48.14,120],[17.10,48.15,120],[17.12,48.15,120],[17.12,48.14,120]]]}},
+		{"type":"Feature","properties":{"zone_type":"other"},
+		 "geometry":{"type":"GeometryCollection","geometries":[{"type":"MultiPolygon","coordinates":[[[[17.20,48.14],[17.22,48.14],[17.22,48.15],[17.20,48.14]]]]}]}},
+		{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[17.1,48.1]}},
+		{"type":"Feature","properties":{"zone_type":"no_parking","through":"maybe"},
+		 "geometry":{"type":"Polygon","coordinates":[[[17.30,48.14],[17.32,48.14],[17.32,48.15],[17.30,48.14]]]}}
+	]}`
+	feed, err := ImportGeofencingZonesGeoJSON([]byte(doc), testImportOptions())
+	if !errors.Is(err, ErrInvalidGeometry) {
+		t.Fatalf("expected invalid point zone to be reported, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "features[3]") || !strings.Contains(err.Error(), "ride_through_allowed") {
+		t.Errorf("expected invalid rule property to be reported, got %v", err)
+	}
+	features := feed.Data.GeofencingZones.Features
+	if len(features) != 2 {
+		t.Fatalf("expected 2 zones, got %d", len(features))
+	}
+	zone := features[0]
+	polygons, err := zone.Geometry.MultiPolygon()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring := polygons[0][0]
+	if len(ring) != 5 || ring[0] != ring[4] || ring[1] != (GeoJSONPoint{17.12, 48.14}) {
+		t.Errorf("expected closed counterclockwise ring without altitude, got %v", ring)
+	}
+	properties := zone.Properties
+	if len(properties.Name) != 2 || properties.Name[0].Text != "Center" || properties.Name[1].Text != "Centrum" {
+		t.Errorf("unexpected names %v", testJSON(t, properties.Name))
+	}
+	if properties.Start == nil || string(*properties.Start) != "2024-05-01T00:00:00Z" {
+		t.Errorf("unexpected start %v", properties.Start)
+	}
+	if len(properties.Rules) != 1 {
+		t.Fatalf("expected single rule, got %d", len(properties.Rules))
+	}
+	rule := properties.Rules[0]
+	if *rule.MaximumSpeedKph != 15 || len(rule.VehicleTypeIDs) != 2 || *rule.VehicleTypeIDs[1] != "scooter" || bool(*rule.RideThroughAllowed) || bool(*rule.RideStartAllowed) {
+		t.Errorf("unexpected rule %s", testJSON(t, rule))
+	}
+	if len(features[1].Properties.Rules) != 0 {
+		t.Error("expected no rules for zone not matched by mapping")
+	}
+	if len(feed.Data.GlobalRules) != 1 {
+		t.Errorf("expected global rule, got %d", len(feed.Data.GlobalRules))
+	}
+}
+
+func TestImportGeofencingZonesRequiredRuleFields(t *testing.T) {
+	doc := `{"type":"Polygon","coordinates":[[[17.10,48.14],[17.12,48.14],[17.12,48.15],[17.10,48.14]]]}`
+	options := GeofencingImportOptions{
+		Rules: []*GeofencingRuleMapping{
+			{Rule: FeedGeofencingZonesRule{RideStartAllowed: NewBoolean(false)}, Properties: map[string]string{"ride_end_allowed": "end"}},
+		},
+		GlobalRules: []*FeedGeofencingZonesRule{nil, {RideStartAllowed: NewBoolean(true)}},
+	}
+	feed, err := ImportGeofencingZonesGeoJSON([]byte(doc), options)
+	if !errors.Is(err, ErrInvalidGeofencingRule) {
+		t.Fatalf("expected ErrInvalidGeofencingRule, got %v", err)
+	}
+	for _, s := range []string{"geometry: missing ride_end_allowed, ride_through_allowed", "global_rules[0]: null rule", "global_rules[1]: missing ride_end_allowed, ride_through_allowed"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("expected %q in %v", s, err)
+		}
+	}
+	if len(feed.Data.GeofencingZones.Features) != 0 || len(feed.Data.GlobalRules) != 0 {
+		t.Error("expected zones and global rules with missing rule fields to be left out")
+	}
+}
+
+func TestImportGeofencingZonesKML(t *testing.T) {
+	kml := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2"><Document>
+	<Placemark>
+		<name> Centrum </name>
+		<ExtendedData>
+			<Data name="zone_type"><value>no_parking</value></Data>
+			<SchemaData><SimpleData name="speed_limit">10</SimpleData></SchemaData>
+		</ExtendedData>
+		<MultiGeometry><Polygon>
+			<outerBoundaryIs><LinearRing><coordinates>
+				17.10,48.14,250 17.12,48.14,250 17.12,48.16,250 17.10,48.16,250 17.10,48.14,250
+			</coordinates></LinearRing></outerBoundaryIs>
+			<innerBoundaryIs><LinearRing><coordinates>17.105,48.145 17.11,48.145 17.11,48.15 17.105,48.145</coordinates></LinearRing></innerBoundaryIs>
+		</Polygon></MultiGeometry>
+	</Placemark>
+	<Placemark><name>Stop</name><Point><coordinates>17.1,48.1,0</coordinates></Point></Placemark>
+	<Placemark><name>Broken</name><Polygon><outerBoundaryIs><LinearRing><coordinates>17.1 48.1</coordinates></LinearRing></outerBoundaryIs></Polygon></Placemark>
+</Document></kml>`
+	path := filepath.Join(t.TempDir(), "zones.kml")
+	if err := os.WriteFile(path, []byte(kml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	options := testImportOptions()
+	options.NameProperties = map[string]string{"sk": "name"}
+	feed, err := ImportGeofencingZonesFile(path, options)
+	if !errors.Is(err, ErrInvalidGeometry) || !strings.Contains(err.Error(), `"Stop"`) || !strings.Contains(err.Error(), `"Broken"`) {
+		t.Fatalf("expected point and broken placemarks to be reported, got %v", err)
+	}
+	features := feed.Data.GeofencingZones.Features
+	if len(features) != 1 {
+		t.Fatalf("expected single zone, got %d", len(features))
+	}
+	polygons, err := features[0].Geometry.MultiPolygon()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(polygons) != 1 || len(polygons[0]) != 2 || polygons[0][0][0] != (GeoJSONPoint{17.10, 48.14}) {
+		t.Errorf("expected polygon with hole and altitude dropped, got %v", polygons)
+	}
+	properties := features[0].Properties
+	if properties.Name[0].Text != "Centrum" || *properties.Rules[0].MaximumSpeedKph != 10 {
+		t.Errorf("unexpected properties %s", testJSON(t, properties))
+	}
+	if _, err := ImportGeofencingZonesFile(filepath.Join(t.TempDir(), "zones.shp"), options); err == nil {
+		t.Error("expected error for missing file")
+	}
+	path = filepath.Join(t.TempDir(), "zones.shp")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportGeofencingZonesFile(path, options); !errors.Is(err, ErrUnsupportedImportFormat) {
+		t.Errorf("expected ErrUnsupportedImportFormat, got %v", err)
+	}
+}
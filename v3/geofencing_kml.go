@@ -0,0 +1,124 @@
+package gbfs
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	kmlPlacemark struct {
+		Name          string        `xml:"name"`
+		Description   string        `xml:"description"`
+		Data          []*kmlData    `xml:"ExtendedData>Data"`
+		SimpleData    []*kmlData    `xml:"ExtendedData>SchemaData>SimpleData"`
+		Polygons      []*kmlPolygon `xml:"Polygon"`
+		MultiPolygons []*kmlPolygon `xml:"MultiGeometry>Polygon"`
+		Points        []struct{}    `xml:"Point"`
+		LineStrings   []struct{}    `xml:"LineString"`
+	}
+	kmlData struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value"`
+		Text  string `xml:",chardata"`
+	}
+	kmlPolygon struct {
+		Outer string   `xml:"outerBoundaryIs>LinearRing>coordinates"`
+		Inner []string `xml:"innerBoundaryIs>LinearRing>coordinates"`
+	}
+)
+
+// ImportGeofencingZonesKML imports zones from polygon placemarks of KML
+// document. Properties of zone are name and description of placemark and
+// fields of its extended data. Zones are normalized and validated as in
+// ImportGeofencingZonesGeoJSON.
+func ImportGeofencingZonesKML(b []byte, options GeofencingImportOptions) (*FeedGeofencingZones, error) {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	sources := []*geofencingSource{}
+	for {
+		t, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := t.(xml.StartElement)
+		if !ok || start.Name.Local != "Placemark" {
+			continue
+		}
+		p := &kmlPlacemark{}
+		err = d.DecodeElement(p, &start)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, p.source("placemarks["+strconv.Itoa(len(sources))+"]"))
+	}
+	return importGeofencingZones(sources, options)
+}
+
+func (p *kmlPlacemark) source(label string) *geofencingSource {
+	if p.Name != "" {
+		label += " " + strconv.Quote(p.Name)
+	}
+	src := &geofencingSource{
+		label: label,
+		properties: map[string]any{
+			"name":        strings.TrimSpace(p.Name),
+			"description": strings.TrimSpace(p.Description),
+		},
+	}
+	for _, data := range append(p.Data, p.SimpleData...) {
+		value := data.Value
+		if value == "" {
+			value = data.Text
+		}
+		src.properties[data.Name] = strings.TrimSpace(value)
+	}
+	polygons := append(p.Polygons, p.MultiPolygons...)
+	if len(polygons) == 0 {
+		src.err = NewError("placemark has no polygon: ", ErrInvalidGeometry)
+		if len(p.Points) > 0 || len(p.LineStrings) > 0 {
+			src.err = NewError("unsupported geometry type: ", ErrInvalidGeometry)
+		}
+		return src
+	}
+	for _, polygon := range polygons {
+		rings := GeoJSONPolygon{}
+		for _, coordinates := range append([]string{polygon.Outer}, polygon.Inner...) {
+			ring, err := kmlCoordinates(coordinates)
+			if err != nil {
+				src.err = err
+				return src
+			}
+			rings = append(rings, ring)
+		}
+		src.polygons = append(src.polygons, rings)
+	}
+	return src
+}
+
+// kmlCoordinates parses whitespace separated lon,lat[,alt] tuples.
+// Altitude is ignored.
+func kmlCoordinates(s string) ([][2]float64, error) {
+	ring := [][2]float64{}
+	for _, tuple := range strings.Fields(s) {
+		parts := strings.Split(tuple, ",")
+		if len(parts) < 2 {
+			return nil, NewError("invalid coordinates "+strconv.Quote(tuple)+": ", ErrInvalidGeometry)
+		}
+		lon, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return nil, NewError("invalid coordinates "+strconv.Quote(tuple)+": ", ErrInvalidGeometry)
+		}
+		lat, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, NewError("invalid coordinates "+strconv.Quote(tuple)+": ", ErrInvalidGeometry)
+		}
+		ring = append(ring, [2]float64{lon, lat})
+	}
+	return ring, nil
+}
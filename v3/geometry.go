@@ -0,0 +1,573 @@
+package gbfs
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+)
+
+var ErrInvalidGeometry = errors.New("invalid geometry")
+
+// earthRadius is equatorial radius of WGS 84 ellipsoid in meters.
+const earthRadius = 6378137.0
+
+type (
+	// GeoJSONPoint is position of longitude and latitude.
+	GeoJSONPoint [2]float64
+	// GeoJSONPolygon is exterior ring followed by holes.
+	GeoJSONPolygon [][][2]float64
+	// GeoJSONMultiPolygon is list of polygons.
+	GeoJSONMultiPolygon []GeoJSONPolygon
+	// GeoJSONBBox is bounding box of west, south, east and north.
+	GeoJSONBBox [4]float64
+)
+
+// Point returns coordinates of Point geometry.
+func (g *GeoJSONGeometry) Point() (GeoJSONPoint, error) {
+	if g == nil || g.Type != "Point" {
+		return GeoJSONPoint{}, NewError("not a Point: ", ErrInvalidGeometry)
+	}
+	if c, ok := g.Coordinates.(GeoJSONPoint); ok {
+		return c, nil
+	}
+	v, err := geoJSONValue(g.Coordinates)
+	if err != nil {
+		return GeoJSONPoint{}, err
+	}
+	return positionFromValue(v)
+}
+
+// Polygon returns coordinates of Polygon geometry.
+func (g *GeoJSONGeometry) Polygon() (GeoJSONPolygon, error) {
+	if g == nil || g.Type != "Polygon" {
+		return nil, NewError("not a Polygon: ", ErrInvalidGeometry)
+	}
+	if c, ok := g.Coordinates.(GeoJSONPolygon); ok {
+		return c, nil
+	}
+	v, err := geoJSONValue(g.Coordinates)
+	if err != nil {
+		return nil, err
+	}
+	return polygonFromValue(v)
+}
+
+// MultiPolygon returns coordinates of Polygon or MultiPolygon geometry as
+// MultiPolygon.
+func (g *GeoJSONGeometry) MultiPolygon() (GeoJSONMultiPolygon, error) {
+	if g != nil && g.Type == "Polygon" {
+		p, err := g.Polygon()
+		if err != nil {
+			return nil, err
+		}
+		return GeoJSONMultiPolygon{p}, nil
+	}
+	if g == nil || g.Type != "MultiPolygon" {
+		return nil, NewError("not a Polygon or MultiPolygon: ", ErrInvalidGeometry)
+	}
+	if c, ok := g.Coordinates.(GeoJSONMultiPolygon); ok {
+		return c, nil
+	}
+	v, err := geoJSONValue(g.Coordinates)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := v.([]any)
+	if !ok {
+		return nil, NewError("invalid coordinates: ", ErrInvalidGeometry)
+	}
+	p := make(GeoJSONMultiPolygon, 0, len(items))
+	for _, item := range items {
+		polygon, err := polygonFromValue(item)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, polygon)
+	}
+	return p, nil
+}
+
+// geoJSONValue returns coordinates as decoded by encoding/json into any.
+// Coordinates set from Go values (e.g. [][][][]float64) are converted.
+func geoJSONValue(coordinates any) (any, error) {
+	switch c := coordinates.(type) {
+	case nil:
+		return nil, NewError("missing coordinates: ", ErrInvalidGeometry)
+	case []any:
+		return c, nil
+	}
+	b, err := json.Marshal(coordinates)
+	if err != nil {
+		return nil, NewError("invalid coordinates: ", ErrInvalidGeometry)
+	}
+	var v any
+	err = json.Unmarshal(b, &v)
+	if err != nil || v == nil {
+		return nil, NewError("invalid coordinates: ", ErrInvalidGeometry)
+	}
+	return v, nil
+}
+
+// positionFromValue returns position of longitude and latitude. Altitude
+// (and other ordinates) would be lost, so only zero altitude is accepted.
+func positionFromValue(v any) (GeoJSONPoint, error) {
+	ordinates, ok := v.([]any)
+	if !ok || len(ordinates) < 2 {
+		return GeoJSONPoint{}, NewError("invalid position: ", ErrInvalidGeometry)
+	}
+	p := GeoJSONPoint{}
+	for i, o := range ordinates {
+		f, ok := o.(float64)
+		if !ok {
+			return GeoJSONPoint{}, NewError("invalid position: ", ErrInvalidGeometry)
+		}
+		if i < 2 {
+			p[i] = f
+		} else if f != 0 {
+			return GeoJSONPoint{}, NewError("position with altitude is not supported: ", ErrInvalidGeometry)
+		}
+	}
+	return p, nil
+}
+
+func polygonFromValue(v any) (GeoJSONPolygon, error) {
+	rings, ok := v.([]any)
+	if !ok {
+		return nil, NewError("invalid polygon: ", ErrInvalidGeometry)
+	}
+	p := make(GeoJSONPolygon, 0, len(rings))
+	for _, r := range rings {
+		positions, ok := r.([]any)
+		if !ok {
+			return nil, NewError("invalid ring: ", ErrInvalidGeometry)
+		}
+		ring := make([][2]float64, 0, len(positions))
+		for _, position := range positions {
+			pos, err := positionFromValue(position)
+			if err != nil {
+				return nil, err
+			}
+			ring = append(ring, pos)
+		}
+		p = append(p, ring)
+	}
+	return p, nil
+}
+
+// BBox returns bounding box of Point, Polygon or MultiPolygon geometry,
+// zero for other geometries and invalid coordinates.
+func (g *GeoJSONGeometry) BBox() GeoJSONBBox {
+	if g != nil && g.Type == "Point" {
+		p, err := g.Point()
+		if err != nil {
+			return GeoJSONBBox{}
+		}
+		return p.BBox()
+	}
+	p, err := g.MultiPolygon()
+	if err != nil {
+		return GeoJSONBBox{}
+	}
+	return p.BBox()
+}
+
+// Validate checks coordinates of Point, Polygon and MultiPolygon geometry.
+func (g *GeoJSONGeometry) Validate() error {
+	if g == nil {
+		return NewError("missing geometry: ", ErrInvalidGeometry)
+	}
+	switch g.Type {
+	case "Point":
+		p, err := g.Point()
+		if err != nil {
+			return err
+		}
+		return p.Validate()
+	case "Polygon":
+		p, err := g.Polygon()
+		if err != nil {
+			return err
+		}
+		return p.Validate()
+	case "MultiPolygon":
+		p, err := g.MultiPolygon()
+		if err != nil {
+			return err
+		}
+		return p.Validate()
+	}
+	if g.Coordinates == nil {
+		return NewError("missing coordinates: ", ErrInvalidGeometry)
+	}
+	return nil
+}
+
+func (p GeoJSONPoint) BBox() GeoJSONBBox {
+	return GeoJSONBBox{p[0], p[1], p[0], p[1]}
+}
+
+func (p GeoJSONPoint) Validate() error {
+	if !validPosition(p) {
+		return NewError("position out of range: ", ErrInvalidGeometry)
+	}
+	return nil
+}
+
+func (p GeoJSONPolygon) BBox() GeoJSONBBox {
+	return GeoJSONMultiPolygon{p}.BBox()
+}
+
+func (p GeoJSONMultiPolygon) BBox() GeoJSONBBox {
+	b := GeoJSONBBox{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, polygon := range p {
+		for _, ring := range polygon {
+			for _, pos := range ring {
+				b[0] = math.Min(b[0], pos[0])
+				b[1] = math.Min(b[1], pos[1])
+				b[2] = math.Max(b[2], pos[0])
+				b[3] = math.Max(b[3], pos[1])
+			}
+		}
+	}
+	if math.IsInf(b[0], 1) {
+		return GeoJSONBBox{}
+	}
+	return b
+}
+
+// Contains reports whether point is inside bounding box.
+func (b GeoJSONBBox) Contains(lon, lat float64) bool {
+	return lon >= b[0] && lon <= b[2] && lat >= b[1] && lat <= b[3]
+}
+
+// Validate checks that rings are closed, have at least 3 distinct
+// positions, are not self-intersecting and follow right-hand rule
+// (exterior ring counterclockwise, holes clockwise).
+func (p GeoJSONPolygon) Validate() error {
+	if len(p) == 0 {
+		return NewError("polygon has no rings: ", ErrInvalidGeometry)
+	}
+	for i, ring := range p {
+		n := len(ring)
+		if n > 0 && ring[0] != ring[n-1] {
+			return NewError("ring "+strconv.Itoa(i)+" is not closed: ", ErrInvalidGeometry)
+		}
+		area, err := checkRing(ring)
+		if err != nil {
+			return NewError("ring "+strconv.Itoa(i)+" ", err)
+		}
+		if (area > 0) != (i == 0) {
+			return NewError("ring "+strconv.Itoa(i)+" has wrong winding order: ", ErrInvalidGeometry)
+		}
+	}
+	return nil
+}
+
+func (p GeoJSONMultiPolygon) Validate() error {
+	if len(p) == 0 {
+		return NewError("geometry has no polygons: ", ErrInvalidGeometry)
+	}
+	for i, polygon := range p {
+		err := polygon.Validate()
+		if err != nil {
+			return NewError("polygon "+strconv.Itoa(i)+" ", err)
+		}
+	}
+	return nil
+}
+
+// Normalize returns copy of polygon with rings closed, repeated positions
+// removed and rings oriented by right-hand rule. Polygon with invalid ring
+// is rejected.
+func (p GeoJSONPolygon) Normalize() (GeoJSONPolygon, error) {
+	if len(p) == 0 {
+		return nil, NewError("polygon has no rings: ", ErrInvalidGeometry)
+	}
+	rings := make(GeoJSONPolygon, 0, len(p))
+	for i, ring := range p {
+		ring, err := normalizeRing(ring, i == 0)
+		if err != nil {
+			return nil, NewError("ring "+strconv.Itoa(i)+" ", err)
+		}
+		rings = append(rings, ring)
+	}
+	return rings, nil
+}
+
+func (p GeoJSONMultiPolygon) Normalize() (GeoJSONMultiPolygon, error) {
+	if len(p) == 0 {
+		return nil, NewError("geometry has no polygons: ", ErrInvalidGeometry)
+	}
+	normalized := make(GeoJSONMultiPolygon, 0, len(p))
+	for i, polygon := range p {
+		polygon, err := polygon.Normalize()
+		if err != nil {
+			return nil, NewError("polygon "+strconv.Itoa(i)+" ", err)
+		}
+		normalized = append(normalized, polygon)
+	}
+	return normalized, nil
+}
+
+// Area returns area of polygon in square meters on sphere, holes are
+// subtracted.
+func (p GeoJSONPolygon) Area() float64 {
+	area := 0.0
+	for i, ring := range p {
+		a := math.Abs(sphericalRingArea(ring))
+		if i == 0 {
+			area += a
+		} else {
+			area -= a
+		}
+	}
+	return math.Max(area, 0)
+}
+
+func (p GeoJSONMultiPolygon) Area() float64 {
+	area := 0.0
+	for _, polygon := range p {
+		area += polygon.Area()
+	}
+	return area
+}
+
+// Centroid returns center of mass of polygon computed in longitude and
+// latitude, holes are subtracted.
+func (p GeoJSONPolygon) Centroid() GeoJSONPoint {
+	return GeoJSONMultiPolygon{p}.Centroid()
+}
+
+func (p GeoJSONMultiPolygon) Centroid() GeoJSONPoint {
+	var area, x, y float64
+	for _, polygon := range p {
+		for i, ring := range polygon {
+			a, cx, cy := ringCentroid(ring)
+			a = math.Abs(a)
+			if i > 0 {
+				a = -a
+			}
+			area += a
+			x += cx * a
+			y += cy * a
+		}
+	}
+	if area == 0 {
+		return GeoJSONPoint{}
+	}
+	return GeoJSONPoint{x / area, y / area}
+}
+
+// Contains reports whether point is inside exterior ring of polygon and
+// outside of its holes.
+func (p GeoJSONPolygon) Contains(lon, lat float64) bool {
+	if len(p) == 0 || !ringContains(p[0], lon, lat) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if ringContains(hole, lon, lat) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p GeoJSONMultiPolygon) Contains(lon, lat float64) bool {
+	for _, polygon := range p {
+		if polygon.Contains(lon, lat) {
+			return true
+		}
+	}
+	return false
+}
+
+func ringContains(ring [][2]float64, x, y float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func validPosition(p [2]float64) bool {
+	return !math.IsNaN(p[0]) && !math.IsNaN(p[1]) && p[0] >= -180 && p[0] <= 180 && p[1] >= -90 && p[1] <= 90
+}
+
+// dedupeRing returns ring without repeated consecutive positions.
+func dedupeRing(ring [][2]float64) [][2]float64 {
+	positions := make([][2]float64, 0, len(ring)+1)
+	for _, p := range ring {
+		if n := len(positions); n > 0 && positions[n-1] == p {
+			continue
+		}
+		positions = append(positions, p)
+	}
+	return positions
+}
+
+// checkRing checks closed ring and returns its signed planar area.
+func checkRing(ring [][2]float64) (float64, error) {
+	for _, p := range ring {
+		if !validPosition(p) {
+			return 0, NewError("has position out of range: ", ErrInvalidGeometry)
+		}
+	}
+	ring = dedupeRing(ring)
+	if len(ring) < 4 {
+		return 0, NewError("has less than 3 distinct positions: ", ErrInvalidGeometry)
+	}
+	area := ringArea(ring)
+	if area == 0 {
+		return 0, NewError("has zero area: ", ErrInvalidGeometry)
+	}
+	if ringSelfIntersects(ring) {
+		return 0, NewError("is self-intersecting: ", ErrInvalidGeometry)
+	}
+	return area, nil
+}
+
+func normalizeRing(ring [][2]float64, exterior bool) ([][2]float64, error) {
+	positions := dedupeRing(ring)
+	if n := len(positions); n > 1 && positions[0] != positions[n-1] {
+		positions = append(positions, positions[0])
+	}
+	area, err := checkRing(positions)
+	if err != nil {
+		return nil, err
+	}
+	if (area > 0) != exterior {
+		for i, j := 0, len(positions)-1; i < j; i, j = i+1, j-1 {
+			positions[i], positions[j] = positions[j], positions[i]
+		}
+	}
+	return positions, nil
+}
+
+// ringArea returns signed planar area of closed ring, positive for
+// counterclockwise ring.
+func ringArea(ring [][2]float64) float64 {
+	area := 0.0
+	for i := 0; i < len(ring)-1; i++ {
+		area += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return area / 2
+}
+
+// ringCentroid returns signed planar area and centroid of closed ring.
+func ringCentroid(ring [][2]float64) (float64, float64, float64) {
+	var area, x, y float64
+	for i := 0; i < len(ring)-1; i++ {
+		cross := ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+		area += cross
+		x += (ring[i][0] + ring[i+1][0]) * cross
+		y += (ring[i][1] + ring[i+1][1]) * cross
+	}
+	if area == 0 {
+		return 0, 0, 0
+	}
+	return area / 2, x / (3 * area), y / (3 * area)
+}
+
+// sphericalRingArea returns signed area of closed ring on sphere in square
+// meters.
+func sphericalRingArea(ring [][2]float64) float64 {
+	n := len(ring) - 1
+	if n < 3 {
+		return 0
+	}
+	area := 0.0
+	for i := 0; i < n; i++ {
+		prev := ring[(i+n-1)%n]
+		next := ring[(i+1)%n]
+		area += (radians(next[0]) - radians(prev[0])) * math.Sin(radians(ring[i][1]))
+	}
+	return area * earthRadius * earthRadius / 2
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// ringSelfIntersects reports whether any two segments of closed ring cross
+// or overlap. Segments are swept by longitude, so only segments with
+// overlapping extent are compared.
+func ringSelfIntersects(ring [][2]float64) bool {
+	n := len(ring) - 1
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	minX := func(i int) float64 {
+		return math.Min(ring[i][0], ring[i+1][0])
+	}
+	maxX := func(i int) float64 {
+		return math.Max(ring[i][0], ring[i+1][0])
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return minX(order[a]) < minX(order[b])
+	})
+	active := []int{}
+	for _, i := range order {
+		kept := active[:0]
+		for _, j := range active {
+			if maxX(j) >= minX(i) {
+				kept = append(kept, j)
+			}
+		}
+		active = kept
+		for _, j := range active {
+			if segmentsIntersect(ring, i, j, n) {
+				return true
+			}
+		}
+		active = append(active, i)
+	}
+	return false
+}
+
+// segmentsIntersect reports whether segments i and j of ring with n
+// segments intersect. Adjacent segments may share their common position
+// only.
+func segmentsIntersect(ring [][2]float64, i, j, n int) bool {
+	a, b := ring[i], ring[i+1]
+	c, d := ring[j], ring[j+1]
+	switch {
+	case (i+1)%n == j:
+		return orientation(a, b, d) == 0 && onSegment(a, b, d) || orientation(c, d, a) == 0 && onSegment(c, d, a)
+	case (j+1)%n == i:
+		return orientation(c, d, b) == 0 && onSegment(c, d, b) || orientation(a, b, c) == 0 && onSegment(a, b, c)
+	}
+	return segmentsCross(a, b, c, d)
+}
+
+// segmentsCross reports whether segments ab and cd cross or touch.
+func segmentsCross(a, b, c, d [2]float64) bool {
+	o1, o2 := orientation(a, b, c), orientation(a, b, d)
+	o3, o4 := orientation(c, d, a), orientation(c, d, b)
+	if o1 != o2 && o3 != o4 && o1 != 0 && o2 != 0 && o3 != 0 && o4 != 0 {
+		return true
+	}
+	return o1 == 0 && onSegment(a, b, c) || o2 == 0 && onSegment(a, b, d) || o3 == 0 && onSegment(c, d, a) || o4 == 0 && onSegment(c, d, b)
+}
+
+func orientation(a, b, c [2]float64) int {
+	v := (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+// onSegment reports whether collinear position p lies on segment ab.
+func onSegment(a, b, p [2]float64) bool {
+	return p[0] >= math.Min(a[0], b[0]) && p[0] <= math.Max(a[0], b[0]) && p[1] >= math.Min(a[1], b[1]) && p[1] <= math.Max(a[1], b[1])
+}
@@ -0,0 +1,274 @@
+package gbfs
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+const (
+	SimplifyDouglasPeucker = "douglas-peucker"
+	SimplifyVisvalingam    = "visvalingam"
+)
+
+// Simplify returns copy of polygon with rings simplified with tolerance in
+// meters. Douglas-Peucker removes positions closer than tolerance to
+// simplified ring, Visvalingam-Whyatt removes positions forming triangles
+// smaller than square of tolerance. Ring, which would become invalid or
+// would cross other ring of polygon, is simplified with lower tolerance or
+// kept unchanged, so topology of polygon is preserved.
+func (p GeoJSONPolygon) Simplify(method string, tolerance float64) GeoJSONPolygon {
+	out := append(GeoJSONPolygon{}, p...)
+	if tolerance <= 0 {
+		return out
+	}
+	for i, ring := range p {
+		for t := tolerance; t >= tolerance/8; t /= 2 {
+			simplified := simplifyRing(ring, method, t)
+			if len(simplified) == len(ring) {
+				break
+			}
+			if ringFits(out, i, simplified) {
+				out[i] = simplified
+				break
+			}
+		}
+	}
+	return out
+}
+
+func (p GeoJSONMultiPolygon) Simplify(method string, tolerance float64) GeoJSONMultiPolygon {
+	out := make(GeoJSONMultiPolygon, 0, len(p))
+	for _, polygon := range p {
+		out = append(out, polygon.Simplify(method, tolerance))
+	}
+	return out
+}
+
+// Round returns copy of polygon with coordinates rounded to precision
+// decimal places and repeated positions removed. Ring, which would become
+// invalid or would cross other ring, is kept unchanged.
+func (p GeoJSONPolygon) Round(precision int) GeoJSONPolygon {
+	out := append(GeoJSONPolygon{}, p...)
+	f := math.Pow(10, float64(precision))
+	for i, ring := range p {
+		rounded := make([][2]float64, 0, len(ring))
+		for _, pos := range ring {
+			rounded = append(rounded, [2]float64{math.Round(pos[0]*f) / f, math.Round(pos[1]*f) / f})
+		}
+		rounded = dedupeRing(rounded)
+		if ringFits(out, i, rounded) {
+			out[i] = rounded
+		}
+	}
+	return out
+}
+
+func (p GeoJSONMultiPolygon) Round(precision int) GeoJSONMultiPolygon {
+	out := make(GeoJSONMultiPolygon, 0, len(p))
+	for _, polygon := range p {
+		out = append(out, polygon.Round(precision))
+	}
+	return out
+}
+
+// ringFits reports whether ring can replace ring i of polygon. Ring must be
+// valid, keep orientation of replaced ring and must not cross other rings.
+func ringFits(polygon GeoJSONPolygon, i int, ring [][2]float64) bool {
+	if len(ring) < 4 || ring[0] != ring[len(ring)-1] {
+		return false
+	}
+	area, err := checkRing(ring)
+	if err != nil || (area > 0) != (ringArea(polygon[i]) > 0) {
+		return false
+	}
+	for j, other := range polygon {
+		if j != i && ringsCross(ring, other) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringsCross reports whether any segments of two rings cross or touch.
+func ringsCross(a, b [][2]float64) bool {
+	type segment struct {
+		p, q [2]float64
+		ring int
+	}
+	segments := []segment{}
+	for r, ring := range [][][2]float64{a, b} {
+		for i := 0; i < len(ring)-1; i++ {
+			p, q := ring[i], ring[i+1]
+			if q[0] < p[0] {
+				p, q = q, p
+			}
+			segments = append(segments, segment{p, q, r})
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].p[0] < segments[j].p[0]
+	})
+	active := []segment{}
+	for _, s := range segments {
+		kept := active[:0]
+		for _, o := range active {
+			if o.q[0] >= s.p[0] {
+				kept = append(kept, o)
+			}
+		}
+		active = kept
+		for _, o := range active {
+			if o.ring != s.ring && segmentsCross(s.p, s.q, o.p, o.q) {
+				return true
+			}
+		}
+		active = append(active, s)
+	}
+	return false
+}
+
+// simplifyRing simplifies closed ring in local projection in meters.
+func simplifyRing(ring [][2]float64, method string, tolerance float64) [][2]float64 {
+	if len(ring) <= 4 {
+		return ring
+	}
+	points := projectRing(ring)
+	var keep []bool
+	if method == SimplifyVisvalingam {
+		keep = visvalingam(points, tolerance*tolerance)
+	} else {
+		keep = douglasPeucker(points, tolerance)
+	}
+	simplified := make([][2]float64, 0, len(ring))
+	for i, pos := range ring {
+		if keep[i] {
+			simplified = append(simplified, pos)
+		}
+	}
+	return simplified
+}
+
+// projectRing projects positions to equirectangular projection in meters
+// centered at latitude of ring.
+func projectRing(ring [][2]float64) [][2]float64 {
+	b := GeoJSONPolygon{ring}.BBox()
+	k := earthRadius * math.Pi / 180
+	cos := math.Cos(radians((b[1] + b[3]) / 2))
+	points := make([][2]float64, len(ring))
+	for i, pos := range ring {
+		points[i] = [2]float64{pos[0] * k * cos, pos[1] * k}
+	}
+	return points
+}
+
+func douglasPeucker(points [][2]float64, tolerance float64) []bool {
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+	stack := [][2]int{{0, len(points) - 1}}
+	for len(stack) > 0 {
+		r := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		maxDistance, index := 0.0, -1
+		for i := r[0] + 1; i < r[1]; i++ {
+			d := segmentDistance(points[i], points[r[0]], points[r[1]])
+			if d > maxDistance {
+				maxDistance, index = d, i
+			}
+		}
+		if index >= 0 && maxDistance > tolerance {
+			keep[index] = true
+			stack = append(stack, [2]int{r[0], index}, [2]int{index, r[1]})
+		}
+	}
+	return keep
+}
+
+// segmentDistance returns distance of point p from segment ab.
+func segmentDistance(p, a, b [2]float64) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p[0]-a[0], p[1]-a[1])
+	}
+	t := ((p[0]-a[0])*dx + (p[1]-a[1])*dy) / (dx*dx + dy*dy)
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(p[0]-(a[0]+t*dx), p[1]-(a[1]+t*dy))
+}
+
+type (
+	vertex struct {
+		index      int
+		area       float64
+		prev, next *vertex
+		heapIndex  int
+	}
+	vertexHeap []*vertex
+)
+
+func (h vertexHeap) Len() int {
+	return len(h)
+}
+
+func (h vertexHeap) Less(i, j int) bool {
+	return h[i].area < h[j].area
+}
+
+func (h vertexHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *vertexHeap) Push(x any) {
+	v := x.(*vertex)
+	v.heapIndex = len(*h)
+	*h = append(*h, v)
+}
+
+func (h *vertexHeap) Pop() any {
+	old := *h
+	v := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return v
+}
+
+func visvalingam(points [][2]float64, minArea float64) []bool {
+	n := len(points)
+	keep := make([]bool, n)
+	for i := range keep {
+		keep[i] = true
+	}
+	vertices := make([]*vertex, n)
+	for i := range vertices {
+		vertices[i] = &vertex{index: i}
+	}
+	h := vertexHeap{}
+	for i := 1; i < n-1; i++ {
+		v := vertices[i]
+		v.prev, v.next = vertices[i-1], vertices[i+1]
+		v.area = triangleArea(points[i-1], points[i], points[i+1])
+		heap.Push(&h, v)
+	}
+	for h.Len() > 0 {
+		v := heap.Pop(&h).(*vertex)
+		if v.area >= minArea {
+			break
+		}
+		keep[v.index] = false
+		v.prev.next, v.next.prev = v.next, v.prev
+		for _, u := range []*vertex{v.prev, v.next} {
+			if u.prev == nil || u.next == nil {
+				continue
+			}
+			// area of neighbour is not lower than area of removed vertex,
+			// so that positions are removed in order of significance
+			u.area = math.Max(v.area, triangleArea(points[u.prev.index], points[u.index], points[u.next.index]))
+			heap.Fix(&h, u.heapIndex)
+		}
+	}
+	return keep
+}
+
+func triangleArea(a, b, c [2]float64) float64 {
+	return math.Abs((b[0]-a[0])*(c[1]-a[1])-(c[0]-a[0])*(b[1]-a[1])) / 2
+}
@@ -0,0 +1,113 @@
+package gbfs
+
+import (
+	"math"
+	"testing"
+)
+
+// testRing returns closed circular ring of n positions with zigzag noise in
+// meters around center, counterclockwise unless clockwise is set.
+func testRing(lon, lat, radius, noise float64, n int, clockwise bool) [][2]float64 {
+	k := earthRadius * math.Pi / 180
+	cos := math.Cos(radians(lat))
+	ring := make([][2]float64, 0, n+1)
+	for i := 0; i < n; i++ {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		if clockwise {
+			a = -a
+		}
+		r := radius + noise
+		if i%2 == 1 {
+			r = radius - noise
+		}
+		ring = append(ring, [2]float64{lon + r*math.Cos(a)/(k*cos), lat + r*math.Sin(a)/k})
+	}
+	return append(ring, ring[0])
+}
+
+func testPolygonPositions(p GeoJSONMultiPolygon) int {
+	n := 0
+	for _, polygon := range p {
+		for _, ring := range polygon {
+			n += len(ring)
+		}
+	}
+	return n
+}
+
+func TestGeoJSONPolygonSimplify(t *testing.T) {
+	// hole is 10 meters from exterior ring, simplification of either ring
+	// with higher tolerance would make them cross
+	polygon := GeoJSONPolygon{
+		testRing(17.1, 48.1, 2000, 2, 1000, false),
+		testRing(17.1, 48.1, 1990, 2, 1000, true),
+	}
+	if err := polygon.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range []string{SimplifyDouglasPeucker, SimplifyVisvalingam} {
+		for _, tolerance := range []float64{1, 5, 20, 100} {
+			simplified := polygon.Simplify(method, tolerance)
+			if len(simplified) != len(polygon) {
+				t.Fatalf("%s %v: expected %d rings, got %d", method, tolerance, len(polygon), len(simplified))
+			}
+			if err := simplified.Validate(); err != nil {
+				t.Errorf("%s %v: invalid polygon: %v", method, tolerance, err)
+			}
+			if ringsCross(simplified[0], simplified[1]) {
+				t.Errorf("%s %v: rings cross", method, tolerance)
+			}
+			for _, pos := range simplified[1] {
+				if !ringContains(simplified[0], pos[0], pos[1]) {
+					t.Errorf("%s %v: hole position %v outside exterior ring", method, tolerance, pos)
+					break
+				}
+			}
+			n := testPolygonPositions(GeoJSONMultiPolygon{simplified})
+			if tolerance >= 20 && n >= testPolygonPositions(GeoJSONMultiPolygon{polygon}) {
+				t.Errorf("%s %v: expected fewer positions than %d, got %d", method, tolerance, testPolygonPositions(GeoJSONMultiPolygon{polygon}), n)
+			}
+		}
+	}
+	if len(polygon[0]) != 1001 || len(polygon[1]) != 1001 {
+		t.Error("expected original polygon to be unchanged")
+	}
+}
+
+func TestGeoJSONPolygonSimplifyDegenerate(t *testing.T) {
+	polygon := GeoJSONPolygon{testRing(17.1, 48.1, 20, 0, 8, false)}
+	for _, method := range []string{SimplifyDouglasPeucker, SimplifyVisvalingam} {
+		simplified := polygon.Simplify(method, 1000)
+		if err := simplified.Validate(); err != nil {
+			t.Errorf("%s: expected ring to stay valid, got %v", method, err)
+		}
+		if len(simplified[0]) < 4 {
+			t.Errorf("%s: expected at least 4 positions, got %d", method, len(simplified[0]))
+		}
+	}
+}
+
+func TestGeoJSONPolygonRound(t *testing.T) {
+	polygon := GeoJSONPolygon{testRing(17.1, 48.1, 2000, 2, 1000, false)}
+	rounded := polygon.Round(4)
+	if err := rounded.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, pos := range rounded[0] {
+		for _, v := range pos {
+			if math.Abs(v*1e4-math.Round(v*1e4)) > 1e-6 {
+				t.Fatalf("expected 4 decimal places, got %v", v)
+			}
+		}
+	}
+	// rounding to ~100 meters would make ring invalid, ring is kept
+	rounded = polygon.Round(3)
+	if len(rounded[0]) != len(polygon[0]) || rounded[0][1] != polygon[0][1] {
+		t.Error("expected ring to be kept unchanged")
+	}
+	polygon = GeoJSONPolygon{{{17.1, 48.1}, {17.2, 48.1}, {17.2000001, 48.1000001}, {17.2, 48.2}, {17.1, 48.1}}}
+	rounded = polygon.Round(6)
+	if len(rounded[0]) != 4 {
+		t.Errorf("expected repeated position to be removed, got %v", rounded[0])
+	}
+}
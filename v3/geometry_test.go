@@ -0,0 +1,125 @@
+package gbfs
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var testSquare = GeoJSONPolygon{
+	{{17.10, 48.14}, {17.12, 48.14}, {17.12, 48.15}, {17.10, 48.15}, {17.10, 48.14}},
+}
+
+func TestGeoJSONGeometryCoordinates(t *testing.T) {
+	g := NewGeoJSONGeometryMultiPolygon(GeoJSONMultiPolygon{
+		{{{17.10, 48.14}, {17.12, 48.14}, {17.12, 48.15}, {17.10, 48.15}, {17.10, 48.14}}},
+	}, nil)
+	mp, err := g.MultiPolygon()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(mp, GeoJSONMultiPolygon{testSquare}) {
+		t.Errorf("unexpected multipolygon %v", mp)
+	}
+	b, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &GeoJSONGeometry{}
+	err = json.Unmarshal(b, decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.Coordinates.([]any); !ok {
+		t.Errorf("expected coordinates decoded as []any, got %T", decoded.Coordinates)
+	}
+	mp, err = decoded.MultiPolygon()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(mp, GeoJSONMultiPolygon{testSquare}) {
+		t.Errorf("unexpected multipolygon %v", mp)
+	}
+	plain := &GeoJSONGeometry{Type: "MultiPolygon", Coordinates: [][][][]float64{
+		{{{17.10, 48.14}, {17.12, 48.14}, {17.12, 48.15}, {17.10, 48.15}, {17.10, 48.14}}},
+	}}
+	mp, err = plain.MultiPolygon()
+	if err != nil || !reflect.DeepEqual(mp, GeoJSONMultiPolygon{testSquare}) {
+		t.Errorf("unexpected multipolygon of plain slices %v, %v", mp, err)
+	}
+	p, err := NewGeoJSONGeometryPolygon(testSquare, nil).MultiPolygon()
+	if err != nil || !reflect.DeepEqual(p, GeoJSONMultiPolygon{testSquare}) {
+		t.Errorf("unexpected polygon as multipolygon %v, %v", p, err)
+	}
+	_, err = decoded.Point()
+	if !errors.Is(err, ErrInvalidGeometry) {
+		t.Errorf("expected ErrInvalidGeometry for Point of MultiPolygon, got %v", err)
+	}
+}
+
+func TestGeoJSONGeometryAltitude(t *testing.T) {
+	tests := []struct {
+		json  string
+		valid bool
+	}{
+		{`{"type":"Point","coordinates":[17.1,48.1]}`, true},
+		{`{"type":"Point","coordinates":[17.1,48.1,0]}`, true},
+		{`{"type":"Point","coordinates":[17.1,48.1,120.5]}`, false},
+		{`{"type":"Polygon","coordinates":[[[17.1,48.1,10],[17.2,48.1,10],[17.2,48.2,10],[17.1,48.1,10]]]}`, false},
+		{`{"type":"MultiPolygon","coordinates":[[[[17.1,48.1],[17.2,48.1],[17.2,48.2],[17.1,48.1,5]]]]}`, false},
+		{`{"type":"Point","coordinates":[17.1]}`, false},
+		{`{"type":"Point","coordinates":["17.1",48.1]}`, false},
+	}
+	for _, test := range tests {
+		g := &GeoJSONGeometry{}
+		err := json.Unmarshal([]byte(test.json), g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := json.Marshal(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != test.json {
+			t.Errorf("expected %s to round-trip, got %s", test.json, b)
+		}
+		err = g.Validate()
+		if test.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", test.json, err)
+		}
+		if !test.valid && !errors.Is(err, ErrInvalidGeometry) {
+			t.Errorf("%s: expected ErrInvalidGeometry, got %v", test.json, err)
+		}
+	}
+}
+
+func TestGeoJSONGeometryValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		polygon GeoJSONPolygon
+		valid   bool
+	}{
+		{"valid", testSquare, true},
+		{"clockwise", GeoJSONPolygon{{{17.10, 48.14}, {17.10, 48.15}, {17.12, 48.15}, {17.12, 48.14}, {17.10, 48.14}}}, false},
+		{"not closed", GeoJSONPolygon{{{17.10, 48.14}, {17.12, 48.14}, {17.12, 48.15}, {17.10, 48.15}}}, false},
+		{"self intersecting", GeoJSONPolygon{{{17.10, 48.14}, {17.12, 48.15}, {17.12, 48.14}, {17.10, 48.15}, {17.10, 48.14}}}, false},
+		{"degenerate", GeoJSONPolygon{{{17.10, 48.14}, {17.12, 48.14}, {17.10, 48.14}}}, false},
+	}
+	for _, test := range tests {
+		err := NewGeoJSONGeometryPolygon(test.polygon, nil).Validate()
+		if test.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if !test.valid && !errors.Is(err, ErrInvalidGeometry) {
+			t.Errorf("%s: expected ErrInvalidGeometry, got %v", test.name, err)
+		}
+	}
+	normalized, err := GeoJSONMultiPolygon{tests[1].polygon}.Normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := normalized.Validate(); err != nil {
+		t.Errorf("expected normalized polygon to be valid, got %v", err)
+	}
+}
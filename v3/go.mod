@@ -1,3 +1,7 @@
 module github.com/petoc/gbfs/v3
 
 go 1.23
+
+require github.com/petoc/gbfs/v2 v2.1.0
+
+replace github.com/petoc/gbfs/v2 => ../v2
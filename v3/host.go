@@ -0,0 +1,260 @@
+package gbfs
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrMissingSystems     = errors.New("missing systems")
+	ErrDuplicateSystemID  = errors.New("duplicate system id")
+	ErrHostAlreadyStarted = errors.New("host already started")
+)
+
+type (
+	// Host publishes multiple systems of one operator under single root
+	// directory and base URL. Feed handlers of all systems share limited
+	// number of concurrently running handlers.
+	Host struct {
+		Options     *HostOptions
+		mu          sync.Mutex
+		servers     []*Server
+		started     bool
+		limiter     chan struct{}
+		manifestMu  sync.Mutex
+		manifestRev *feedRevision
+	}
+	// HostOptions are shared by all systems. UpdateHandler and
+	// PublishHandler are called with nil server for operator manifest.
+	HostOptions struct {
+		RootDir               string
+		BaseURL               string
+		BasePath              string
+		Version               string
+		DefaultTTL            int
+		ManifestPath          string
+		MaxConcurrentHandlers int
+		Store                 FeedStore
+		Serialization         SerializationOptions
+		UpdateHandler         func(server *Server, feed Feed, path string, err error)
+		PublishHandler        func(server *Server, feed Feed, path string, result *PublishResult)
+	}
+)
+
+func NewHost(options HostOptions) (*Host, error) {
+	if options.Store == nil {
+		if options.RootDir == "" {
+			return nil, ErrMissingRootDir
+		}
+		options.Store = &FileFeedStore{
+			RootDir: options.RootDir,
+		}
+	}
+	if options.BaseURL == "" {
+		return nil, ErrMissingBaseURL
+	}
+	if options.DefaultTTL <= 0 {
+		return nil, ErrInvalidDefaultTTL
+	}
+	if options.Version == "" {
+		options.Version = V30
+	}
+	if options.ManifestPath == "" {
+		options.ManifestPath = "manifest.json"
+	}
+	if options.MaxConcurrentHandlers <= 0 {
+		options.MaxConcurrentHandlers = runtime.NumCPU()
+	}
+	if options.UpdateHandler == nil {
+		options.UpdateHandler = func(server *Server, feed Feed, path string, err error) {}
+	}
+	h := &Host{
+		Options: &options,
+		limiter: make(chan struct{}, options.MaxConcurrentHandlers),
+	}
+	return h, nil
+}
+
+// AddSystem registers new system. Options not set for system are inherited
+// from host, BasePath defaults to host BasePath followed by system id.
+// Systems added after Start are started immediately.
+func (h *Host) AddSystem(options ServerOptions) (*Server, error) {
+	if options.SystemID == "" {
+		return nil, ErrMissingSystemID
+	}
+	options.RootDir = h.Options.RootDir
+	options.Store = h.Options.Store
+	options.BaseURL = h.Options.BaseURL
+	options.ManifestPath = ""
+	if options.BasePath == "" {
+		options.BasePath = path.Join(strings.Trim(h.Options.BasePath, "/"), options.SystemID)
+	}
+	if options.Version == "" {
+		options.Version = h.Options.Version
+	}
+	if options.DefaultTTL <= 0 {
+		options.DefaultTTL = h.Options.DefaultTTL
+	}
+	if options.UpdateHandler == nil {
+		options.UpdateHandler = h.Options.UpdateHandler
+	}
+	if options.PublishHandler == nil {
+		options.PublishHandler = h.Options.PublishHandler
+	}
+	if options.Serialization.isZero() {
+		options.Serialization = h.Options.Serialization
+	}
+	s, err := NewServer(options)
+	if err != nil {
+		return nil, err
+	}
+	s.host = h
+	s.limiter = h.limiter
+	h.mu.Lock()
+	for _, existing := range h.servers {
+		if existing.Options.SystemID == options.SystemID {
+			h.mu.Unlock()
+			return nil, ErrDuplicateSystemID
+		}
+	}
+	h.servers = append(h.servers, s)
+	started := h.started
+	h.mu.Unlock()
+	if started {
+		go h.startServer(s)
+		h.writeManifestFeed()
+	}
+	return s, nil
+}
+
+// Server returns registered server of system.
+func (h *Host) Server(systemID string) *Server {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, s := range h.servers {
+		if s.Options.SystemID == systemID {
+			return s
+		}
+	}
+	return nil
+}
+
+// Servers returns all registered servers in order of registration.
+func (h *Host) Servers() []*Server {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]*Server{}, h.servers...)
+}
+
+// Start starts all registered systems and keeps operator manifest up to
+// date. It blocks until host TTL is zero.
+func (h *Host) Start() error {
+	h.mu.Lock()
+	if h.started {
+		h.mu.Unlock()
+		return ErrHostAlreadyStarted
+	}
+	if len(h.servers) == 0 {
+		h.mu.Unlock()
+		return ErrMissingSystems
+	}
+	h.started = true
+	servers := append([]*Server{}, h.servers...)
+	h.mu.Unlock()
+	for _, s := range servers {
+		go h.startServer(s)
+	}
+	for {
+		h.writeManifestFeed()
+		if h.Options.DefaultTTL == 0 {
+			break
+		}
+		time.Sleep(time.Duration(h.Options.DefaultTTL) * time.Second)
+	}
+	return nil
+}
+
+func (h *Host) startServer(s *Server) {
+	err := s.Start()
+	if err != nil {
+		s.Options.UpdateHandler(s, nil, "", NewError(s.Options.SystemID+": ", err))
+	}
+}
+
+func (h *Host) manifestPathSegments() []string {
+	return []string{strings.Trim(h.Options.ManifestPath, "/")}
+}
+
+func (h *Host) manifestURL() string {
+	return strings.Join(append([]string{strings.Trim(h.Options.BaseURL, "/")}, h.manifestPathSegments()...), "/")
+}
+
+func (h *Host) manifestFeed() *FeedManifest {
+	datasets := []*FeedManifestDataset{}
+	for _, s := range h.Servers() {
+		datasets = append(datasets, &FeedManifestDataset{
+			SystemID: NewString(s.Options.SystemID),
+			Versions: manifestDatasetVersions(s.gbfsVersions()),
+		})
+	}
+	feed := &FeedManifest{
+		Data: &FeedManifestData{
+			Datasets: datasets,
+		},
+	}
+	feed.SetTTL(h.Options.DefaultTTL)
+	feed.SetVersion(h.Options.Version)
+	return feed
+}
+
+// writeManifestFeed writes operator manifest.json covering all systems,
+// when its content changed. Unchanged manifest keeps its last_updated.
+func (h *Host) writeManifestFeed() {
+	h.manifestMu.Lock()
+	feed := h.manifestFeed()
+	path := strings.Join(h.manifestPathSegments(), "/")
+	rev, changed, err := stampRevision(h.manifestRev, feed, nowLastUpdated())
+	if err == nil && changed {
+		err = putEncodedFeed(h.Options.Store.Put, path, feed, h.Options.Serialization)
+	}
+	if err == nil && changed {
+		h.manifestRev = rev
+	}
+	h.manifestMu.Unlock()
+	if err != nil || changed {
+		h.Options.UpdateHandler(nil, feed, path, err)
+	}
+	if err == nil && h.Options.PublishHandler != nil {
+		h.Options.PublishHandler(nil, feed, path, &PublishResult{
+			Published: changed,
+		})
+	}
+}
+
+// Handler returns HTTP handler serving published feeds of all systems.
+// Stores implementing http.Handler serve feeds themselves.
+func (h *Host) Handler() http.Handler {
+	if handler, ok := h.Options.Store.(http.Handler); ok {
+		return handler
+	}
+	return NewFileHandler(h.Options.RootDir)
+}
+
+// NewFileServer returns HTTP server serving feeds of all systems on addr.
+func (h *Host) NewFileServer(addr string) (*http.Server, error) {
+	if addr == "" {
+		return nil, ErrMissingServerAddress
+	}
+	s := &http.Server{
+		Addr:         addr,
+		Handler:      h.Handler(),
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	}
+	return s, nil
+}
@@ -0,0 +1,62 @@
+package gbfs
+
+import "testing"
+
+func TestHostManifestWrittenOnlyWhenChanged(t *testing.T) {
+	store := NewMemoryFeedStore()
+	errs := []error{}
+	published := []bool{}
+	h, err := NewHost(HostOptions{
+		Store:      store,
+		BaseURL:    "http://127.0.0.1:8080",
+		BasePath:   "v3",
+		DefaultTTL: 60,
+		Serialization: SerializationOptions{
+			Encoders: []*FeedEncoder{NewGzipEncoder(-1)},
+		},
+		UpdateHandler: func(server *Server, feed Feed, path string, err error) {
+			if server == nil {
+				errs = append(errs, err)
+			}
+		},
+		PublishHandler: func(server *Server, feed Feed, path string, result *PublishResult) {
+			if server == nil {
+				published = append(published, result.Published)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := h.AddSystem(ServerOptions{SystemID: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Options.Serialization.Encoders) != 1 {
+		t.Fatal("expected system to inherit serialization of host")
+	}
+	h.writeManifestFeed()
+	first, err := store.Get("manifest.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("manifest.json.gz"); err != nil {
+		t.Fatal("expected precompressed manifest sibling:", err)
+	}
+	h.writeManifestFeed()
+	if len(errs) != 1 || len(published) != 2 || published[1] {
+		t.Fatalf("expected unchanged manifest to be reported only as not published, got %v %v", errs, published)
+	}
+	second, _ := store.Get("manifest.json")
+	if string(first) != string(second) {
+		t.Fatal("unchanged manifest was rewritten")
+	}
+	_, err = h.AddSystem(ServerOptions{SystemID: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.writeManifestFeed()
+	if len(errs) != 2 || errs[1] != nil || !published[2] {
+		t.Fatalf("expected changed manifest to be written, got %v %v", errs, published)
+	}
+}
@@ -0,0 +1,237 @@
+package mvt
+
+import (
+	"math"
+	"sort"
+)
+
+// Wire types and field numbers of vector_tile.proto version 2.1.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+
+	tileLayers = 3
+
+	layerName     = 1
+	layerFeatures = 2
+	layerKeys     = 3
+	layerValues   = 4
+	layerExtent   = 5
+	layerVersion  = 15
+
+	featureTags     = 2
+	featureType     = 3
+	featureGeometry = 4
+
+	valueString = 1
+	valueDouble = 3
+	valueInt    = 4
+	valueBool   = 7
+
+	commandMoveTo    = 1
+	commandLineTo    = 2
+	commandClosePath = 7
+)
+
+type geometryType int
+
+const (
+	geometryPoint   geometryType = 1
+	geometryPolygon geometryType = 3
+)
+
+type (
+	layer struct {
+		name     string
+		features []*feature
+	}
+	// feature has geometry in tile coordinates. Point feature has single
+	// ring with all points, polygon feature has closed rings without
+	// repeated last position, exterior rings followed by their holes.
+	feature struct {
+		geometryType geometryType
+		rings        [][][2]int
+		properties   map[string]any
+	}
+	// buffer is protobuf message being encoded.
+	buffer []byte
+)
+
+func (b *buffer) varint(v uint64) {
+	for v >= 0x80 {
+		*b = append(*b, byte(v)|0x80)
+		v >>= 7
+	}
+	*b = append(*b, byte(v))
+}
+
+func (b *buffer) key(field, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *buffer) bytes(field int, v []byte) {
+	b.key(field, wireBytes)
+	b.varint(uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+func (b *buffer) uint(field int, v uint64) {
+	b.key(field, wireVarint)
+	b.varint(v)
+}
+
+func (b *buffer) double(field int, v float64) {
+	b.key(field, wireFixed64)
+	bits := math.Float64bits(v)
+	for i := 0; i < 8; i++ {
+		*b = append(*b, byte(bits>>(8*i)))
+	}
+}
+
+func (b *buffer) packed(field int, values []uint32) {
+	p := buffer{}
+	for _, v := range values {
+		p.varint(uint64(v))
+	}
+	b.bytes(field, p)
+}
+
+func zigzag(v int) uint32 {
+	n := int32(v)
+	return uint32((n << 1) ^ (n >> 31))
+}
+
+func command(id, count int) uint32 {
+	return uint32(id&0x7) | uint32(count)<<3
+}
+
+// encodeTile encodes layers with features, empty layers are left out.
+func encodeTile(layers []*layer, extent int) []byte {
+	tile := buffer{}
+	for _, l := range layers {
+		if len(l.features) > 0 {
+			tile.bytes(tileLayers, l.encode(extent))
+		}
+	}
+	return tile
+}
+
+func (l *layer) encode(extent int) []byte {
+	b := buffer{}
+	b.uint(layerVersion, 2)
+	b.bytes(layerName, []byte(l.name))
+	keys := map[string]int{}
+	values := map[any]int{}
+	keyList := []string{}
+	valueList := []any{}
+	for _, f := range l.features {
+		tags := []uint32{}
+		for _, k := range sortedKeys(f.properties) {
+			v := propertyValue(f.properties[k])
+			if v == nil {
+				continue
+			}
+			ki, ok := keys[k]
+			if !ok {
+				ki = len(keyList)
+				keys[k] = ki
+				keyList = append(keyList, k)
+			}
+			vi, ok := values[v]
+			if !ok {
+				vi = len(valueList)
+				values[v] = vi
+				valueList = append(valueList, v)
+			}
+			tags = append(tags, uint32(ki), uint32(vi))
+		}
+		fb := buffer{}
+		if len(tags) > 0 {
+			fb.packed(featureTags, tags)
+		}
+		fb.uint(featureType, uint64(f.geometryType))
+		fb.packed(featureGeometry, f.encodeGeometry())
+		b.bytes(layerFeatures, fb)
+	}
+	for _, k := range keyList {
+		b.bytes(layerKeys, []byte(k))
+	}
+	for _, v := range valueList {
+		vb := buffer{}
+		switch v := v.(type) {
+		case string:
+			vb.bytes(valueString, []byte(v))
+		case int64:
+			vb.uint(valueInt, uint64(v))
+		case float64:
+			vb.double(valueDouble, v)
+		case bool:
+			n := uint64(0)
+			if v {
+				n = 1
+			}
+			vb.uint(valueBool, n)
+		}
+		b.bytes(layerValues, vb)
+	}
+	b.uint(layerExtent, uint64(extent))
+	return b
+}
+
+// encodeGeometry encodes rings to commands with zigzag encoded deltas.
+func (f *feature) encodeGeometry() []uint32 {
+	commands := []uint32{}
+	var cursor [2]int
+	moveTo := func(p [2]int) {
+		commands = append(commands, zigzag(p[0]-cursor[0]), zigzag(p[1]-cursor[1]))
+		cursor = p
+	}
+	for _, ring := range f.rings {
+		if f.geometryType == geometryPoint {
+			commands = append(commands, command(commandMoveTo, len(ring)))
+			for _, p := range ring {
+				moveTo(p)
+			}
+			continue
+		}
+		commands = append(commands, command(commandMoveTo, 1))
+		moveTo(ring[0])
+		commands = append(commands, command(commandLineTo, len(ring)-1))
+		for _, p := range ring[1:] {
+			moveTo(p)
+		}
+		commands = append(commands, command(commandClosePath, 1))
+	}
+	return commands
+}
+
+// propertyValue converts property to comparable value of supported type,
+// or nil, when property has no value.
+func propertyValue(v any) any {
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return v
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return v
+	case bool:
+		return v
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,165 @@
+package mvt
+
+import (
+	"math"
+
+	gbfs "github.com/petoc/gbfs/v3"
+)
+
+// maxLatitude is latitude bound of Web Mercator projection.
+const maxLatitude = 85.05112878
+
+// Bounds returns longitude and latitude bounds of tile as
+// [west, south, east, north].
+func (t Tile) Bounds() gbfs.GeoJSONBBox {
+	n := math.Exp2(float64(t.Z))
+	return gbfs.GeoJSONBBox{
+		float64(t.X)/n*360 - 180,
+		tileLatitude(float64(t.Y+1) / n),
+		float64(t.X+1)/n*360 - 180,
+		tileLatitude(float64(t.Y) / n),
+	}
+}
+
+func tileLatitude(y float64) float64 {
+	return math.Atan(math.Sinh(math.Pi*(1-2*y))) * 180 / math.Pi
+}
+
+// projection converts longitude and latitude to coordinates of tile with
+// given extent, y axis points down.
+type projection struct {
+	tile   Tile
+	n      float64
+	extent float64
+}
+
+func newProjection(tile Tile, extent int) *projection {
+	return &projection{
+		tile:   tile,
+		n:      math.Exp2(float64(tile.Z)),
+		extent: float64(extent),
+	}
+}
+
+func (p *projection) project(lon, lat float64) [2]float64 {
+	lat = math.Max(-maxLatitude, math.Min(maxLatitude, lat))
+	sin := math.Sin(lat * math.Pi / 180)
+	x := (lon + 180) / 360 * p.n
+	y := (0.5 - math.Log((1+sin)/(1-sin))/(4*math.Pi)) * p.n
+	return [2]float64{
+		(x - float64(p.tile.X)) * p.extent,
+		(y - float64(p.tile.Y)) * p.extent,
+	}
+}
+
+// point returns point in tile coordinates, or false, when point is
+// outside of tile extended by buffer.
+func (p *projection) point(lon, lat float64, buffer int) ([2]int, bool) {
+	q := p.project(lon, lat)
+	min, max := -float64(buffer), p.extent+float64(buffer)
+	if q[0] < min || q[0] > max || q[1] < min || q[1] > max {
+		return [2]int{}, false
+	}
+	return [2]int{int(math.Round(q[0])), int(math.Round(q[1]))}, true
+}
+
+// polygons returns rings of multipolygon clipped to tile extended by
+// buffer. Exterior rings have positive area in tile coordinates, holes
+// negative, as required by specification. Rings collapsed by clipping
+// or rounding are left out together with holes of collapsed exterior.
+func (p *projection) polygons(mp gbfs.GeoJSONMultiPolygon, buffer int) [][][2]int {
+	rings := [][][2]int{}
+	min, max := -float64(buffer), p.extent+float64(buffer)
+	for _, polygon := range mp {
+		for i, ring := range polygon {
+			projected := make([][2]float64, 0, len(ring))
+			for _, pos := range ring {
+				projected = append(projected, p.project(pos[0], pos[1]))
+			}
+			clipped := tileRing(clipRing(projected, min, max))
+			if len(clipped) == 0 {
+				if i == 0 {
+					break
+				}
+				continue
+			}
+			if (ringArea(clipped) > 0) != (i == 0) {
+				reverse(clipped)
+			}
+			rings = append(rings, clipped)
+		}
+	}
+	return rings
+}
+
+// clipRing clips ring to square by Sutherland-Hodgman algorithm.
+func clipRing(ring [][2]float64, min, max float64) [][2]float64 {
+	edges := []struct {
+		axis   int
+		value  float64
+		inside func(v, limit float64) bool
+	}{
+		{0, min, func(v, limit float64) bool { return v >= limit }},
+		{0, max, func(v, limit float64) bool { return v <= limit }},
+		{1, min, func(v, limit float64) bool { return v >= limit }},
+		{1, max, func(v, limit float64) bool { return v <= limit }},
+	}
+	for _, e := range edges {
+		if len(ring) == 0 {
+			break
+		}
+		out := make([][2]float64, 0, len(ring))
+		prev := ring[len(ring)-1]
+		for _, cur := range ring {
+			curIn, prevIn := e.inside(cur[e.axis], e.value), e.inside(prev[e.axis], e.value)
+			if curIn != prevIn {
+				t := (e.value - prev[e.axis]) / (cur[e.axis] - prev[e.axis])
+				var q [2]float64
+				q[e.axis] = e.value
+				q[1-e.axis] = prev[1-e.axis] + t*(cur[1-e.axis]-prev[1-e.axis])
+				out = append(out, q)
+			}
+			if curIn {
+				out = append(out, cur)
+			}
+			prev = cur
+		}
+		ring = out
+	}
+	return ring
+}
+
+// tileRing rounds ring to integer coordinates, removes repeated and
+// closing positions and returns nil, when ring has no area.
+func tileRing(ring [][2]float64) [][2]int {
+	out := make([][2]int, 0, len(ring))
+	for _, pos := range ring {
+		q := [2]int{int(math.Round(pos[0])), int(math.Round(pos[1]))}
+		if len(out) == 0 || out[len(out)-1] != q {
+			out = append(out, q)
+		}
+	}
+	for len(out) > 1 && out[0] == out[len(out)-1] {
+		out = out[:len(out)-1]
+	}
+	if len(out) < 3 || ringArea(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// ringArea returns doubled area of ring by surveyor's formula.
+func ringArea(ring [][2]int) int {
+	area := 0
+	for i := range ring {
+		j := (i + 1) % len(ring)
+		area += ring[i][0]*ring[j][1] - ring[j][0]*ring[i][1]
+	}
+	return area
+}
+
+func reverse(ring [][2]int) {
+	for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+		ring[i], ring[j] = ring[j], ring[i]
+	}
+}
@@ -0,0 +1,119 @@
+package mvt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	gbfs "github.com/petoc/gbfs/v3"
+)
+
+const ContentType = "application/vnd.mapbox-vector-tile"
+
+// tileFeeds are feeds encoded to tiles.
+var tileFeeds = []string{
+	gbfs.FeedNameGeofencingZones,
+	gbfs.FeedNameStationInformation,
+	gbfs.FeedNameStationStatus,
+	gbfs.FeedNameVehicleStatus,
+}
+
+type (
+	// FeedSource provides last published feeds keyed by feed name, it is
+	// implemented by gbfs.Server.
+	FeedSource interface {
+		Feeds() map[string]gbfs.Feed
+	}
+	handler struct {
+		source  FeedSource
+		options Options
+	}
+)
+
+// NewHandler returns handler serving tiles encoded from last published
+// feeds of source on paths ending with z/x/y.mvt (or .pbf). Tiles are
+// gzip compressed, when client accepts it, Last-Modified is last update of
+// encoded feeds and Cache-Control max-age their lowest TTL. Tile without
+// features is served with status 204.
+func NewHandler(source FeedSource, options Options) http.Handler {
+	return &handler{
+		source:  source,
+		options: options,
+	}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	tile, err := ParseTile(r.URL.Path)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	feeds := h.source.Feeds()
+	b, err := Encode(feeds, tile, h.options)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	modified, ttl := time.Time{}, -1
+	for _, name := range tileFeeds {
+		feed, ok := feeds[name]
+		if !ok {
+			continue
+		}
+		t, err := feed.GetLastUpdated().Time()
+		if err == nil && t.After(modified) {
+			modified = t
+		}
+		if ttl < 0 || feed.GetTTL() < ttl {
+			ttl = feed.GetTTL()
+		}
+	}
+	if ttl >= 0 {
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(ttl))
+	}
+	if len(b) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Add("Vary", "Accept-Encoding")
+	if acceptsGzip(r) {
+		buf := &bytes.Buffer{}
+		zw := gzip.NewWriter(buf)
+		_, err = zw.Write(b)
+		if err == nil {
+			err = zw.Close()
+		}
+		if err == nil {
+			b = buf.Bytes()
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+	}
+	http.ServeContent(w, r, tile.String()+".mvt", modified, bytes.NewReader(b))
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(part, ";")
+		if strings.ToLower(strings.TrimSpace(params[0])) != "gzip" {
+			continue
+		}
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(param[2:], 64)
+				return err != nil || q > 0
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,341 @@
+// Package mvt encodes geofencing zones, stations and vehicles of GBFS feeds
+// to Mapbox Vector Tiles.
+package mvt
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	gbfs "github.com/petoc/gbfs/v3"
+)
+
+const (
+	LayerGeofencingZones = "geofencing_zones"
+	LayerStations        = "stations"
+	LayerVehicles        = "vehicles"
+)
+
+var ErrInvalidTile = errors.New("invalid tile")
+
+type (
+	// Tile is address of tile in XYZ scheme.
+	Tile struct {
+		Z, X, Y int
+	}
+	// Options of encoding. Extent defaults to 4096 and Buffer around tile,
+	// in which geometries are kept, to 64. MinZoom limits zoom levels of
+	// layers, tiles above MaxZoom (default 22) are rejected.
+	Options struct {
+		Extent  int
+		Buffer  int
+		MaxZoom int
+		MinZoom map[string]int
+	}
+)
+
+func (o *Options) defaults() Options {
+	v := *o
+	if v.Extent <= 0 {
+		v.Extent = 4096
+	}
+	if v.Buffer < 0 {
+		v.Buffer = 0
+	} else if v.Buffer == 0 {
+		v.Buffer = 64
+	}
+	if v.MaxZoom <= 0 {
+		v.MaxZoom = 22
+	}
+	return v
+}
+
+// ParseTile parses tile from path ending with z/x/y and optional
+// extension (.mvt, .pbf).
+func ParseTile(path string) (Tile, error) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) < 3 {
+		return Tile{}, gbfs.NewError(path+": ", ErrInvalidTile)
+	}
+	segments = segments[len(segments)-3:]
+	last := segments[2]
+	if i := strings.IndexByte(last, '.'); i >= 0 {
+		switch last[i:] {
+		case ".mvt", ".pbf":
+		default:
+			return Tile{}, gbfs.NewError(path+": ", ErrInvalidTile)
+		}
+		segments[2] = last[:i]
+	}
+	v := [3]int{}
+	for i, s := range segments {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return Tile{}, gbfs.NewError(path+": ", ErrInvalidTile)
+		}
+		v[i] = n
+	}
+	tile := Tile{Z: v[0], X: v[1], Y: v[2]}
+	if !tile.Valid() {
+		return Tile{}, gbfs.NewError(path+": ", ErrInvalidTile)
+	}
+	return tile, nil
+}
+
+// Valid reports whether tile exists at its zoom level.
+func (t Tile) Valid() bool {
+	if t.Z < 0 || t.Z > 30 {
+		return false
+	}
+	n := 1 << t.Z
+	return t.X >= 0 && t.X < n && t.Y >= 0 && t.Y < n
+}
+
+func (t Tile) String() string {
+	return strconv.Itoa(t.Z) + "/" + strconv.Itoa(t.X) + "/" + strconv.Itoa(t.Y)
+}
+
+// Encode encodes tile with layers geofencing_zones (from
+// geofencing_zones), stations (from station_information joined with
+// station_status) and vehicles (from vehicle_status). Feeds are keyed by
+// feed name, as returned by Server.Feeds. Layers without features are
+// left out, tile without layers is empty.
+func Encode(feeds map[string]gbfs.Feed, tile Tile, options Options) ([]byte, error) {
+	o := options.defaults()
+	if !tile.Valid() || tile.Z > o.MaxZoom {
+		return nil, gbfs.NewError(tile.String()+": ", ErrInvalidTile)
+	}
+	p := newProjection(tile, o.Extent)
+	layers := []*layer{}
+	if tile.Z >= o.MinZoom[LayerGeofencingZones] {
+		if f, ok := feeds[gbfs.FeedNameGeofencingZones].(*gbfs.FeedGeofencingZones); ok {
+			layers = append(layers, geofencingZonesLayer(f, p, tile, o.Buffer))
+		}
+	}
+	if tile.Z >= o.MinZoom[LayerStations] {
+		if f, ok := feeds[gbfs.FeedNameStationInformation].(*gbfs.FeedStationInformation); ok {
+			status, _ := feeds[gbfs.FeedNameStationStatus].(*gbfs.FeedStationStatus)
+			layers = append(layers, stationsLayer(f, status, p, o.Buffer))
+		}
+	}
+	if tile.Z >= o.MinZoom[LayerVehicles] {
+		if f, ok := feeds[gbfs.FeedNameVehicleStatus].(*gbfs.FeedVehicleStatus); ok {
+			layers = append(layers, vehiclesLayer(f, p, o.Buffer))
+		}
+	}
+	return encodeTile(layers, o.Extent), nil
+}
+
+// geofencingZonesLayer has polygon of every zone intersecting tile. First
+// rule of zone is flattened to properties, all rules are in rules as JSON.
+func geofencingZonesLayer(feed *gbfs.FeedGeofencingZones, p *projection, tile Tile, buffer int) *layer {
+	l := &layer{name: LayerGeofencingZones}
+	if feed.Data == nil || feed.Data.GeofencingZones == nil {
+		return l
+	}
+	bounds := bufferedBounds(tile, buffer, p)
+	for _, zone := range feed.Data.GeofencingZones.Features {
+		if zone.Geometry == nil {
+			continue
+		}
+		mp, err := zone.Geometry.MultiPolygon()
+		if err != nil || len(mp) == 0 || !intersects(mp.BBox(), bounds) {
+			continue
+		}
+		rings := p.polygons(mp, buffer)
+		if len(rings) == 0 {
+			continue
+		}
+		properties := map[string]any{}
+		if zone.Properties != nil {
+			localizedProperties(properties, "name", zone.Properties.Name)
+			properties["start"] = timestamp(zone.Properties.Start)
+			properties["end"] = timestamp(zone.Properties.End)
+			if len(zone.Properties.Rules) > 0 {
+				rule := zone.Properties.Rules[0]
+				properties["ride_start_allowed"] = boolean(rule.RideStartAllowed)
+				properties["ride_end_allowed"] = boolean(rule.RideEndAllowed)
+				properties["ride_through_allowed"] = boolean(rule.RideThroughAllowed)
+				properties["station_parking"] = boolean(rule.StationParking)
+				properties["maximum_speed_kph"] = integer(rule.MaximumSpeedKph)
+				properties["vehicle_type_ids"] = ids(rule.VehicleTypeIDs)
+				b, err := json.Marshal(zone.Properties.Rules)
+				if err == nil {
+					properties["rules"] = string(b)
+				}
+			}
+		}
+		l.features = append(l.features, &feature{
+			geometryType: geometryPolygon,
+			rings:        rings,
+			properties:   properties,
+		})
+	}
+	return l
+}
+
+// stationsLayer has point of every station in tile with status of station,
+// when station_status is available.
+func stationsLayer(feed *gbfs.FeedStationInformation, status *gbfs.FeedStationStatus, p *projection, buffer int) *layer {
+	l := &layer{name: LayerStations}
+	if feed.Data == nil {
+		return l
+	}
+	statuses := map[gbfs.ID]*gbfs.FeedStationStatusStation{}
+	if status != nil && status.Data != nil {
+		for _, s := range status.Data.Stations {
+			if s.StationID != nil {
+				statuses[*s.StationID] = s
+			}
+		}
+	}
+	for _, station := range feed.Data.Stations {
+		if station.StationID == nil || station.Lat == nil || station.Lon == nil {
+			continue
+		}
+		point, ok := p.point(station.Lon.Float64, station.Lat.Float64, buffer)
+		if !ok {
+			continue
+		}
+		properties := map[string]any{
+			"station_id":          string(*station.StationID),
+			"region_id":           id(station.RegionID),
+			"address":             str(station.Address),
+			"parking_type":        str(station.ParkingType),
+			"capacity":            integer(station.Capacity),
+			"is_virtual_station":  boolean(station.IsVirtualStation),
+			"is_charging_station": boolean(station.IsChargingStation),
+		}
+		localizedProperties(properties, "name", station.Name)
+		localizedProperties(properties, "short_name", station.ShortName)
+		if s := statuses[*station.StationID]; s != nil {
+			properties["num_vehicles_available"] = integer(s.NumVehiclesAvailable)
+			properties["num_vehicles_disabled"] = integer(s.NumVehiclesDisabled)
+			properties["num_docks_available"] = integer(s.NumDocksAvailable)
+			properties["num_docks_disabled"] = integer(s.NumDocksDisabled)
+			properties["is_installed"] = boolean(s.IsInstalled)
+			properties["is_renting"] = boolean(s.IsRenting)
+			properties["is_returning"] = boolean(s.IsReturning)
+			properties["last_reported"] = timestamp(s.LastReported)
+		}
+		l.features = append(l.features, &feature{
+			geometryType: geometryPoint,
+			rings:        [][][2]int{{point}},
+			properties:   properties,
+		})
+	}
+	return l
+}
+
+// vehiclesLayer has point of every vehicle in tile. Vehicles at stations
+// without position are left out.
+func vehiclesLayer(feed *gbfs.FeedVehicleStatus, p *projection, buffer int) *layer {
+	l := &layer{name: LayerVehicles}
+	if feed.Data == nil {
+		return l
+	}
+	for _, vehicle := range feed.Data.Vehicles {
+		if vehicle.VehicleID == nil || vehicle.Lat == nil || vehicle.Lon == nil {
+			continue
+		}
+		point, ok := p.point(vehicle.Lon.Float64, vehicle.Lat.Float64, buffer)
+		if !ok {
+			continue
+		}
+		properties := map[string]any{
+			"vehicle_id":           string(*vehicle.VehicleID),
+			"vehicle_type_id":      id(vehicle.VehicleTypeID),
+			"station_id":           id(vehicle.StationID),
+			"pricing_plan_id":      id(vehicle.PricingPlanID),
+			"is_reserved":          boolean(vehicle.IsReserved),
+			"is_disabled":          boolean(vehicle.IsDisabled),
+			"current_range_meters": float(vehicle.CurrentRangeMeters),
+			"current_fuel_percent": float(vehicle.CurrentFuelPercent),
+			"last_reported":        timestamp(vehicle.LastReported),
+		}
+		l.features = append(l.features, &feature{
+			geometryType: geometryPoint,
+			rings:        [][][2]int{{point}},
+			properties:   properties,
+		})
+	}
+	return l
+}
+
+// bufferedBounds returns bounds of tile extended by buffer.
+func bufferedBounds(tile Tile, buffer int, p *projection) gbfs.GeoJSONBBox {
+	b := tile.Bounds()
+	dx := (b[2] - b[0]) * float64(buffer) / p.extent
+	dy := (b[3] - b[1]) * float64(buffer) / p.extent
+	return gbfs.GeoJSONBBox{b[0] - dx, b[1] - dy, b[2] + dx, b[3] + dy}
+}
+
+func intersects(a, b gbfs.GeoJSONBBox) bool {
+	return a[0] <= b[2] && b[0] <= a[2] && a[1] <= b[3] && b[1] <= a[3]
+}
+
+// localizedProperties sets property to text of first language and
+// property:language to text of every language.
+func localizedProperties(properties map[string]any, name string, values []*gbfs.LocalizedString) {
+	for i, v := range values {
+		if i == 0 {
+			properties[name] = v.Text
+		}
+		if v.Language != "" {
+			properties[name+":"+v.Language] = v.Text
+		}
+	}
+}
+
+func boolean(v *gbfs.Boolean) any {
+	if v == nil {
+		return nil
+	}
+	return bool(*v)
+}
+
+func integer(v *int64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func float(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func str(v *string) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func id(v *gbfs.ID) any {
+	if v == nil {
+		return nil
+	}
+	return string(*v)
+}
+
+func timestamp(v *gbfs.Timestamp) any {
+	if v == nil {
+		return nil
+	}
+	return string(*v)
+}
+
+func ids(v []*gbfs.ID) any {
+	s := []string{}
+	for _, id := range v {
+		if id != nil {
+			s = append(s, string(*id))
+		}
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,458 @@
+package mvt
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	gbfs "github.com/petoc/gbfs/v3"
+)
+
+type (
+	testLayer struct {
+		name     string
+		version  uint64
+		extent   uint64
+		keys     []string
+		values   []any
+		features []*testFeature
+	}
+	testFeature struct {
+		geometryType uint64
+		tags         []uint64
+		geometry     []uint64
+	}
+)
+
+// testFields decodes protobuf message to fields, varints and fixed64 are
+// returned as uint64, length delimited fields as []byte.
+func testFields(t *testing.T, b []byte) [][2]any {
+	t.Helper()
+	fields := [][2]any{}
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatal("invalid key")
+		}
+		b = b[n:]
+		field, wireType := int(key>>3), key&0x7
+		switch wireType {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				t.Fatal("invalid varint")
+			}
+			b = b[n:]
+			fields = append(fields, [2]any{field, v})
+		case wireFixed64:
+			fields = append(fields, [2]any{field, binary.LittleEndian.Uint64(b[:8])})
+			b = b[8:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || int(l) > len(b)-n {
+				t.Fatal("invalid length")
+			}
+			fields = append(fields, [2]any{field, b[n : n+int(l)]})
+			b = b[n+int(l):]
+		default:
+			t.Fatalf("unexpected wire type %d", wireType)
+		}
+	}
+	return fields
+}
+
+func testPacked(t *testing.T, b []byte) []uint64 {
+	t.Helper()
+	values := []uint64{}
+	for len(b) > 0 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatal("invalid packed varint")
+		}
+		values = append(values, v)
+		b = b[n:]
+	}
+	return values
+}
+
+func testDecode(t *testing.T, b []byte) []*testLayer {
+	t.Helper()
+	layers := []*testLayer{}
+	for _, f := range testFields(t, b) {
+		if f[0] != tileLayers {
+			t.Fatalf("unexpected tile field %v", f[0])
+		}
+		l := &testLayer{}
+		for _, lf := range testFields(t, f[1].([]byte)) {
+			switch lf[0] {
+			case layerName:
+				l.name = string(lf[1].([]byte))
+			case layerVersion:
+				l.version = lf[1].(uint64)
+			case layerExtent:
+				l.extent = lf[1].(uint64)
+			case layerKeys:
+				l.keys = append(l.keys, string(lf[1].([]byte)))
+			case layerValues:
+				vf := testFields(t, lf[1].([]byte))
+				if len(vf) != 1 {
+					t.Fatalf("expected single value, got %d", len(vf))
+				}
+				switch vf[0][0] {
+				case valueString:
+					l.values = append(l.values, string(vf[0][1].([]byte)))
+				case valueDouble:
+					l.values = append(l.values, math.Float64frombits(vf[0][1].(uint64)))
+				case valueInt:
+					l.values = append(l.values, int64(vf[0][1].(uint64)))
+				case valueBool:
+					l.values = append(l.values, vf[0][1].(uint64) == 1)
+				default:
+					t.Fatalf("unexpected value field %v", vf[0][0])
+				}
+			case layerFeatures:
+				feature := &testFeature{}
+				for _, ff := range testFields(t, lf[1].([]byte)) {
+					switch ff[0] {
+					case featureType:
+						feature.geometryType = ff[1].(uint64)
+					case featureTags:
+						feature.tags = testPacked(t, ff[1].([]byte))
+					case featureGeometry:
+						feature.geometry = testPacked(t, ff[1].([]byte))
+					}
+				}
+				l.features = append(l.features, feature)
+			}
+		}
+		layers = append(layers, l)
+	}
+	return layers
+}
+
+func (f *testFeature) properties(t *testing.T, l *testLayer) map[string]any {
+	t.Helper()
+	if len(f.tags)%2 != 0 {
+		t.Fatal("odd number of tags")
+	}
+	properties := map[string]any{}
+	for i := 0; i < len(f.tags); i += 2 {
+		if int(f.tags[i]) >= len(l.keys) || int(f.tags[i+1]) >= len(l.values) {
+			t.Fatal("tag out of range")
+		}
+		properties[l.keys[f.tags[i]]] = l.values[f.tags[i+1]]
+	}
+	return properties
+}
+
+// rings decodes geometry commands to rings of absolute coordinates and
+// checks, that commands follow specification for geometry type.
+func (f *testFeature) rings(t *testing.T) [][][2]int {
+	t.Helper()
+	unzigzag := func(v uint64) int {
+		return int(int32(uint32(v)>>1) ^ -int32(uint32(v)&1))
+	}
+	rings := [][][2]int{}
+	cursor := [2]int{}
+	g := f.geometry
+	next := func(expected int) int {
+		if len(g) == 0 {
+			t.Fatal("unexpected end of geometry")
+		}
+		id, count := int(g[0]&0x7), int(g[0]>>3)
+		if id != expected {
+			t.Fatalf("expected command %d, got %d", expected, id)
+		}
+		g = g[1:]
+		return count
+	}
+	positions := func(count int) [][2]int {
+		if len(g) < 2*count {
+			t.Fatal("missing parameters")
+		}
+		ring := [][2]int{}
+		for i := 0; i < count; i++ {
+			cursor = [2]int{cursor[0] + unzigzag(g[2*i]), cursor[1] + unzigzag(g[2*i+1])}
+			ring = append(ring, cursor)
+		}
+		g = g[2*count:]
+		return ring
+	}
+	for len(g) > 0 {
+		switch f.geometryType {
+		case uint64(geometryPoint):
+			rings = append(rings, positions(next(commandMoveTo)))
+		case uint64(geometryPolygon):
+			if next(commandMoveTo) != 1 {
+				t.Fatal("expected MoveTo with count 1")
+			}
+			ring := positions(1)
+			count := next(commandLineTo)
+			if count < 2 {
+				t.Fatalf("expected LineTo with count at least 2, got %d", count)
+			}
+			ring = append(ring, positions(count)...)
+			if next(commandClosePath) != 1 {
+				t.Fatal("expected ClosePath with count 1")
+			}
+			rings = append(rings, ring)
+		default:
+			t.Fatalf("unexpected geometry type %d", f.geometryType)
+		}
+	}
+	return rings
+}
+
+func testTile(lon, lat float64, z int) Tile {
+	q := newProjection(Tile{Z: z}, 1).project(lon, lat)
+	return Tile{Z: z, X: int(q[0]), Y: int(q[1])}
+}
+
+func testSquare(west, south, east, north float64) [][2]float64 {
+	return [][2]float64{{west, south}, {east, south}, {east, north}, {west, north}, {west, south}}
+}
+
+func testZones(geometries ...*gbfs.GeoJSONGeometry) *gbfs.FeedGeofencingZones {
+	features := []*gbfs.FeedGeofencingZonesGeoJSONFeature{}
+	for _, g := range geometries {
+		features = append(features, gbfs.NewFeedGeofencingZonesGeoJSONFeature(g, &gbfs.FeedGeofencingZonesGeoJSONFeatureProperties{
+			Name: []*gbfs.LocalizedString{gbfs.NewLocalizedString("Zone", "en")},
+			Rules: []*gbfs.FeedGeofencingZonesRule{
+				{RideEndAllowed: gbfs.NewBoolean(false), MaximumSpeedKph: gbfs.NewInt64(10)},
+			},
+		}))
+	}
+	return &gbfs.FeedGeofencingZones{
+		Data: &gbfs.FeedGeofencingZonesData{
+			GeofencingZones: gbfs.NewFeedGeofencingZonesGeoJSONFeatureCollection(features),
+		},
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tile := testTile(17.11, 48.145, 14)
+	b := tile.Bounds()
+	w, h := b[2]-b[0], b[3]-b[1]
+	feeds := map[string]gbfs.Feed{
+		gbfs.FeedNameGeofencingZones: testZones(gbfs.NewGeoJSONGeometryPolygon(gbfs.GeoJSONPolygon{
+			testSquare(b[0]+w/4, b[1]+h/4, b[2]-w/4, b[3]-h/4),
+		}, nil)),
+		gbfs.FeedNameStationInformation: &gbfs.FeedStationInformation{
+			Data: &gbfs.FeedStationInformationData{
+				Stations: []*gbfs.FeedStationInformationStation{
+					{StationID: gbfs.NewID("in"), Name: []*gbfs.LocalizedString{gbfs.NewLocalizedString("Station", "en")}, Lon: gbfs.NewCoordinate(17.11), Lat: gbfs.NewCoordinate(48.145)},
+					{StationID: gbfs.NewID("out"), Lon: gbfs.NewCoordinate(18.11), Lat: gbfs.NewCoordinate(48.145)},
+				},
+			},
+		},
+		gbfs.FeedNameStationStatus: &gbfs.FeedStationStatus{
+			Data: &gbfs.FeedStationStatusData{
+				Stations: []*gbfs.FeedStationStatusStation{
+					{StationID: gbfs.NewID("in"), NumVehiclesAvailable: gbfs.NewInt64(3)},
+				},
+			},
+		},
+		gbfs.FeedNameVehicleStatus: &gbfs.FeedVehicleStatus{
+			Data: &gbfs.FeedVehicleStatusData{
+				Vehicles: []*gbfs.FeedVehicleStatusVehicle{
+					{VehicleID: gbfs.NewID("v1"), Lon: gbfs.NewCoordinate(17.111), Lat: gbfs.NewCoordinate(48.146), CurrentRangeMeters: gbfs.NewFloat64(1500.5)},
+					{VehicleID: gbfs.NewID("v2"), StationID: gbfs.NewID("in")},
+				},
+			},
+		},
+	}
+	data, err := Encode(feeds, tile, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers := testDecode(t, data)
+	names := []string{LayerGeofencingZones, LayerStations, LayerVehicles}
+	if len(layers) != len(names) {
+		t.Fatalf("expected %d layers, got %d", len(names), len(layers))
+	}
+	for i, l := range layers {
+		if l.name != names[i] || l.version != 2 || l.extent != 4096 {
+			t.Errorf("unexpected layer %s version %d extent %d", l.name, l.version, l.extent)
+		}
+		if len(l.features) != 1 {
+			t.Errorf("%s: expected 1 feature, got %d", l.name, len(l.features))
+		}
+	}
+
+	zone := layers[0].features[0]
+	if zone.geometryType != uint64(geometryPolygon) {
+		t.Errorf("expected polygon, got %d", zone.geometryType)
+	}
+	rings := zone.rings(t)
+	if len(rings) != 1 || len(rings[0]) != 4 {
+		t.Fatalf("expected single ring of 4 positions, got %v", rings)
+	}
+	for _, pos := range rings[0] {
+		for _, v := range pos {
+			if v != 1024 && v != 3072 {
+				t.Errorf("expected position at quarter of tile, got %v", pos)
+			}
+		}
+	}
+	properties := zone.properties(t, layers[0])
+	if properties["name"] != "Zone" || properties["name:en"] != "Zone" || properties["ride_end_allowed"] != false || properties["maximum_speed_kph"] != int64(10) {
+		t.Errorf("unexpected zone properties %v", properties)
+	}
+
+	station := layers[1].features[0]
+	points := station.rings(t)
+	expected, _ := newProjection(tile, 4096).point(17.11, 48.145, 0)
+	if station.geometryType != uint64(geometryPoint) || len(points) != 1 || len(points[0]) != 1 || points[0][0] != expected {
+		t.Errorf("expected point %v, got %v", expected, points)
+	}
+	properties = station.properties(t, layers[1])
+	if properties["station_id"] != "in" || properties["num_vehicles_available"] != int64(3) {
+		t.Errorf("unexpected station properties %v", properties)
+	}
+	if _, ok := properties["is_renting"]; ok {
+		t.Error("expected property without value to be left out")
+	}
+
+	properties = layers[2].features[0].properties(t, layers[2])
+	if properties["vehicle_id"] != "v1" || properties["current_range_meters"] != 1500.5 {
+		t.Errorf("unexpected vehicle properties %v", properties)
+	}
+}
+
+func TestEncodeClipping(t *testing.T) {
+	tile := testTile(17.11, 48.145, 14)
+	b := tile.Bounds()
+	w, h := b[2]-b[0], b[3]-b[1]
+	feeds := map[string]gbfs.Feed{
+		gbfs.FeedNameGeofencingZones: testZones(
+			// covers tile with its buffer
+			gbfs.NewGeoJSONGeometryPolygon(gbfs.GeoJSONPolygon{testSquare(b[0]-w, b[1]-h, b[2]+w, b[3]+h)}, nil),
+			// crosses east edge of tile
+			gbfs.NewGeoJSONGeometryPolygon(gbfs.GeoJSONPolygon{testSquare(b[0]+w/2, b[1]+h/4, b[2]+w, b[3]-h/4)}, nil),
+			// outside of tile
+			gbfs.NewGeoJSONGeometryPolygon(gbfs.GeoJSONPolygon{testSquare(b[2]+w, b[1], b[2]+2*w, b[3])}, nil),
+		),
+	}
+	data, err := Encode(feeds, tile, Options{Buffer: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers := testDecode(t, data)
+	if len(layers) != 1 || len(layers[0].features) != 2 {
+		t.Fatalf("expected 2 features in 1 layer, got %d layers", len(layers))
+	}
+	corners := map[[2]int]bool{{-64, -64}: true, {4160, -64}: true, {4160, 4160}: true, {-64, 4160}: true}
+	rings := layers[0].features[0].rings(t)
+	if len(rings) != 1 || len(rings[0]) != 4 {
+		t.Fatalf("expected ring clipped to 4 corners, got %v", rings)
+	}
+	for _, pos := range rings[0] {
+		if !corners[pos] {
+			t.Errorf("expected corner of buffered tile, got %v", pos)
+		}
+	}
+	rings = layers[0].features[1].rings(t)
+	if len(rings) != 1 || len(rings[0]) != 4 {
+		t.Fatalf("expected clipped ring of 4 positions, got %v", rings)
+	}
+	for _, pos := range rings[0] {
+		if pos[0] != 2048 && pos[0] != 4160 || pos[1] != 1024 && pos[1] != 3072 {
+			t.Errorf("unexpected position of clipped ring %v", pos)
+		}
+	}
+}
+
+func TestEncodeWinding(t *testing.T) {
+	tile := testTile(17.11, 48.145, 14)
+	b := tile.Bounds()
+	w, h := b[2]-b[0], b[3]-b[1]
+	exterior := testSquare(b[0]+w/8, b[1]+h/8, b[2]-w/8, b[3]-h/8)
+	hole := testSquare(b[0]+w/4, b[1]+h/4, b[2]-w/4, b[3]-h/4)
+	reversed := func(ring [][2]float64) [][2]float64 {
+		r := make([][2]float64, len(ring))
+		for i, pos := range ring {
+			r[len(ring)-1-i] = pos
+		}
+		return r
+	}
+	for _, polygon := range []gbfs.GeoJSONPolygon{
+		{exterior, reversed(hole)},
+		{reversed(exterior), hole},
+	} {
+		data, err := Encode(map[string]gbfs.Feed{
+			gbfs.FeedNameGeofencingZones: testZones(gbfs.NewGeoJSONGeometryMultiPolygon(gbfs.GeoJSONMultiPolygon{polygon}, nil)),
+		}, tile, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		layers := testDecode(t, data)
+		if len(layers) != 1 || len(layers[0].features) != 1 {
+			t.Fatal("expected single feature")
+		}
+		rings := layers[0].features[0].rings(t)
+		if len(rings) != 2 {
+			t.Fatalf("expected exterior ring and hole, got %v", rings)
+		}
+		if ringArea(rings[0]) <= 0 {
+			t.Errorf("expected exterior ring with positive area, got %d", ringArea(rings[0]))
+		}
+		if ringArea(rings[1]) >= 0 {
+			t.Errorf("expected hole with negative area, got %d", ringArea(rings[1]))
+		}
+	}
+}
+
+func TestEncodeOptions(t *testing.T) {
+	tile := testTile(17.11, 48.145, 14)
+	feeds := map[string]gbfs.Feed{
+		gbfs.FeedNameVehicleStatus: &gbfs.FeedVehicleStatus{
+			Data: &gbfs.FeedVehicleStatusData{
+				Vehicles: []*gbfs.FeedVehicleStatusVehicle{
+					{VehicleID: gbfs.NewID("v1"), Lon: gbfs.NewCoordinate(17.11), Lat: gbfs.NewCoordinate(48.145)},
+				},
+			},
+		},
+	}
+	data, err := Encode(feeds, tile, Options{MinZoom: map[string]int{LayerVehicles: 15}})
+	if err != nil || len(data) != 0 {
+		t.Errorf("expected empty tile below min zoom, got %d bytes, %v", len(data), err)
+	}
+	_, err = Encode(feeds, tile, Options{MaxZoom: 13})
+	if !errors.Is(err, ErrInvalidTile) {
+		t.Errorf("expected ErrInvalidTile above max zoom, got %v", err)
+	}
+	data, err = Encode(feeds, tile, Options{Extent: 512})
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers := testDecode(t, data)
+	if len(layers) != 1 || layers[0].extent != 512 {
+		t.Error("expected layer with extent 512")
+	}
+}
+
+func TestParseTile(t *testing.T) {
+	tests := []struct {
+		path  string
+		tile  Tile
+		valid bool
+	}{
+		{"/tiles/14/9054/5680.mvt", Tile{14, 9054, 5680}, true},
+		{"14/9054/5680.pbf", Tile{14, 9054, 5680}, true},
+		{"0/0/0", Tile{0, 0, 0}, true},
+		{"1/2/0", Tile{}, false},
+		{"14/9054/5680.png", Tile{}, false},
+		{"9054/5680", Tile{}, false},
+		{"a/b/c", Tile{}, false},
+	}
+	for _, test := range tests {
+		tile, err := ParseTile(test.path)
+		if test.valid && (err != nil || tile != test.tile) {
+			t.Errorf("%s: expected %v, got %v, %v", test.path, test.tile, tile, err)
+		}
+		if !test.valid && !errors.Is(err, ErrInvalidTile) {
+			t.Errorf("%s: expected ErrInvalidTile, got %v", test.path, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package schema
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"sync"
+)
+
+//go:generate go run gen.go
+
+//go:embed schemas
+var files embed.FS
+
+var ErrSchemaNotFound = errors.New("schema not found")
+
+var (
+	compiledMu sync.Mutex
+	compiled   = map[string]*Schema{}
+)
+
+// Get returns embedded schema of feed of given version.
+func Get(version, name string) (*Schema, error) {
+	key := version + "/" + name
+	compiledMu.Lock()
+	defer compiledMu.Unlock()
+	if s, ok := compiled[key]; ok {
+		return s, nil
+	}
+	b, err := files.ReadFile(path.Join("schemas", "v"+version, name+".json"))
+	if err != nil {
+		return nil, ErrSchemaNotFound
+	}
+	s, err := Compile(b)
+	if err != nil {
+		return nil, err
+	}
+	compiled[key] = s
+	return s, nil
+}
+
+// Validate validates raw feed against embedded schema of feed of given
+// version.
+func Validate(version, name string, raw []byte) (Errors, error) {
+	s, err := Get(version, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.Validate(raw)
+}
+
+// Versions returns versions with embedded schemas.
+func Versions() []string {
+	versions := []string{}
+	entries, _ := fs.ReadDir(files, "schemas")
+	for _, entry := range entries {
+		if entry.IsDir() && len(entry.Name()) > 1 {
+			versions = append(versions, entry.Name()[1:])
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
@@ -0,0 +1,61 @@
+//go:build ignore
+
+// Downloads official JSON Schemas of GBFS specification into schemas
+// directory.
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const baseURL = "https://raw.githubusercontent.com/MobilityData/gbfs-json-schema/master/"
+
+var feeds = map[string][]string{
+	"v3.0": {
+		"gbfs",
+		"gbfs_versions",
+		"geofencing_zones",
+		"manifest",
+		"station_information",
+		"station_status",
+		"system_alerts",
+		"system_information",
+		"system_pricing_plans",
+		"system_regions",
+		"vehicle_status",
+		"vehicle_types",
+	},
+}
+
+func main() {
+	for version, names := range feeds {
+		err := os.MkdirAll(filepath.Join("schemas", version), 0755)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, name := range names {
+			res, err := http.Get(baseURL + version + "/" + name + ".json")
+			if err != nil {
+				log.Fatal(err)
+			}
+			if res.StatusCode != http.StatusOK {
+				log.Fatal(version + "/" + name + ": invalid response status: " + strconv.Itoa(res.StatusCode))
+			}
+			b, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				log.Fatal(err)
+			}
+			err = os.WriteFile(filepath.Join("schemas", version, name+".json"), b, 0644)
+			if err != nil {
+				log.Fatal(err)
+			}
+			log.Println(version + "/" + name)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package gbfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"sort"
+)
+
+type (
+	// SerializationOptions control JSON output of published feeds. With
+	// OmitNull or StableOrder, keys of objects are ordered alphabetically.
+	SerializationOptions struct {
+		// Indent enables pretty output, e.g. "  ".
+		Indent string
+		// OmitNull removes object fields with null value.
+		OmitNull bool
+		// StableOrder sorts lists of feed data (stations, vehicles, ...) by
+		// their identifiers.
+		StableOrder bool
+		// Encoders write precompressed siblings of each file, e.g.
+		// system_information.json.gz.
+		Encoders []*FeedEncoder
+	}
+	// FeedEncoder encodes file for Content-Encoding. Extension is appended to
+	// name of file.
+	FeedEncoder struct {
+		Encoding  string
+		Extension string
+		Encode    func(data []byte) ([]byte, error)
+	}
+)
+
+func (o *SerializationOptions) isZero() bool {
+	return o.Indent == "" && !o.OmitNull && !o.StableOrder && len(o.Encoders) == 0
+}
+
+// NewGzipEncoder returns encoder writing .gz siblings with given
+// compression level.
+func NewGzipEncoder(level int) *FeedEncoder {
+	return &FeedEncoder{
+		Encoding:  "gzip",
+		Extension: ".gz",
+		Encode: func(data []byte) ([]byte, error) {
+			b := bytes.Buffer{}
+			w, err := gzip.NewWriterLevel(&b, level)
+			if err != nil {
+				return nil, err
+			}
+			_, err = w.Write(data)
+			if err == nil {
+				err = w.Close()
+			}
+			if err != nil {
+				return nil, err
+			}
+			return b.Bytes(), nil
+		},
+	}
+}
+
+// NewBrotliEncoder returns encoder writing .br siblings with given brotli
+// implementation, which is not available in standard library.
+func NewBrotliEncoder(encode func(data []byte) ([]byte, error)) *FeedEncoder {
+	return &FeedEncoder{
+		Encoding:  "br",
+		Extension: ".br",
+		Encode:    encode,
+	}
+}
+
+// sortedListIDs are lists of feed data sorted with StableOrder and fields
+// they are sorted by.
+var sortedListIDs = map[string]string{
+	"stations":      "station_id",
+	"vehicles":      "vehicle_id",
+	"vehicle_types": "vehicle_type_id",
+	"regions":       "region_id",
+	"plans":         "plan_id",
+	"alerts":        "alert_id",
+}
+
+// MarshalFeed returns JSON encoding of feed according to options.
+func MarshalFeed(feed any, options SerializationOptions) ([]byte, error) {
+	b, err := json.Marshal(feed)
+	if err != nil {
+		return nil, err
+	}
+	if options.OmitNull || options.StableOrder {
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+		var v any
+		err = d.Decode(&v)
+		if err != nil {
+			return nil, err
+		}
+		if options.OmitNull {
+			v = omitNull(v)
+		}
+		if options.StableOrder {
+			if m, ok := v.(map[string]any); ok {
+				sortLists(m["data"])
+			}
+		}
+		b, err = json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.Indent != "" {
+		out := bytes.Buffer{}
+		err = json.Indent(&out, b, "", options.Indent)
+		if err != nil {
+			return nil, err
+		}
+		b = out.Bytes()
+	}
+	return b, nil
+}
+
+func omitNull(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, vv := range t {
+			if vv == nil {
+				delete(t, k)
+				continue
+			}
+			t[k] = omitNull(vv)
+		}
+	case []any:
+		for i, vv := range t {
+			t[i] = omitNull(vv)
+		}
+	}
+	return v
+}
+
+func sortLists(data any) {
+	m, ok := data.(map[string]any)
+	if !ok {
+		return
+	}
+lists:
+	for key, idField := range sortedListIDs {
+		list, ok := m[key].([]any)
+		if !ok {
+			continue
+		}
+		ids := make([]string, len(list))
+		for i, item := range list {
+			obj, ok := item.(map[string]any)
+			if !ok {
+				continue lists
+			}
+			ids[i], _ = obj[idField].(string)
+		}
+		sort.Stable(&listByID{list, ids})
+	}
+}
+
+type listByID struct {
+	list []any
+	ids  []string
+}
+
+func (l *listByID) Len() int {
+	return len(l.list)
+}
+
+func (l *listByID) Less(i, j int) bool {
+	return l.ids[i] < l.ids[j]
+}
+
+func (l *listByID) Swap(i, j int) {
+	l.list[i], l.list[j] = l.list[j], l.list[i]
+	l.ids[i], l.ids[j] = l.ids[j], l.ids[i]
+}
@@ -0,0 +1,78 @@
+package gbfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+func TestMarshalFeed(t *testing.T) {
+	feed := &FeedStationInformation{Data: &FeedStationInformationData{
+		Stations: []*FeedStationInformationStation{
+			{StationID: NewID("s2")},
+			{StationID: NewID("s1")},
+		},
+	}}
+	feed.SetTTL(60)
+	b, err := MarshalFeed(feed, SerializationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(b, []byte(`"last_updated":null`)) {
+		t.Errorf("expected null fields without OmitNull, got %s", b)
+	}
+	b, err = MarshalFeed(feed, SerializationOptions{OmitNull: true, StableOrder: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"data":{"stations":[{"station_id":"s1"},{"station_id":"s2"}]},"ttl":60}`
+	if string(b) != expected {
+		t.Errorf("expected %s, got %s", expected, b)
+	}
+	if *feed.Data.Stations[0].StationID != "s2" {
+		t.Error("stable order modified feed")
+	}
+	b, err = MarshalFeed(map[string]any{"a": []int{1}}, SerializationOptions{Indent: "  "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "{\n  \"a\": [\n    1\n  ]\n}"; string(b) != expected {
+		t.Errorf("expected indented output, got %q", b)
+	}
+}
+
+func TestPutEncodedFeed(t *testing.T) {
+	store := NewMemoryFeedStore()
+	err := putEncodedFeed(store.Put, "v3/gbfs.json", map[string]string{"a": "b"}, SerializationOptions{
+		Encoders: []*FeedEncoder{
+			NewGzipEncoder(-1),
+			NewBrotliEncoder(func(data []byte) ([]byte, error) {
+				return append([]byte("br:"), data...), nil
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := store.Get("v3/gbfs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := store.Get("v3/gbfs.json.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(b, plain) {
+		t.Errorf("expected gzip sibling to decode to %s, got %s, %v", plain, b, err)
+	}
+	br, err := store.Get("v3/gbfs.json.br")
+	if err != nil || string(br) != "br:"+string(plain) {
+		t.Errorf("expected brotli sibling, got %s, %v", br, err)
+	}
+}
@@ -23,7 +23,27 @@ var (
 
 type (
 	Server struct {
-		Options *ServerOptions
+		Options       *ServerOptions
+		gbfsMu        sync.Mutex
+		gbfsFeed      *FeedGbfs
+		gbfsFeeds     map[string]*gbfsFeedEntry
+		gbfsReady     bool
+		host          *Host
+		limiter       chan struct{}
+		v2            *serverV2
+		feedsMu       sync.RWMutex
+		feeds         map[string]Feed
+		stagingMu     sync.Mutex
+		staging       map[string][]byte
+		pushMu        sync.Mutex
+		pushFeeds     map[string]*pushFeedState
+		pushReady     bool
+		revisionsMu   sync.Mutex
+		revisions     map[string]*feedRevision
+		statusMu      sync.Mutex
+		handlerStates map[*FeedHandler]*feedHandlerState
+		feedStates    map[string]*feedState
+		stream        *streamHub
 	}
 	ServerOptions struct {
 		SystemID      string
@@ -34,12 +54,63 @@ type (
 		DefaultTTL    int
 		FeedHandlers  []*FeedHandler
 		UpdateHandler func(server *Server, feed Feed, path string, err error)
+		// PublishHandler is called for each feed processed without error,
+		// also when feed was not written, because it has not changed.
+		PublishHandler func(server *Server, feed Feed, path string, result *PublishResult)
+		Versions       []*ServerVersion
+		Systems        []*ServerSystem
+		ManifestPath   string
+		V2BasePath     string
+		Validation     ValidationMode
+		Validator      func(server *Server, feed Feed) ValidationErrors
+		Store          FeedStore
+		// MinPublishInterval is minimal interval between two publications
+		// of feed pushed with Publish. Feeds pushed more often are coalesced.
+		MinPublishInterval time.Duration
+		// VehicleIDRotator replaces vehicle ids in vehicle_status with
+		// rotating public ids before feed is published.
+		VehicleIDRotator *VehicleIDRotator
+		// VehicleFilters are applied to vehicle_status in order before feed
+		// is published and before vehicle ids are rotated.
+		VehicleFilters []*VehicleFilter
+		// GeofencingBudget reduces geofencing_zones exceeding size budget
+		// before feed is published.
+		GeofencingBudget *GeofencingBudget
+		Serialization    SerializationOptions
+	}
+	// PublishResult describes feed processed without error. Published is
+	// false, when feed was not written, because its content has not
+	// changed. Warnings are validation findings of feed published despite
+	// them.
+	PublishResult struct {
+		Published bool
+		Warnings  ValidationErrors
+	}
+	ServerVersion struct {
+		Version string
+		URL     string
+	}
+	ServerSystem struct {
+		SystemID string
+		Versions []*ServerVersion
 	}
 	FeedHandler struct {
 		TTL     int
 		Path    string
 		Handler func(*Server) ([]Feed, error)
 	}
+	gbfsFeedEntry struct {
+		language     string
+		name         string
+		pathSegments []string
+		handler      *FeedHandler
+	}
+	publishResult struct {
+		published   []*gbfsFeedEntry
+		failed      []string
+		v2Published []*gbfsFeedEntry
+		v2Failed    []string
+	}
 )
 
 func WriteFeed(filePath string, feed Feed) error {
@@ -62,8 +133,13 @@ func NewServer(options ServerOptions) (*Server, error) {
 	if options.SystemID == "" {
 		return nil, ErrMissingSystemID
 	}
-	if options.RootDir == "" {
-		return nil, ErrMissingRootDir
+	if options.Store == nil {
+		if options.RootDir == "" {
+			return nil, ErrMissingRootDir
+		}
+		options.Store = &FileFeedStore{
+			RootDir: options.RootDir,
+		}
 	}
 	if options.BaseURL == "" {
 		return nil, ErrMissingBaseURL
@@ -71,8 +147,19 @@ func NewServer(options ServerOptions) (*Server, error) {
 	if options.DefaultTTL <= 0 {
 		return nil, ErrInvalidDefaultTTL
 	}
+	if options.Version == "" {
+		options.Version = V30
+	}
+	for _, filter := range options.VehicleFilters {
+		for _, zone := range filter.Zones {
+			if err := zone.Validate(); err != nil {
+				return nil, NewError("invalid privacy zone: ", err)
+			}
+		}
+	}
 	s := &Server{
 		Options: &options,
+		stream:  newStreamHub(),
 	}
 	return s, nil
 }
@@ -81,103 +168,462 @@ func (s *Server) Start() error {
 	if len(s.Options.FeedHandlers) == 0 {
 		return ErrMissingFeedHandlers
 	}
-	gbfsGenerated := false
-	gbfsFeed := &FeedGbfs{}
-	gbfsFeed.SetTTL(s.Options.DefaultTTL)
-	gbfsFeed.SetVersion(s.Options.Version)
+	s.gbfsMu.Lock()
+	s.gbfsFeed = &FeedGbfs{
+		Data: &FeedGbfsData{
+			Feeds: []*FeedGbfsFeed{},
+		},
+	}
+	s.gbfsFeed.SetTTL(s.Options.DefaultTTL)
+	s.gbfsFeed.SetVersion(s.Options.Version)
+	s.gbfsFeeds = make(map[string]*gbfsFeedEntry)
+	s.gbfsReady = false
+	s.feedsMu.Lock()
+	s.feeds = make(map[string]Feed)
+	s.feedsMu.Unlock()
+	if s.Options.V2BasePath != "" {
+		s.v2 = newServerV2(s)
+	}
+	s.gbfsMu.Unlock()
+	s.stagingMu.Lock()
+	s.staging = make(map[string][]byte)
+	s.stagingMu.Unlock()
+	s.pushMu.Lock()
+	s.pushFeeds = make(map[string]*pushFeedState)
+	s.pushReady = true
+	s.pushMu.Unlock()
+	s.revisionsMu.Lock()
+	s.revisions = make(map[string]*feedRevision)
+	s.revisionsMu.Unlock()
+	feedHandlers := append([]*FeedHandler{}, s.Options.FeedHandlers...)
+	if len(s.Options.Versions) > 0 || s.v2 != nil {
+		feedHandlers = append(feedHandlers, s.gbfsVersionsFeedHandler())
+	}
+	s.statusMu.Lock()
+	s.handlerStates = make(map[*FeedHandler]*feedHandlerState)
+	s.feedStates = make(map[string]*feedState)
+	for _, feedHandler := range feedHandlers {
+		s.handlerStates[feedHandler] = &feedHandlerState{
+			running: true,
+			refresh: make(chan struct{}, 1),
+		}
+	}
+	s.statusMu.Unlock()
 	var wgGbfsFeed sync.WaitGroup
-	wgGbfsFeed.Add(len(s.Options.FeedHandlers))
-	for _, feedHandler := range s.Options.FeedHandlers {
+	wgGbfsFeed.Add(len(feedHandlers))
+	for _, feedHandler := range feedHandlers {
 		go (func(feedHandler *FeedHandler) {
 			if feedHandler.TTL == 0 {
 				feedHandler.TTL = s.Options.DefaultTTL
 			}
+			first := true
 			for {
-				feeds, err := feedHandler.Handler(s)
+				started := time.Now()
+				feeds, err := s.runFeedHandler(feedHandler)
+				s.recordHandlerRun(feedHandler, err)
 				if err != nil {
 					s.Options.UpdateHandler(s, nil, "", err)
-					if !gbfsGenerated {
+					if first {
 						wgGbfsFeed.Done()
 					}
 					break
 				}
-				for _, feed := range feeds {
-					feed.SetLastUpdated(Timestamp(time.Now().Format(time.RFC3339)))
-					if feed.GetTTL() == 0 {
-						feed.SetTTL(feedHandler.TTL)
-					}
-					feed.SetVersion(s.Options.Version)
-					pathSegments := []string{}
-					if s.Options.BasePath != "" {
-						pathSegments = append(pathSegments, strings.Trim(s.Options.BasePath, "/"))
-					}
-					path := strings.Trim(feedHandler.Path, "/")
-					if path == "" {
-						path = feed.Name() + ".json"
-					}
-					pathSegments = append(pathSegments, path)
-					filePath := strings.Join(append([]string{s.Options.RootDir}, pathSegments...), "/")
-					err := WriteFeed(filePath, feed)
-					s.Options.UpdateHandler(s, feed, strings.Join(pathSegments, "/"), err)
-					if err != nil {
-						continue
-					}
-					if !gbfsGenerated && feed.Name() != FeedNameGbfs {
-						gbfsFeed.Lock()
-						if gbfsFeed.Data == nil {
-							gbfsFeed.Data = &FeedGbfsData{
-								Feeds: []*FeedGbfsFeed{},
-							}
-						}
-						if gbfsFeed.Data.Feeds != nil {
-							gbfsFeed.Data.Feeds = append(gbfsFeed.Data.Feeds, &FeedGbfsFeed{
-								Name: NewString(feed.Name()),
-								URL:  NewString(strings.Join(append([]string{strings.Trim(s.Options.BaseURL, "/")}, pathSegments...), "/")),
-							})
-						}
-						gbfsFeed.Unlock()
-					}
-				}
-				if !gbfsGenerated {
+				s.publishFeeds(feedHandler, feeds, true, started)
+				if first {
+					first = false
 					wgGbfsFeed.Done()
 				}
 				if feedHandler.TTL == 0 {
+					s.stopHandler(feedHandler)
 					break
 				}
-				time.Sleep(time.Duration(feedHandler.TTL) * time.Second)
+				s.waitNextRun(feedHandler)
 			}
 		})(feedHandler)
 	}
 	wgGbfsFeed.Wait()
-	gbfsGenerated = true
-	feedNames := FeedNameAll()
-	if gbfsFeed.Data.Feeds != nil {
-		sort.Slice(gbfsFeed.Data.Feeds, func(i, j int) bool {
-			if gbfsFeed.Data.Feeds[i].Name == nil || gbfsFeed.Data.Feeds[j].Name == nil {
-				return false
-			}
-			if IndexInSlice(*gbfsFeed.Data.Feeds[i].Name, feedNames) > IndexInSlice(*gbfsFeed.Data.Feeds[j].Name, feedNames) {
-				return false
+	s.gbfsMu.Lock()
+	s.gbfsReady = true
+	s.gbfsMu.Unlock()
+	ttl := s.writeDiscoveryFeeds()
+	s.commitStaging()
+	for ttl != 0 {
+		time.Sleep(time.Duration(ttl) * time.Second)
+		ttl = s.writeDiscoveryFeeds()
+	}
+	return nil
+}
+
+// publishFeeds publishes feeds and updates autodiscovery. With retire,
+// feeds of feed handler not present in feeds are removed from
+// autodiscovery. Feeds were produced at given time, pending pushed feeds
+// of the same names pushed before are cancelled. Without retire, feeds
+// superseded by feeds produced later are skipped.
+func (s *Server) publishFeeds(feedHandler *FeedHandler, feeds []Feed, retire bool, produced time.Time) {
+	r := &publishResult{
+		published:   []*gbfsFeedEntry{},
+		failed:      []string{},
+		v2Published: []*gbfsFeedEntry{},
+		v2Failed:    []string{},
+	}
+	for _, feed := range feeds {
+		state := s.pushFeedState(feed.Name())
+		state.publishMu.Lock()
+		if s.markPublished(state, produced) || retire {
+			s.publishFeed(feedHandler, feed, r)
+		}
+		state.publishMu.Unlock()
+	}
+	s.updateGbfsFeeds(feedHandler, r.published, r.failed, retire)
+	if s.v2 != nil {
+		s.v2.updateGbfsFeeds(feedHandler, r.v2Published, r.v2Failed, retire)
+	}
+}
+
+// publishFeed writes single feed and records result of publication.
+func (s *Server) publishFeed(feedHandler *FeedHandler, feed Feed, r *publishResult) {
+	for _, filter := range s.Options.VehicleFilters {
+		feed = filter.FilterFeed(feed)
+	}
+	if s.Options.VehicleIDRotator != nil {
+		feed = s.Options.VehicleIDRotator.RotateFeed(feed)
+	}
+	if feed.GetTTL() == 0 {
+		feed.SetTTL(feedHandler.TTL)
+	}
+	feed.SetVersion(s.Options.Version)
+	s.setManifestURL(feed)
+	pathSegments := s.feedPathSegments(feedHandler, feed)
+	path := strings.Join(pathSegments, "/")
+	if s.Options.GeofencingBudget != nil {
+		var err error
+		feed, err = s.Options.GeofencingBudget.ApplyFeed(feed)
+		if err != nil {
+			s.Options.UpdateHandler(s, feed, path, err)
+		}
+	}
+	validationErrs := s.validateFeed(feed)
+	if s.Options.Validation == ValidationModeStrict && validationErrs.HasErrors() {
+		err := NewError("validation failed, feed not published: ", validationErrs)
+		s.recordFeedStatus(feedHandler, feed, path, nil, err)
+		s.Options.UpdateHandler(s, feed, path, err)
+		r.failed = append(r.failed, feed.Name())
+		return
+	}
+	rev, changed, err := s.stampFeed(path, feed, nowLastUpdated())
+	if err == nil && changed {
+		err = s.putFeedRevision(pathSegments, feed, rev)
+	}
+	if err != nil {
+		s.recordFeedStatus(feedHandler, feed, path, nil, err)
+		s.Options.UpdateHandler(s, feed, path, err)
+		r.failed = append(r.failed, feed.Name())
+		return
+	}
+	result := &PublishResult{
+		Published: changed,
+	}
+	if len(validationErrs) > 0 {
+		result.Warnings = validationErrs
+	}
+	s.recordFeedStatus(feedHandler, feed, path, result, nil)
+	s.reportFeed(feed, path, result, nil)
+	s.feedsMu.Lock()
+	s.feeds[feed.Name()] = feed
+	s.feedsMu.Unlock()
+	if changed {
+		s.stream.publish(feed)
+	}
+	if feed.Name() != FeedNameGbfs {
+		r.published = append(r.published, &gbfsFeedEntry{
+			name:         feed.Name(),
+			pathSegments: pathSegments,
+			handler:      feedHandler,
+		})
+	}
+	if s.v2 != nil {
+		p, f := s.v2.publish(feedHandler, feed)
+		r.v2Published = append(r.v2Published, p...)
+		r.v2Failed = append(r.v2Failed, f...)
+	}
+}
+
+// writeDiscoveryFeeds writes gbfs.json of all published versions and
+// manifest. It returns TTL of gbfs.json.
+func (s *Server) writeDiscoveryFeeds() int {
+	ttl := s.writeGbfsFeed()
+	if s.v2 != nil {
+		s.v2.writeGbfsFeed()
+	}
+	s.writeManifestFeed()
+	return ttl
+}
+
+// putFile writes file to store. Until first round of feed handlers is
+// finished, files are staged and published together by commitStaging.
+func (s *Server) putFile(path string, data []byte) error {
+	s.stagingMu.Lock()
+	defer s.stagingMu.Unlock()
+	if s.staging != nil {
+		s.staging[path] = data
+		return nil
+	}
+	return s.Options.Store.Put(path, data)
+}
+
+func (s *Server) deleteFile(path string) error {
+	s.forgetRevisions(path)
+	for _, encoder := range s.Options.Serialization.Encoders {
+		err := s.deleteStoreFile(path + encoder.Extension)
+		if err != nil {
+			return err
+		}
+	}
+	return s.deleteStoreFile(path)
+}
+
+func (s *Server) deleteStoreFile(path string) error {
+	s.stagingMu.Lock()
+	defer s.stagingMu.Unlock()
+	if s.staging != nil {
+		delete(s.staging, path)
+		return nil
+	}
+	err := s.Options.Store.Delete(path)
+	if errors.Is(err, ErrFileNotFound) {
+		return nil
+	}
+	return err
+}
+
+// putFeed writes feed serialized according to Serialization option
+// together with its precompressed siblings.
+func (s *Server) putFeed(pathSegments []string, feed any) error {
+	return putEncodedFeed(s.putFile, strings.Join(pathSegments, "/"), feed, s.Options.Serialization)
+}
+
+// putEncodedFeed serializes feed according to options and puts it with
+// its precompressed siblings.
+func putEncodedFeed(put func(path string, data []byte) error, path string, feed any, options SerializationOptions) error {
+	b, err := MarshalFeed(feed, options)
+	if err != nil {
+		return err
+	}
+	for _, encoder := range options.Encoders {
+		encoded, err := encoder.Encode(b)
+		if err != nil {
+			return err
+		}
+		err = put(path+encoder.Extension, encoded)
+		if err != nil {
+			return err
+		}
+	}
+	return put(path, b)
+}
+
+// commitStaging publishes staged files. Directories of BasePath and
+// V2BasePath are swapped with staged files in one step, which also removes
+// files left from previous runs.
+func (s *Server) commitStaging() {
+	s.stagingMu.Lock()
+	defer s.stagingMu.Unlock()
+	staged := s.staging
+	s.staging = nil
+	prefixes := []string{}
+	for _, prefix := range []string{s.Options.BasePath, s.Options.V2BasePath} {
+		if prefix = cleanStorePath(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	groups := make(map[string]map[string][]byte)
+	other := make(map[string][]byte)
+	for path, data := range staged {
+		prefix := ""
+		for _, p := range prefixes {
+			if hasStorePrefix(path, p) && len(p) > len(prefix) {
+				prefix = p
 			}
-			return true
+		}
+		if prefix == "" {
+			other[path] = data
+			continue
+		}
+		if groups[prefix] == nil {
+			groups[prefix] = make(map[string][]byte)
+		}
+		groups[prefix][path] = data
+	}
+	for _, prefix := range prefixes {
+		if groups[prefix] == nil {
+			continue
+		}
+		err := SwapFeeds(s.Options.Store, prefix, groups[prefix])
+		if err != nil {
+			s.forgetRevisions(prefix)
+			s.Options.UpdateHandler(s, nil, prefix, err)
+		}
+	}
+	for _, path := range sortedPaths(other) {
+		err := s.Options.Store.Put(path, other[path])
+		if err != nil {
+			s.forgetRevisions(path)
+			s.Options.UpdateHandler(s, nil, path, err)
+		}
+	}
+}
+
+// Feed returns last feed of given name published by server.
+func (s *Server) Feed(name string) Feed {
+	s.feedsMu.RLock()
+	defer s.feedsMu.RUnlock()
+	return s.feeds[name]
+}
+
+// Feeds returns last published feeds keyed by feed name.
+func (s *Server) Feeds() map[string]Feed {
+	s.feedsMu.RLock()
+	defer s.feedsMu.RUnlock()
+	feeds := make(map[string]Feed, len(s.feeds))
+	for k, v := range s.feeds {
+		feeds[k] = v
+	}
+	return feeds
+}
+
+// validateFeed runs configured validator when validation is enabled.
+// Default validator checks required fields for server version and
+// references to previously published feeds. Feed is validated before it is
+// stamped, missing last_updated is not reported, it is always set by
+// server.
+func (s *Server) validateFeed(feed Feed) ValidationErrors {
+	if s.Options.Validation == ValidationModeOff {
+		return nil
+	}
+	errs := s.checkFeed(feed)
+	checked := ValidationErrors{}
+	for _, e := range errs {
+		if e.Path != "last_updated" {
+			checked = append(checked, e)
+		}
+	}
+	return checked
+}
+
+// checkFeed runs configured validator regardless of validation mode.
+func (s *Server) checkFeed(feed Feed) ValidationErrors {
+	if s.Options.Validator != nil {
+		return s.Options.Validator(s, feed)
+	}
+	errs := ValidateFeed(feed, s.Options.Version)
+	return append(errs, ValidateReferences(feed, s.Feeds())...)
+}
+
+// runFeedHandler executes feed handler, waiting for free slot when
+// server shares limited handler concurrency with other servers.
+func (s *Server) runFeedHandler(feedHandler *FeedHandler) ([]Feed, error) {
+	if s.limiter != nil {
+		s.limiter <- struct{}{}
+		defer func() {
+			<-s.limiter
+		}()
+	}
+	return feedHandler.Handler(s)
+}
+
+func (s *Server) feedURL(pathSegments []string) string {
+	return strings.Join(append([]string{strings.Trim(s.Options.BaseURL, "/")}, pathSegments...), "/")
+}
+
+func (s *Server) feedPathSegments(feedHandler *FeedHandler, feed Feed) []string {
+	pathSegments := []string{}
+	if s.Options.BasePath != "" {
+		pathSegments = append(pathSegments, strings.Trim(s.Options.BasePath, "/"))
+	}
+	path := ""
+	if feedHandler != nil {
+		path = strings.Trim(feedHandler.Path, "/")
+	}
+	if path == "" {
+		path = feed.Name() + ".json"
+	}
+	return append(pathSegments, path)
+}
+
+// updateGbfsFeeds replaces entries owned by feed handler with currently
+// published feeds and rewrites gbfs.json when the set of feeds has changed.
+// Feeds which failed to be written keep their previous entries.
+func (s *Server) updateGbfsFeeds(feedHandler *FeedHandler, published []*gbfsFeedEntry, failed []string, retire bool) {
+	s.gbfsMu.Lock()
+	changed := false
+	current := map[string]bool{}
+	for _, entry := range published {
+		current[entry.name] = true
+		existing, ok := s.gbfsFeeds[entry.name]
+		s.gbfsFeeds[entry.name] = entry
+		if ok && strings.Join(existing.pathSegments, "/") == strings.Join(entry.pathSegments, "/") {
+			continue
+		}
+		changed = true
+	}
+	retired := []*gbfsFeedEntry{}
+	for name, entry := range s.gbfsFeeds {
+		if !retire || entry.handler != feedHandler || current[name] || InSlice(name, failed) {
+			continue
+		}
+		delete(s.gbfsFeeds, name)
+		retired = append(retired, entry)
+		changed = true
+	}
+	if !changed {
+		s.gbfsMu.Unlock()
+		return
+	}
+	feedNames := FeedNameAll()
+	feeds := []*FeedGbfsFeed{}
+	for _, entry := range s.gbfsFeeds {
+		feeds = append(feeds, &FeedGbfsFeed{
+			Name: NewString(entry.name),
+			URL:  NewString(s.feedURL(entry.pathSegments)),
 		})
 	}
-	for {
-		gbfsFeed.SetLastUpdated(Timestamp(time.Now().Format(time.RFC3339)))
-		pathSegments := []string{}
-		if s.Options.BasePath != "" {
-			pathSegments = append(pathSegments, strings.Trim(s.Options.BasePath, "/"))
+	sort.SliceStable(feeds, func(i, j int) bool {
+		ii := IndexInSlice(*feeds[i].Name, feedNames)
+		ij := IndexInSlice(*feeds[j].Name, feedNames)
+		if ii != ij {
+			return ii < ij
 		}
-		pathSegments = append(pathSegments, gbfsFeed.Name()+".json")
-		filePath := strings.Join(append([]string{s.Options.RootDir}, pathSegments...), "/")
-		err := WriteFeed(filePath, gbfsFeed)
-		s.Options.UpdateHandler(s, gbfsFeed, strings.Join(pathSegments, "/"), err)
-		if gbfsFeed.GetTTL() == 0 {
-			break
+		return *feeds[i].Name < *feeds[j].Name
+	})
+	s.gbfsFeed.Lock()
+	s.gbfsFeed.Data = &FeedGbfsData{
+		Feeds: feeds,
+	}
+	s.gbfsFeed.Unlock()
+	ready := s.gbfsReady
+	s.gbfsMu.Unlock()
+	if ready {
+		s.writeGbfsFeed()
+	}
+	for _, entry := range retired {
+		path := strings.Join(entry.pathSegments, "/")
+		err := s.deleteFile(path)
+		if err != nil {
+			s.Options.UpdateHandler(s, nil, path, err)
 		}
-		time.Sleep(time.Duration(gbfsFeed.GetTTL()) * time.Second)
 	}
-	return nil
+}
+
+// writeGbfsFeed writes current autodiscovery feed and returns its TTL.
+func (s *Server) writeGbfsFeed() int {
+	s.gbfsMu.Lock()
+	s.gbfsFeed.Lock()
+	pathSegments := s.feedPathSegments(nil, s.gbfsFeed)
+	err := s.putFeedIfChanged(pathSegments, s.gbfsFeed, nowLastUpdated())
+	s.gbfsFeed.Unlock()
+	s.gbfsMu.Unlock()
+	s.Options.UpdateHandler(s, s.gbfsFeed, strings.Join(pathSegments, "/"), err)
+	return s.gbfsFeed.GetTTL()
 }
 
 func NewFileServer(addr, rootDir string) (*http.Server, error) {
@@ -189,9 +635,21 @@ func NewFileServer(addr, rootDir string) (*http.Server, error) {
 	}
 	s := &http.Server{
 		Addr:         addr,
-		Handler:      http.FileServer(http.Dir(rootDir)),
+		Handler:      NewFileHandler(rootDir),
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 5 * time.Second,
 	}
 	return s, nil
 }
+
+// reportFeed passes result of feed processing to UpdateHandler, when feed
+// was written or failed, and to PublishHandler, when feed was processed
+// without error.
+func (s *Server) reportFeed(feed Feed, path string, result *PublishResult, err error) {
+	if err != nil || result.Published {
+		s.Options.UpdateHandler(s, feed, path, err)
+	}
+	if err == nil && s.Options.PublishHandler != nil {
+		s.Options.PublishHandler(s, feed, path, result)
+	}
+}
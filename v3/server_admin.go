@@ -0,0 +1,254 @@
+package gbfs
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+)
+
+var ErrFeedHandlerNotRunning = errors.New("feed handler not running")
+
+type (
+	// AdminOptions configure admin handler. Feed is stale, when it was not
+	// published for StaleFactor multiple of its TTL, default 2.
+	AdminOptions struct {
+		StaleFactor float64
+	}
+	// FeedStatus describes publication of feed.
+	FeedStatus struct {
+		Name             string     `json:"name"`
+		Path             string     `json:"path"`
+		TTL              int        `json:"ttl"`
+		LastPublished    *time.Time `json:"last_published,omitempty"`
+		LastWritten      *time.Time `json:"last_written,omitempty"`
+		LastError        string     `json:"last_error,omitempty"`
+		LastErrorAt      *time.Time `json:"last_error_at,omitempty"`
+		LastHandlerError string     `json:"last_handler_error,omitempty"`
+		NextRun          *time.Time `json:"next_run,omitempty"`
+		Running          bool       `json:"running"`
+		Stale            bool       `json:"stale"`
+	}
+	feedHandlerState struct {
+		running   bool
+		refresh   chan struct{}
+		nextRun   time.Time
+		lastError error
+	}
+	feedState struct {
+		handler       *FeedHandler
+		path          string
+		ttl           int
+		lastPublished time.Time
+		lastWritten   time.Time
+		lastError     error
+		lastErrorAt   time.Time
+	}
+)
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// recordHandlerRun records result of feed handler run in scheduling loop.
+func (s *Server) recordHandlerRun(feedHandler *FeedHandler, err error) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	if state, ok := s.handlerStates[feedHandler]; ok {
+		state.lastError = err
+		if err != nil {
+			state.running = false
+		}
+	}
+}
+
+// waitNextRun waits for TTL of feed handler or forced refresh.
+func (s *Server) waitNextRun(feedHandler *FeedHandler) {
+	d := time.Duration(feedHandler.TTL) * time.Second
+	s.statusMu.Lock()
+	state := s.handlerStates[feedHandler]
+	state.nextRun = time.Now().Add(d)
+	s.statusMu.Unlock()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-state.refresh:
+	}
+}
+
+func (s *Server) stopHandler(feedHandler *FeedHandler) {
+	s.statusMu.Lock()
+	if state, ok := s.handlerStates[feedHandler]; ok {
+		state.running = false
+		state.nextRun = time.Time{}
+	}
+	s.statusMu.Unlock()
+}
+
+// recordFeedStatus records result of feed publication. Unchanged feeds and
+// feeds with validation findings count as published.
+func (s *Server) recordFeedStatus(feedHandler *FeedHandler, feed Feed, path string, result *PublishResult, err error) {
+	now := time.Now()
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	state, ok := s.feedStates[feed.Name()]
+	if !ok {
+		state = &feedState{}
+		s.feedStates[feed.Name()] = state
+	}
+	state.path = path
+	state.ttl = feed.GetTTL()
+	if _, ok := s.handlerStates[feedHandler]; ok || state.handler == nil {
+		state.handler = feedHandler
+	}
+	if err != nil {
+		state.lastError = err
+		state.lastErrorAt = now
+		return
+	}
+	state.lastPublished = now
+	if result.Published {
+		state.lastWritten = now
+	}
+	if len(result.Warnings) > 0 {
+		state.lastError = result.Warnings
+		state.lastErrorAt = now
+	}
+}
+
+// FeedStatuses returns status of all feeds published by server.
+func (s *Server) FeedStatuses(options AdminOptions) []*FeedStatus {
+	if options.StaleFactor <= 0 {
+		options.StaleFactor = 2
+	}
+	now := time.Now()
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	statuses := []*FeedStatus{}
+	for name, state := range s.feedStates {
+		status := &FeedStatus{
+			Name:          name,
+			Path:          state.path,
+			TTL:           state.ttl,
+			LastPublished: optionalTime(state.lastPublished),
+			LastWritten:   optionalTime(state.lastWritten),
+			LastErrorAt:   optionalTime(state.lastErrorAt),
+		}
+		if state.lastError != nil {
+			status.LastError = state.lastError.Error()
+		}
+		if handlerState, ok := s.handlerStates[state.handler]; ok {
+			status.Running = handlerState.running
+			status.NextRun = optionalTime(handlerState.nextRun)
+			if handlerState.lastError != nil {
+				status.LastHandlerError = handlerState.lastError.Error()
+			}
+		}
+		if state.ttl > 0 {
+			maxAge := time.Duration(float64(state.ttl) * options.StaleFactor * float64(time.Second))
+			status.Stale = now.Sub(state.lastPublished) > maxAge
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Refresh runs feed handler publishing feed immediately.
+func (s *Server) Refresh(name string) error {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	state, ok := s.feedStates[name]
+	if !ok {
+		return ErrFeedNotFound
+	}
+	return s.refreshHandlerLocked(state.handler)
+}
+
+// refreshHandler runs feed handler immediately.
+func (s *Server) refreshHandler(feedHandler *FeedHandler) error {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	return s.refreshHandlerLocked(feedHandler)
+}
+
+func (s *Server) refreshHandlerLocked(feedHandler *FeedHandler) error {
+	handlerState, ok := s.handlerStates[feedHandler]
+	if !ok || !handlerState.running {
+		return ErrFeedHandlerNotRunning
+	}
+	select {
+	case handlerState.refresh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// AdminHandler returns handler exposing status of feeds.
+//
+//	GET  /feeds                 status of feeds
+//	POST /feeds/{name}/refresh  run feed handler of feed now
+//	GET  /livez                 liveness, fails when server is not started
+//	GET  /readyz                readiness, fails until first round of feed
+//	                            handlers is published or when any feed is stale
+func (s *Server) AdminHandler(options AdminOptions) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /feeds", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, s.FeedStatuses(options))
+	})
+	mux.HandleFunc("POST /feeds/{name}/refresh", func(w http.ResponseWriter, r *http.Request) {
+		err := s.Refresh(r.PathValue("name"))
+		switch {
+		case errors.Is(err, ErrFeedNotFound):
+			writeAdminJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		case err != nil:
+			writeAdminJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		default:
+			writeAdminJSON(w, http.StatusAccepted, map[string]string{"status": "refresh scheduled"})
+		}
+	})
+	mux.HandleFunc("GET /livez", func(w http.ResponseWriter, r *http.Request) {
+		s.pushMu.Lock()
+		started := s.pushReady
+		s.pushMu.Unlock()
+		if !started {
+			writeAdminJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "not started"})
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		s.gbfsMu.Lock()
+		ready := s.gbfsReady
+		s.gbfsMu.Unlock()
+		if !ready {
+			writeAdminJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "not ready"})
+			return
+		}
+		stale := []string{}
+		for _, status := range s.FeedStatuses(options) {
+			if status.Stale {
+				stale = append(stale, status.Name)
+			}
+		}
+		if len(stale) > 0 {
+			writeAdminJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "stale", "feeds": stale})
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+	})
+	return mux
+}
+
+func writeAdminJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
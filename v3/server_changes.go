@@ -0,0 +1,112 @@
+package gbfs
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// errFeedUnchanged is returned, when feed was not written, because its
+// content has not changed since it was last published.
+var errFeedUnchanged = errors.New("feed unchanged, not published")
+
+type feedRevision struct {
+	hash        [32]byte
+	lastUpdated string
+}
+
+func nowLastUpdated() json.RawMessage {
+	b, _ := json.Marshal(time.Now().Format(time.RFC3339))
+	return b
+}
+
+// stampFeed sets last_updated of feed and reports whether content of feed
+// changed since it was last written to path.
+func (s *Server) stampFeed(path string, feed any, now json.RawMessage) (*feedRevision, bool, error) {
+	s.revisionsMu.Lock()
+	prev := s.revisions[path]
+	s.revisionsMu.Unlock()
+	return stampRevision(prev, feed, now)
+}
+
+// stampRevision sets last_updated of feed and reports whether content of
+// feed changed since previous revision. Content is compared without
+// last_updated. Unchanged feed keeps last_updated of previous revision,
+// changed feed gets now, unless feed handler has set last_updated itself.
+func stampRevision(prev *feedRevision, feed any, now json.RawMessage) (*feedRevision, bool, error) {
+	b, err := json.Marshal(feed)
+	if err != nil {
+		return nil, false, err
+	}
+	fields := map[string]json.RawMessage{}
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		return nil, false, err
+	}
+	lastUpdated := string(fields["last_updated"])
+	delete(fields, "last_updated")
+	b, err = json.Marshal(fields)
+	if err != nil {
+		return nil, false, err
+	}
+	rev := &feedRevision{
+		hash: sha256.Sum256(b),
+	}
+	supplied := lastUpdated != "" && lastUpdated != "null" && (prev == nil || lastUpdated != prev.lastUpdated)
+	changed := true
+	switch {
+	case supplied:
+		rev.lastUpdated = lastUpdated
+	case prev != nil && prev.hash == rev.hash:
+		rev.lastUpdated = prev.lastUpdated
+		changed = false
+	default:
+		rev.lastUpdated = string(now)
+	}
+	if rev.lastUpdated != lastUpdated {
+		err = json.Unmarshal([]byte(`{"last_updated":`+rev.lastUpdated+`}`), feed)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return rev, changed, nil
+}
+
+// putFeedIfChanged stamps feed and writes it, when its content changed. It
+// returns errFeedUnchanged otherwise.
+func (s *Server) putFeedIfChanged(pathSegments []string, feed any, now json.RawMessage) error {
+	path := strings.Join(pathSegments, "/")
+	rev, changed, err := s.stampFeed(path, feed, now)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return errFeedUnchanged
+	}
+	return s.putFeedRevision(pathSegments, feed, rev)
+}
+
+func (s *Server) putFeedRevision(pathSegments []string, feed any, rev *feedRevision) error {
+	err := s.putFeed(pathSegments, feed)
+	if err != nil {
+		return err
+	}
+	s.revisionsMu.Lock()
+	s.revisions[strings.Join(pathSegments, "/")] = rev
+	s.revisionsMu.Unlock()
+	return nil
+}
+
+// forgetRevisions removes revisions of files under prefix, so they are
+// written again on next publication.
+func (s *Server) forgetRevisions(prefix string) {
+	s.revisionsMu.Lock()
+	for path := range s.revisions {
+		if hasStorePrefix(path, prefix) {
+			delete(s.revisions, path)
+		}
+	}
+	s.revisionsMu.Unlock()
+}
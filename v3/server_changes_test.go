@@ -0,0 +1,53 @@
+package gbfs
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStampRevision(t *testing.T) {
+	first := json.RawMessage(`"2024-01-01T00:00:00Z"`)
+	second := json.RawMessage(`"2024-01-01T00:01:00Z"`)
+	feed := func(name string) *FeedSystemInformation {
+		return &FeedSystemInformation{
+			Data: &FeedSystemInformationData{
+				SystemID: NewID("system_id"),
+				Name:     []*LocalizedString{{Text: name, Language: "en"}},
+			},
+		}
+	}
+	f := feed("a")
+	rev, changed, err := stampRevision(nil, f, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || f.GetLastUpdated() != "2024-01-01T00:00:00Z" {
+		t.Fatalf("expected new feed to be stamped, got changed=%v last_updated=%s", changed, f.GetLastUpdated())
+	}
+	f = feed("a")
+	rev2, changed, err := stampRevision(rev, f, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed || f.GetLastUpdated() != "2024-01-01T00:00:00Z" || rev2.lastUpdated != rev.lastUpdated {
+		t.Fatalf("expected unchanged feed to keep last_updated, got changed=%v last_updated=%s", changed, f.GetLastUpdated())
+	}
+	f = feed("b")
+	rev3, changed, err := stampRevision(rev2, f, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || f.GetLastUpdated() != "2024-01-01T00:01:00Z" || rev3.hash == rev2.hash {
+		t.Fatalf("expected changed feed to be stamped, got changed=%v last_updated=%s", changed, f.GetLastUpdated())
+	}
+	// last_updated set by feed handler is kept, even if content is unchanged
+	f = feed("b")
+	f.SetLastUpdated("2024-01-01T00:02:00Z")
+	_, changed, err = stampRevision(rev3, f, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || f.GetLastUpdated() != "2024-01-01T00:02:00Z" {
+		t.Fatalf("expected supplied last_updated to be kept, got changed=%v last_updated=%s", changed, f.GetLastUpdated())
+	}
+}
@@ -0,0 +1,137 @@
+package gbfs
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrServerNotStarted = errors.New("server not started")
+	ErrInvalidFeed      = errors.New("invalid feed")
+)
+
+type pushFeedState struct {
+	// publishMu serializes publication of feeds of the same name.
+	publishMu     sync.Mutex
+	lastPublished time.Time
+	// producedAt is production time of the latest published feed.
+	producedAt time.Time
+	pending    Feed
+	pendingAt  time.Time
+	timer      *time.Timer
+}
+
+// Publish publishes feed immediately, without waiting for next run of feed
+// handlers. When feed of the same name was published less than
+// MinPublishInterval ago, feed is published after the interval elapses and
+// only the latest feed pushed in meantime is published. Feed published by
+// feed handler, which started after feed was pushed, supersedes pending
+// feed. Feed must not be
+// modified after it is passed to Publish. Result of publication is passed
+// to UpdateHandler and PublishHandler.
+func (s *Server) Publish(feed Feed) error {
+	if feed == nil {
+		return ErrInvalidFeed
+	}
+	name := feed.Name()
+	s.pushMu.Lock()
+	if !s.pushReady {
+		s.pushMu.Unlock()
+		return ErrServerNotStarted
+	}
+	state, ok := s.pushFeeds[name]
+	if !ok {
+		state = &pushFeedState{}
+		s.pushFeeds[name] = state
+	}
+	now := time.Now()
+	wait := s.Options.MinPublishInterval - now.Sub(state.lastPublished)
+	if wait <= 0 && state.pending == nil {
+		state.lastPublished = now
+		s.pushMu.Unlock()
+		s.publishPushed(feed, now)
+		return nil
+	}
+	state.pending = feed
+	state.pendingAt = now
+	if state.timer == nil {
+		if wait < 0 {
+			wait = 0
+		}
+		state.timer = time.AfterFunc(wait, func() {
+			s.flushPushed(name)
+		})
+	}
+	s.pushMu.Unlock()
+	return nil
+}
+
+func (s *Server) flushPushed(name string) {
+	s.pushMu.Lock()
+	state := s.pushFeeds[name]
+	feed, pushed := state.pending, state.pendingAt
+	state.pending = nil
+	state.timer = nil
+	state.lastPublished = time.Now()
+	s.pushMu.Unlock()
+	if feed != nil {
+		s.publishPushed(feed, pushed)
+	}
+}
+
+// publishPushed publishes feed on behalf of feed handler which already
+// publishes feed of the same name, so path of feed is kept.
+func (s *Server) publishPushed(feed Feed, pushed time.Time) {
+	s.gbfsMu.Lock()
+	var feedHandler *FeedHandler
+	if entry, ok := s.gbfsFeeds[feed.Name()]; ok {
+		feedHandler = entry.handler
+	}
+	s.gbfsMu.Unlock()
+	if feedHandler == nil {
+		feedHandler = &FeedHandler{
+			TTL: s.Options.DefaultTTL,
+		}
+	}
+	s.publishFeeds(feedHandler, []Feed{feed}, false, pushed)
+}
+
+// pushFeedState returns state of feeds of given name.
+func (s *Server) pushFeedState(name string) *pushFeedState {
+	s.pushMu.Lock()
+	defer s.pushMu.Unlock()
+	if s.pushFeeds == nil {
+		s.pushFeeds = make(map[string]*pushFeedState)
+	}
+	state, ok := s.pushFeeds[name]
+	if !ok {
+		state = &pushFeedState{}
+		s.pushFeeds[name] = state
+	}
+	return state
+}
+
+// markPublished records publication of feed produced at given time and
+// drops pending pushed feed of the same name, when it was pushed before.
+// Returns false, when feed of the same name produced later was already
+// published.
+func (s *Server) markPublished(state *pushFeedState, produced time.Time) bool {
+	s.pushMu.Lock()
+	defer s.pushMu.Unlock()
+	state.lastPublished = time.Now()
+	if produced.Before(state.producedAt) {
+		return false
+	}
+	state.producedAt = produced
+	if state.pending == nil || !state.pendingAt.Before(produced) {
+		return true
+	}
+	// Timer which already fired finds no pending feed and only resets
+	// state.
+	state.pending = nil
+	if state.timer != nil && state.timer.Stop() {
+		state.timer = nil
+	}
+	return true
+}
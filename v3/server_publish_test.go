@@ -0,0 +1,52 @@
+package gbfs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPublishInvalidFeed(t *testing.T) {
+	s := &Server{Options: &ServerOptions{}}
+	if err := s.Publish(nil); !errors.Is(err, ErrInvalidFeed) {
+		t.Fatalf("expected ErrInvalidFeed, got %v", err)
+	}
+}
+
+func TestMarkPublishedKeepsNewerPendingFeed(t *testing.T) {
+	s := &Server{Options: &ServerOptions{}}
+	state := s.pushFeedState(FeedNameStationStatus)
+	pushed := time.Now()
+	state.pending = &FeedStationStatus{}
+	state.pendingAt = pushed
+	state.timer = time.AfterFunc(time.Hour, func() {})
+	defer state.timer.Stop()
+	s.markPublished(state, pushed.Add(-time.Second))
+	if state.pending == nil {
+		t.Fatal("feed produced before pending feed was pushed cancelled it")
+	}
+	s.markPublished(state, pushed.Add(time.Second))
+	if state.pending != nil || state.timer != nil {
+		t.Fatal("feed produced after pending feed was pushed did not cancel it")
+	}
+}
+
+func TestMarkPublishedDropsFiredPendingFeed(t *testing.T) {
+	s := &Server{Options: &ServerOptions{}}
+	state := s.pushFeedState(FeedNameStationStatus)
+	pushed := time.Now()
+	fired := make(chan struct{})
+	state.pending = &FeedStationStatus{}
+	state.pendingAt = pushed
+	state.timer = time.AfterFunc(0, func() { close(fired) })
+	<-fired
+	if !s.markPublished(state, pushed.Add(time.Second)) {
+		t.Fatal("newer feed reported as superseded")
+	}
+	if state.pending != nil {
+		t.Fatal("pending feed older than published feed was kept after timer fired")
+	}
+	if s.markPublished(state, pushed) {
+		t.Fatal("feed produced before published feed not reported as superseded")
+	}
+}
@@ -0,0 +1,314 @@
+package gbfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	StreamEventFeed  = "feed"
+	StreamEventPatch = "patch"
+)
+
+type (
+	// StreamOptions configure stream handler.
+	StreamOptions struct {
+		// Heartbeat interval, default 15 seconds.
+		Heartbeat time.Duration
+		// Buffer is number of events buffered for each client, default 64.
+		// Clients which are not able to keep up are disconnected.
+		Buffer int
+	}
+	// StreamMessage is data of stream event. Feed is set for feed events,
+	// Patch is JSON merge patch (RFC 7386) of previous feed for patch
+	// events. Feed, which cannot be expressed as merge patch (it contains
+	// removed fields or null values), is sent as feed event.
+	StreamMessage struct {
+		Name  string          `json:"name"`
+		Feed  json.RawMessage `json:"feed,omitempty"`
+		Patch json.RawMessage `json:"patch,omitempty"`
+	}
+	streamEvent struct {
+		id    int64
+		name  string
+		feed  json.RawMessage
+		patch json.RawMessage
+	}
+	streamHub struct {
+		mu      sync.Mutex
+		seq     int64
+		last    map[string]json.RawMessage
+		clients map[chan *streamEvent]bool
+	}
+)
+
+func newStreamHub() *streamHub {
+	return &streamHub{
+		last:    make(map[string]json.RawMessage),
+		clients: make(map[chan *streamEvent]bool),
+	}
+}
+
+// publish sends published feed to all connected clients.
+func (h *streamHub) publish(feed Feed) {
+	b, err := json.Marshal(feed)
+	if err != nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	e := &streamEvent{
+		id:   h.seq,
+		name: feed.Name(),
+		feed: b,
+	}
+	if prev, ok := h.last[e.name]; ok {
+		e.patch, _ = mergePatchDiff(prev, b)
+		// e.patch is nil, when change cannot be expressed by merge patch
+	}
+	h.last[e.name] = b
+	for ch := range h.clients {
+		select {
+		case ch <- e:
+		default:
+			delete(h.clients, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe registers client and returns current feeds as first events.
+func (h *streamHub) subscribe(buffer int) (chan *streamEvent, []*streamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan *streamEvent, buffer)
+	h.clients[ch] = true
+	snapshot := []*streamEvent{}
+	for _, name := range sortedKeys(h.last) {
+		snapshot = append(snapshot, &streamEvent{
+			id:   h.seq,
+			name: name,
+			feed: h.last[name],
+		})
+	}
+	return ch, snapshot
+}
+
+func (h *streamHub) unsubscribe(ch chan *streamEvent) {
+	h.mu.Lock()
+	if h.clients[ch] {
+		delete(h.clients, ch)
+		close(ch)
+	}
+	h.mu.Unlock()
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// StreamHandler returns handler streaming published feeds to clients as
+// Server-Sent Events. Current feeds are sent first as feed events,
+// followed by each newly published feed. With query parameter diff=1,
+// updates are sent as patch events with JSON merge patch of previous
+// feed. Query parameter feeds restricts stream to comma separated feed
+// names. Write timeout of server does not apply to streams, each write has
+// to finish within heartbeat interval.
+func (s *Server) StreamHandler(options StreamOptions) http.Handler {
+	if options.Heartbeat <= 0 {
+		options.Heartbeat = 15 * time.Second
+	}
+	if options.Buffer <= 0 {
+		options.Buffer = 64
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
+		names := []string{}
+		if v := r.URL.Query().Get("feeds"); v != "" {
+			names = strings.Split(v, ",")
+		}
+		diff := r.URL.Query().Get("diff") == "1"
+		rc := http.NewResponseController(w)
+		// stream is kept open longer than WriteTimeout of server allows
+		rc.SetWriteDeadline(time.Time{})
+		deadline := func() {
+			rc.SetWriteDeadline(time.Now().Add(options.Heartbeat))
+		}
+		ch, snapshot := s.stream.subscribe(options.Buffer)
+		defer s.stream.unsubscribe(ch)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		write := func(e *streamEvent, patch bool) bool {
+			if len(names) > 0 && !InSlice(e.name, names) {
+				return true
+			}
+			msg := &StreamMessage{
+				Name: e.name,
+			}
+			event := StreamEventFeed
+			if patch && e.patch != nil {
+				event = StreamEventPatch
+				msg.Patch = e.patch
+			} else {
+				msg.Feed = e.feed
+			}
+			b, err := json.Marshal(msg)
+			if err != nil {
+				return true
+			}
+			deadline()
+			_, err = w.Write([]byte("id: " + strconv.FormatInt(e.id, 10) + "\nevent: " + event + "\ndata: " + string(b) + "\n\n"))
+			flusher.Flush()
+			return err == nil
+		}
+		for _, e := range snapshot {
+			if !write(e, false) {
+				return
+			}
+		}
+		heartbeat := time.NewTicker(options.Heartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				deadline()
+				_, err := w.Write([]byte(": ping\n\n"))
+				if err != nil {
+					return
+				}
+				flusher.Flush()
+			case e, ok := <-ch:
+				if !ok || !write(e, diff) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// mergePatchDiff returns JSON merge patch transforming a to b. Nil patch is
+// returned, when b has null values or lacks fields of a. Merge patch uses
+// null for removal of field, so null value cannot be set and
+// applyMergePatch would not reproduce b.
+func mergePatchDiff(a, b []byte) (json.RawMessage, error) {
+	var va, vb any
+	err := json.Unmarshal(a, &va)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &vb)
+	if err != nil {
+		return nil, err
+	}
+	patch, ok := mergePatchDiffValue(va, vb)
+	if !ok {
+		return nil, nil
+	}
+	return json.Marshal(patch)
+}
+
+func mergePatchDiffValue(a, b any) (any, bool) {
+	ma, okA := a.(map[string]any)
+	mb, okB := b.(map[string]any)
+	if !okA || !okB {
+		return b, !hasNull(b)
+	}
+	patch := map[string]any{}
+	for k, va := range ma {
+		vb, ok := mb[k]
+		if !ok {
+			return nil, false
+		}
+		if !jsonEqual(va, vb) {
+			v, ok := mergePatchDiffValue(va, vb)
+			if !ok {
+				return nil, false
+			}
+			patch[k] = v
+		}
+	}
+	for k, vb := range mb {
+		if _, ok := ma[k]; !ok {
+			if hasNull(vb) {
+				return nil, false
+			}
+			patch[k] = vb
+		}
+	}
+	return patch, true
+}
+
+// hasNull reports whether value or any object nested in it has null value.
+// Nulls in arrays are kept by merge patch, they replace arrays as whole.
+func hasNull(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case map[string]any:
+		for _, e := range v {
+			if hasNull(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jsonEqual(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// applyMergePatch applies JSON merge patch to document.
+func applyMergePatch(doc, patch []byte) (json.RawMessage, error) {
+	var vd, vp any
+	err := json.Unmarshal(doc, &vd)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(patch, &vp)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(applyMergePatchValue(vd, vp))
+}
+
+func applyMergePatchValue(doc, patch any) any {
+	mp, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	md, ok := doc.(map[string]any)
+	if !ok {
+		md = map[string]any{}
+	}
+	for k, v := range mp {
+		if v == nil {
+			delete(md, k)
+			continue
+		}
+		md[k] = applyMergePatchValue(md[k], v)
+	}
+	return md
+}
@@ -0,0 +1,84 @@
+package gbfs
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergePatchDiff(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		patch string
+	}{
+		{`{"a":1,"b":{"c":1,"d":2}}`, `{"a":1,"b":{"c":1,"d":3}}`, `{"b":{"d":3}}`},
+		{`{"a":[1,2]}`, `{"a":[{"x":null}],"e":true}`, `{"a":[{"x":null}],"e":true}`},
+		{`{"a":1,"b":2}`, `{"a":1}`, ``},
+		{`{"a":1,"b":{"c":1}}`, `{"a":1,"b":{}}`, ``},
+		{`{"a":1}`, `{"a":null}`, ``},
+		{`{"a":1}`, `{"a":1,"b":{"c":null}}`, ``},
+	}
+	for _, test := range tests {
+		patch, err := mergePatchDiff([]byte(test.a), []byte(test.b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(patch) != test.patch {
+			t.Errorf("%s -> %s: expected patch %q, got %q", test.a, test.b, test.patch, patch)
+			continue
+		}
+		if patch == nil {
+			continue
+		}
+		doc, err := applyMergePatch([]byte(test.a), patch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !jsonEqualRaw(t, doc, []byte(test.b)) {
+			t.Errorf("%s + %s: expected %s, got %s", test.a, patch, test.b, doc)
+		}
+	}
+}
+
+func jsonEqualRaw(t *testing.T, a, b []byte) bool {
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatal(err)
+	}
+	return jsonEqual(va, vb)
+}
+
+func TestStreamHandlerOutlivesWriteTimeout(t *testing.T) {
+	s := &Server{
+		Options: &ServerOptions{},
+		stream:  newStreamHub(),
+	}
+	ts := httptest.NewUnstartedServer(s.StreamHandler(StreamOptions{Heartbeat: 50 * time.Millisecond}))
+	ts.Config.WriteTimeout = 100 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+	res, err := http.Get(ts.URL + "?diff=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	time.Sleep(300 * time.Millisecond)
+	s.stream.publish(&FeedSystemInformation{Data: &FeedSystemInformationData{SystemID: NewID("a")}})
+	r := bufio.NewReader(res.Body)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal("stream closed:", err)
+		}
+		if strings.HasPrefix(line, "event: "+StreamEventFeed) {
+			return
+		}
+	}
+}
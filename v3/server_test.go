@@ -0,0 +1,115 @@
+package gbfs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestServerStrictValidationBeforeStamp(t *testing.T) {
+	feed := &FeedSystemInformation{
+		Data: &FeedSystemInformationData{
+			SystemID: NewID("system_id"),
+		},
+	}
+	done := make(chan error, 1)
+	s, err := NewServer(ServerOptions{
+		SystemID:   "system_id",
+		Store:      NewMemoryFeedStore(),
+		BaseURL:    "http://127.0.0.1:8080",
+		DefaultTTL: 60,
+		Validation: ValidationModeStrict,
+		FeedHandlers: []*FeedHandler{
+			{
+				Handler: func(s *Server) ([]Feed, error) {
+					return []Feed{feed}, nil
+				},
+			},
+		},
+		UpdateHandler: func(s *Server, f Feed, path string, err error) {
+			if f == feed {
+				done <- err
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Options.Version != V30 {
+		t.Fatalf("expected default version %s, got %q", V30, s.Options.Version)
+	}
+	go s.Start()
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("feed was not published")
+	}
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) || !validationErrs.HasErrors() {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	for _, e := range validationErrs {
+		if e.Path == "last_updated" || e.Rule == RuleVersion {
+			t.Fatalf("unexpected finding %v", e)
+		}
+	}
+	if feed.LastUpdated != nil {
+		t.Fatal("rejected feed was stamped")
+	}
+	if _, err := s.Options.Store.(*MemoryFeedStore).Get("system_information.json"); err == nil {
+		t.Fatal("rejected feed was written")
+	}
+}
+
+func TestServerPublishHandler(t *testing.T) {
+	updates := make(chan error, 10)
+	results := make(chan *PublishResult, 10)
+	s, err := NewServer(ServerOptions{
+		SystemID:   "system_id",
+		Store:      NewMemoryFeedStore(),
+		BaseURL:    "http://127.0.0.1:8080",
+		DefaultTTL: 60,
+		Validation: ValidationModeWarn,
+		FeedHandlers: []*FeedHandler{
+			{
+				TTL: 1,
+				Handler: func(s *Server) ([]Feed, error) {
+					return []Feed{&FeedSystemInformation{Data: &FeedSystemInformationData{SystemID: NewID("system_id")}}}, nil
+				},
+			},
+		},
+		UpdateHandler: func(s *Server, f Feed, path string, err error) {
+			if path == "system_information.json" {
+				updates <- err
+			}
+		},
+		PublishHandler: func(s *Server, f Feed, path string, result *PublishResult) {
+			if path == "system_information.json" {
+				results <- result
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	for i, published := range []bool{true, false} {
+		select {
+		case result := <-results:
+			if result.Published != published {
+				t.Fatalf("run %d: expected published %v, got %v", i, published, result.Published)
+			}
+			if !result.Warnings.HasErrors() {
+				t.Fatalf("run %d: expected validation warnings", i)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("feed was not processed")
+		}
+	}
+	if len(updates) != 1 {
+		t.Fatalf("expected UpdateHandler to be called once, got %d", len(updates))
+	}
+	if err := <-updates; err != nil {
+		t.Fatalf("expected no error for published feed with warnings, got %v", err)
+	}
+}
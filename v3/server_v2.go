@@ -0,0 +1,199 @@
+package gbfs
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gbfsv2 "github.com/petoc/gbfs/v2"
+)
+
+// serverV2 publishes v2.3 rendition of feeds published by server under
+// V2BasePath, with its own language keyed gbfs.json.
+type serverV2 struct {
+	server    *Server
+	gbfsFeed  *gbfsv2.FeedGbfs
+	gbfsFeeds map[string]*gbfsFeedEntry
+	languages []string
+}
+
+func newServerV2(s *Server) *serverV2 {
+	gbfsFeed := &gbfsv2.FeedGbfs{
+		Data: make(map[string]*gbfsv2.FeedGbfsLanguage),
+	}
+	gbfsFeed.SetTTL(s.Options.DefaultTTL)
+	gbfsFeed.SetVersion(gbfsv2.V23)
+	return &serverV2{
+		server:    s,
+		gbfsFeed:  gbfsFeed,
+		gbfsFeeds: make(map[string]*gbfsFeedEntry),
+	}
+}
+
+func (s *Server) v2GbfsURL() string {
+	return s.feedURL(v2PathSegments(s.Options.V2BasePath, "", FeedNameGbfs))
+}
+
+func v2PathSegments(basePath, language, name string) []string {
+	pathSegments := []string{}
+	if basePath = strings.Trim(basePath, "/"); basePath != "" {
+		pathSegments = append(pathSegments, basePath)
+	}
+	if language != "" {
+		pathSegments = append(pathSegments, language)
+	}
+	return append(pathSegments, name+".json")
+}
+
+func v2GbfsFeedKey(language, name string) string {
+	return language + "/" + name
+}
+
+// publish converts and writes v2 rendition of feed. It returns entries of
+// written feeds and keys of feeds which failed to be written.
+func (v *serverV2) publish(feedHandler *FeedHandler, feed Feed) ([]*gbfsFeedEntry, []string) {
+	s := v.server
+	if f, ok := feed.(*FeedSystemInformation); ok && f.Data != nil && len(f.Data.Languages) > 0 {
+		s.gbfsMu.Lock()
+		v.languages = append([]string{}, f.Data.Languages...)
+		s.gbfsMu.Unlock()
+	}
+	s.gbfsMu.Lock()
+	languages := v.languages
+	s.gbfsMu.Unlock()
+	published := []*gbfsFeedEntry{}
+	failed := []string{}
+	feeds, err := ConvertToV2(feed, languages)
+	if err != nil {
+		s.Options.UpdateHandler(s, feed, "", err)
+		return published, failed
+	}
+	for _, f := range feeds {
+		pathSegments := v2PathSegments(s.Options.V2BasePath, f.GetLanguage(), f.Name())
+		err := s.putFeedIfChanged(pathSegments, f, nowLastUpdatedV2())
+		if err != nil && !errors.Is(err, errFeedUnchanged) {
+			s.Options.UpdateHandler(s, feed, strings.Join(pathSegments, "/"), err)
+			failed = append(failed, v2GbfsFeedKey(f.GetLanguage(), f.Name()))
+			continue
+		}
+		published = append(published, &gbfsFeedEntry{
+			language:     f.GetLanguage(),
+			name:         f.Name(),
+			pathSegments: pathSegments,
+			handler:      feedHandler,
+		})
+	}
+	return published, failed
+}
+
+// updateGbfsFeeds replaces v2 entries owned by feed handler with currently
+// published feeds and rewrites v2 gbfs.json when the set of feeds has
+// changed.
+func (v *serverV2) updateGbfsFeeds(feedHandler *FeedHandler, published []*gbfsFeedEntry, failed []string, retire bool) {
+	s := v.server
+	s.gbfsMu.Lock()
+	changed := false
+	current := map[string]bool{}
+	for _, entry := range published {
+		key := v2GbfsFeedKey(entry.language, entry.name)
+		current[key] = true
+		existing, ok := v.gbfsFeeds[key]
+		v.gbfsFeeds[key] = entry
+		if ok && strings.Join(existing.pathSegments, "/") == strings.Join(entry.pathSegments, "/") {
+			continue
+		}
+		changed = true
+	}
+	retired := []*gbfsFeedEntry{}
+	for key, entry := range v.gbfsFeeds {
+		if !retire || entry.handler != feedHandler || current[key] || InSlice(key, failed) {
+			continue
+		}
+		delete(v.gbfsFeeds, key)
+		retired = append(retired, entry)
+		changed = true
+	}
+	if !changed {
+		s.gbfsMu.Unlock()
+		return
+	}
+	feedNames := gbfsv2.FeedNameAll()
+	data := make(map[string]*gbfsv2.FeedGbfsLanguage)
+	for _, entry := range v.gbfsFeeds {
+		if entry.language != "" {
+			data[entry.language] = &gbfsv2.FeedGbfsLanguage{
+				Feeds: []*gbfsv2.FeedGbfsFeed{},
+			}
+		}
+	}
+	for _, entry := range v.gbfsFeeds {
+		languages := []string{entry.language}
+		if entry.language == "" && len(data) > 0 {
+			// language independent feeds are listed for every language
+			languages = []string{}
+			for language := range data {
+				languages = append(languages, language)
+			}
+		}
+		for _, language := range languages {
+			gbfsFeedLanguage, ok := data[language]
+			if !ok {
+				gbfsFeedLanguage = &gbfsv2.FeedGbfsLanguage{
+					Feeds: []*gbfsv2.FeedGbfsFeed{},
+				}
+				data[language] = gbfsFeedLanguage
+			}
+			gbfsFeedLanguage.Feeds = append(gbfsFeedLanguage.Feeds, &gbfsv2.FeedGbfsFeed{
+				Name: NewString(entry.name),
+				URL:  NewString(s.feedURL(entry.pathSegments)),
+			})
+		}
+	}
+	for _, langData := range data {
+		feeds := langData.Feeds
+		sort.SliceStable(feeds, func(i, j int) bool {
+			ii := IndexInSlice(*feeds[i].Name, feedNames)
+			ij := IndexInSlice(*feeds[j].Name, feedNames)
+			if ii != ij {
+				return ii < ij
+			}
+			return *feeds[i].Name < *feeds[j].Name
+		})
+	}
+	v.gbfsFeed.Lock()
+	v.gbfsFeed.Data = data
+	v.gbfsFeed.Unlock()
+	ready := s.gbfsReady
+	s.gbfsMu.Unlock()
+	if ready {
+		v.writeGbfsFeed()
+	}
+	for _, entry := range retired {
+		path := strings.Join(entry.pathSegments, "/")
+		err := s.deleteFile(path)
+		if err != nil {
+			s.Options.UpdateHandler(s, nil, path, err)
+		}
+	}
+}
+
+// writeGbfsFeed writes v2 autodiscovery feed.
+func (v *serverV2) writeGbfsFeed() {
+	s := v.server
+	s.gbfsMu.Lock()
+	v.gbfsFeed.Lock()
+	pathSegments := v2PathSegments(s.Options.V2BasePath, "", FeedNameGbfs)
+	err := s.putFeedIfChanged(pathSegments, v.gbfsFeed, nowLastUpdatedV2())
+	v.gbfsFeed.Unlock()
+	s.gbfsMu.Unlock()
+	if err != nil && !errors.Is(err, errFeedUnchanged) {
+		s.Options.UpdateHandler(s, nil, strings.Join(pathSegments, "/"), err)
+	}
+}
+
+func nowLastUpdatedV2() json.RawMessage {
+	return json.RawMessage(strconv.FormatInt(time.Now().Unix(), 10))
+}
@@ -0,0 +1,170 @@
+package gbfs
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	gbfsv2 "github.com/petoc/gbfs/v2"
+)
+
+// gbfsVersionsFeedHandler returns internal feed handler publishing
+// gbfs_versions.json with current version and all sibling versions.
+func (s *Server) gbfsVersionsFeedHandler() *FeedHandler {
+	return &FeedHandler{
+		TTL: s.Options.DefaultTTL,
+		Handler: func(s *Server) ([]Feed, error) {
+			feed := &FeedGbfsVersions{
+				Data: &FeedGbfsVersionsData{
+					Versions: s.gbfsVersions(),
+				},
+			}
+			return []Feed{feed}, nil
+		},
+	}
+}
+
+func (s *Server) gbfsURL() string {
+	return s.feedURL(s.feedPathSegments(nil, &FeedGbfs{}))
+}
+
+func (s *Server) gbfsVersions() []*FeedGbfsVersionsVersion {
+	serverVersions := []*ServerVersion{{Version: s.Options.Version, URL: s.gbfsURL()}}
+	if s.Options.V2BasePath != "" {
+		serverVersions = append(serverVersions, &ServerVersion{Version: gbfsv2.V23, URL: s.v2GbfsURL()})
+	}
+	serverVersions = append(serverVersions, s.Options.Versions...)
+	return feedGbfsVersions(serverVersions)
+}
+
+func feedGbfsVersions(serverVersions []*ServerVersion) []*FeedGbfsVersionsVersion {
+	versions := []*FeedGbfsVersionsVersion{}
+	seen := map[string]bool{}
+	for _, v := range serverVersions {
+		if v == nil || v.Version == "" || v.URL == "" || seen[v.Version] {
+			continue
+		}
+		seen[v.Version] = true
+		versions = append(versions, &FeedGbfsVersionsVersion{
+			Version: NewString(v.Version),
+			URL:     NewString(v.URL),
+		})
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return CompareVersions(*versions[i].Version, *versions[j].Version) < 0
+	})
+	return versions
+}
+
+// CompareVersions compares two GBFS versions numerically and returns -1, 0
+// or 1. Missing or non-numeric segments are treated as 0.
+func CompareVersions(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var av, bv int
+		if i < len(as) {
+			av, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			bv, _ = strconv.Atoi(bs[i])
+		}
+		if av < bv {
+			return -1
+		}
+		if av > bv {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (s *Server) manifestPathSegments() []string {
+	path := strings.Trim(s.Options.ManifestPath, "/")
+	if path == "" {
+		return nil
+	}
+	return []string{path}
+}
+
+func (s *Server) manifestURL() string {
+	if s.host != nil {
+		return s.host.manifestURL()
+	}
+	pathSegments := s.manifestPathSegments()
+	if pathSegments == nil {
+		return ""
+	}
+	return s.feedURL(pathSegments)
+}
+
+// setManifestURL fills manifest_url of system_information feed, when
+// manifest is published by server and handler has not set it.
+func (s *Server) setManifestURL(feed Feed) {
+	f, ok := feed.(*FeedSystemInformation)
+	if !ok || f.Data == nil || f.Data.ManifestURL != nil {
+		return
+	}
+	if url := s.manifestURL(); url != "" {
+		f.Data.ManifestURL = NewString(url)
+	}
+}
+
+func (s *Server) manifestFeed() *FeedManifest {
+	datasets := []*FeedManifestDataset{
+		{
+			SystemID: NewString(s.Options.SystemID),
+			Versions: manifestDatasetVersions(s.gbfsVersions()),
+		},
+	}
+	for _, system := range s.Options.Systems {
+		if system == nil || system.SystemID == "" || system.SystemID == s.Options.SystemID {
+			continue
+		}
+		datasets = append(datasets, &FeedManifestDataset{
+			SystemID: NewString(system.SystemID),
+			Versions: manifestDatasetVersions(feedGbfsVersions(system.Versions)),
+		})
+	}
+	feed := &FeedManifest{
+		Data: &FeedManifestData{
+			Datasets: datasets,
+		},
+	}
+	feed.SetTTL(s.Options.DefaultTTL)
+	feed.SetVersion(s.Options.Version)
+	return feed
+}
+
+func manifestDatasetVersions(versions []*FeedGbfsVersionsVersion) []*FeedManifestDatasetVersion {
+	datasetVersions := []*FeedManifestDatasetVersion{}
+	for _, v := range versions {
+		datasetVersions = append(datasetVersions, &FeedManifestDatasetVersion{
+			Version: v.Version,
+			URL:     v.URL,
+		})
+	}
+	return datasetVersions
+}
+
+// writeManifestFeed writes manifest.json covering this system and all
+// declared systems, when ManifestPath is configured.
+func (s *Server) writeManifestFeed() {
+	if s.host != nil {
+		return
+	}
+	pathSegments := s.manifestPathSegments()
+	if pathSegments == nil {
+		return
+	}
+	feed := s.manifestFeed()
+	err := s.putFeedIfChanged(pathSegments, feed, nowLastUpdated())
+	result := &PublishResult{
+		Published: err == nil,
+	}
+	if errors.Is(err, errFeedUnchanged) {
+		err = nil
+	}
+	s.reportFeed(feed, strings.Join(pathSegments, "/"), result, err)
+}
@@ -0,0 +1,333 @@
+package gbfs
+
+import (
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrFileNotFound = errors.New("file not found")
+
+type (
+	// FeedStore stores published files. Paths are slash separated and
+	// relative to root of store.
+	FeedStore interface {
+		Put(path string, data []byte) error
+		Delete(path string) error
+		List(prefix string) ([]string, error)
+	}
+	// FeedStoreSwapper is implemented by stores, which can replace all files
+	// under prefix in one step.
+	FeedStoreSwapper interface {
+		Swap(prefix string, files map[string][]byte) error
+	}
+)
+
+// SwapFeeds replaces all files under prefix with given files. Stores
+// implementing FeedStoreSwapper swap files in one step, otherwise all files
+// are put first and stale files are deleted after that.
+func SwapFeeds(store FeedStore, prefix string, files map[string][]byte) error {
+	if swapper, ok := store.(FeedStoreSwapper); ok {
+		return swapper.Swap(prefix, files)
+	}
+	for _, p := range sortedPaths(files) {
+		err := store.Put(p, files[p])
+		if err != nil {
+			return err
+		}
+	}
+	existing, err := store.List(prefix)
+	if err != nil {
+		return err
+	}
+	for _, p := range existing {
+		if _, ok := files[p]; ok {
+			continue
+		}
+		err := store.Delete(p)
+		if err != nil && !errors.Is(err, ErrFileNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedPaths(files map[string][]byte) []string {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func cleanStorePath(p string) string {
+	return strings.Trim(path.Clean("/"+p), "/")
+}
+
+func hasStorePrefix(p, prefix string) bool {
+	prefix = cleanStorePath(prefix)
+	return prefix == "" || p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+// FileFeedStore stores files in directory on local filesystem.
+type FileFeedStore struct {
+	RootDir string
+}
+
+func NewFileFeedStore(rootDir string) (*FileFeedStore, error) {
+	if rootDir == "" {
+		return nil, ErrMissingRootDir
+	}
+	return &FileFeedStore{
+		RootDir: rootDir,
+	}, nil
+}
+
+func (s *FileFeedStore) filePath(p string) string {
+	return filepath.Join(filepath.FromSlash(s.RootDir), filepath.FromSlash(cleanStorePath(p)))
+}
+
+// Put writes file to temporary file first and renames it, so readers never
+// see partially written file.
+func (s *FileFeedStore) Put(p string, data []byte) error {
+	filePath := s.filePath(p)
+	err := os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filePath, data)
+}
+
+func writeFileAtomic(filePath string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(0644)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filePath)
+}
+
+func (s *FileFeedStore) Delete(p string) error {
+	err := os.Remove(s.filePath(p))
+	if os.IsNotExist(err) {
+		return ErrFileNotFound
+	}
+	return err
+}
+
+func (s *FileFeedStore) List(prefix string) ([]string, error) {
+	root := filepath.FromSlash(s.RootDir)
+	dir := s.filePath(prefix)
+	paths := []string{}
+	err := filepath.WalkDir(dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Swap writes files to temporary directory and replaces directory of
+// prefix by renaming it. Files outside of prefix are put individually.
+func (s *FileFeedStore) Swap(prefix string, files map[string][]byte) error {
+	prefix = cleanStorePath(prefix)
+	if prefix == "" {
+		return swapFeedsFallback(s, files)
+	}
+	dir := s.filePath(prefix)
+	err := os.MkdirAll(filepath.Dir(dir), 0755)
+	if err != nil {
+		return err
+	}
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	tmpDir := filepath.Join(filepath.Dir(dir), "."+filepath.Base(dir)+".tmp"+suffix)
+	oldDir := filepath.Join(filepath.Dir(dir), "."+filepath.Base(dir)+".old"+suffix)
+	for _, p := range sortedPaths(files) {
+		if !hasStorePrefix(p, prefix) {
+			err := s.Put(p, files[p])
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(cleanStorePath(p), prefix), "/")
+		filePath := filepath.Join(tmpDir, filepath.FromSlash(rel))
+		err := os.MkdirAll(filepath.Dir(filePath), 0755)
+		if err == nil {
+			err = os.WriteFile(filePath, files[p], 0644)
+		}
+		if err != nil {
+			os.RemoveAll(tmpDir)
+			return err
+		}
+	}
+	err = os.Rename(dir, oldDir)
+	if err != nil && !os.IsNotExist(err) {
+		os.RemoveAll(tmpDir)
+		return err
+	}
+	err = os.Rename(tmpDir, dir)
+	if err != nil {
+		os.Rename(oldDir, dir)
+		os.RemoveAll(tmpDir)
+		return err
+	}
+	return os.RemoveAll(oldDir)
+}
+
+func swapFeedsFallback(store FeedStore, files map[string][]byte) error {
+	for _, p := range sortedPaths(files) {
+		err := store.Put(p, files[p])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MemoryFeedStore keeps files in memory and serves them over HTTP.
+type MemoryFeedStore struct {
+	mu       sync.RWMutex
+	files    map[string][]byte
+	modified map[string]time.Time
+}
+
+func NewMemoryFeedStore() *MemoryFeedStore {
+	return &MemoryFeedStore{
+		files:    make(map[string][]byte),
+		modified: make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryFeedStore) Put(p string, data []byte) error {
+	p = cleanStorePath(p)
+	s.mu.Lock()
+	s.files[p] = append([]byte{}, data...)
+	s.modified[p] = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryFeedStore) Delete(p string) error {
+	p = cleanStorePath(p)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.files[p]; !ok {
+		return ErrFileNotFound
+	}
+	delete(s.files, p)
+	delete(s.modified, p)
+	return nil
+}
+
+func (s *MemoryFeedStore) List(prefix string) ([]string, error) {
+	s.mu.RLock()
+	paths := []string{}
+	for p := range s.files {
+		if hasStorePrefix(p, prefix) {
+			paths = append(paths, p)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Get returns content of file.
+func (s *MemoryFeedStore) Get(p string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.files[cleanStorePath(p)]
+	if !ok {
+		return nil, ErrFileNotFound
+	}
+	return data, nil
+}
+
+// Swap replaces all files under prefix atomically.
+func (s *MemoryFeedStore) Swap(prefix string, files map[string][]byte) error {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for p := range s.files {
+		if hasStorePrefix(p, prefix) {
+			delete(s.files, p)
+			delete(s.modified, p)
+		}
+	}
+	for p, data := range files {
+		p = cleanStorePath(p)
+		s.files[p] = append([]byte{}, data...)
+		s.modified[p] = now
+	}
+	return nil
+}
+
+func (s *MemoryFeedStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	p := cleanStorePath(r.URL.Path)
+	w.Header().Add("Vary", "Accept-Encoding")
+	s.mu.RLock()
+	encoding, extension := negotiateEncoding(r, func(extension string) bool {
+		_, ok := s.files[p+extension]
+		return ok
+	})
+	data, ok := s.files[p+extension]
+	modified := s.modified[p+extension]
+	s.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if encoding != "" {
+		setEncodingHeaders(w, p, encoding)
+		http.ServeContent(w, r, p, modified, strings.NewReader(string(data)))
+		return
+	}
+	contentType := mime.TypeByExtension(path.Ext(p))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, p, modified, strings.NewReader(string(data)))
+}
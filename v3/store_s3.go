@@ -0,0 +1,295 @@
+package gbfs
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMissingS3Endpoint = errors.New("missing s3 endpoint")
+	ErrMissingS3Bucket   = errors.New("missing s3 bucket")
+)
+
+// S3FeedStore stores files in S3 compatible object storage. Requests are
+// signed with AWS Signature Version 4, unless AccessKeyID is empty. Local
+// stand-ins (MinIO, LocalStack, ...) usually require PathStyle.
+type S3FeedStore struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	PathStyle       bool
+	CacheControl    string
+	HTTPClient      *http.Client
+}
+
+type s3ListBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func NewS3FeedStore(store S3FeedStore) (*S3FeedStore, error) {
+	if store.Endpoint == "" {
+		return nil, ErrMissingS3Endpoint
+	}
+	if store.Bucket == "" {
+		return nil, ErrMissingS3Bucket
+	}
+	if store.Region == "" {
+		store.Region = "us-east-1"
+	}
+	if store.HTTPClient == nil {
+		store.HTTPClient = &http.Client{
+			Timeout: 30 * time.Second,
+		}
+	}
+	return &store, nil
+}
+
+func (s *S3FeedStore) key(p string) string {
+	return strings.Trim(path.Join(cleanStorePath(s.Prefix), cleanStorePath(p)), "/")
+}
+
+func (s *S3FeedStore) Put(p string, data []byte) error {
+	header := http.Header{}
+	contentType := mime.TypeByExtension(path.Ext(p))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header.Set("Content-Type", contentType)
+	if s.CacheControl != "" {
+		header.Set("Cache-Control", s.CacheControl)
+	}
+	res, err := s.do(http.MethodPut, s.key(p), nil, header, data)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+func (s *S3FeedStore) Delete(p string) error {
+	res, err := s.do(http.MethodDelete, s.key(p), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+func (s *S3FeedStore) List(prefix string) ([]string, error) {
+	keyPrefix := s.key(prefix)
+	if keyPrefix != "" {
+		keyPrefix += "/"
+	}
+	storePrefix := cleanStorePath(s.Prefix)
+	paths := []string{}
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		if keyPrefix != "" {
+			query.Set("prefix", keyPrefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		res, err := s.do(http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		result := &s3ListBucketResult{}
+		err = xml.NewDecoder(res.Body).Decode(result)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range result.Contents {
+			p := c.Key
+			if storePrefix != "" {
+				p = strings.TrimPrefix(strings.TrimPrefix(p, storePrefix), "/")
+			}
+			paths = append(paths, p)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (s *S3FeedStore) objectURL(key string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimRight(s.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	p := "/"
+	if s.PathStyle {
+		p += s.Bucket + "/"
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+	}
+	p += key
+	u.Path = p
+	u.RawPath = s3EncodePath(p)
+	return u, nil
+}
+
+func (s *S3FeedStore) do(method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	if query != nil {
+		u.RawQuery = s3CanonicalQuery(query)
+	}
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	s.sign(req, body, time.Now().UTC())
+	res, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound && method == http.MethodDelete {
+		res.Body.Close()
+		return nil, ErrFileNotFound
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		res.Body.Close()
+		return nil, errors.New("s3: " + method + " " + key + ": invalid response status: " + strconv.Itoa(res.StatusCode) + ": " + strings.TrimSpace(string(b)))
+	}
+	return res, nil
+}
+
+// sign adds AWS Signature Version 4 headers to request.
+func (s *S3FeedStore) sign(req *http.Request, body []byte, t time.Time) {
+	payloadHash := sha256Hex(body)
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+	if s.AccessKeyID == "" {
+		return
+	}
+	headers := map[string]string{
+		"host": req.URL.Host,
+	}
+	for k, v := range req.Header {
+		k = strings.ToLower(k)
+		if k == "content-type" || strings.HasPrefix(k, "x-amz-") {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	canonicalHeaders := strings.Builder{}
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKeyID+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Encode encodes string as required by Signature Version 4, all
+// characters except unreserved ones are percent encoded.
+func s3Encode(s string, encodeSlash bool) string {
+	b := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return b.String()
+}
+
+func s3EncodePath(p string) string {
+	return s3Encode(p, false)
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{}
+	for _, k := range keys {
+		values := append([]string{}, query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, s3Encode(k, true)+"="+s3Encode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
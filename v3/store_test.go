@@ -0,0 +1,129 @@
+package gbfs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// plainFeedStore hides Swap of store, so SwapFeeds falls back to put and
+// delete.
+type plainFeedStore struct {
+	FeedStore
+}
+
+func testFeedStore(t *testing.T, store FeedStore) {
+	t.Helper()
+	for _, p := range []string{"v3/gbfs.json", "v3/en/system_information.json", "other.json"} {
+		if err := store.Put(p, []byte(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths, err := store.List("v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(paths, []string{"v3/en/system_information.json", "v3/gbfs.json"}) {
+		t.Errorf("unexpected paths %v", paths)
+	}
+	if err := store.Delete("v3/gbfs.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("v3/gbfs.json"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("expected ErrFileNotFound, got %v", err)
+	}
+	err = SwapFeeds(store, "v3", map[string][]byte{
+		"v3/gbfs.json":                  []byte("new"),
+		"v3/en/station_status.json":     []byte("new"),
+		"v3/de/system_information.json": []byte("new"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, err = store.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"other.json", "v3/de/system_information.json", "v3/en/station_status.json", "v3/gbfs.json"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected %v after swap, got %v", expected, paths)
+	}
+	paths, err = store.List("missing")
+	if err != nil || len(paths) != 0 {
+		t.Errorf("expected no paths for missing prefix, got %v, %v", paths, err)
+	}
+}
+
+func TestFileFeedStore(t *testing.T) {
+	_, err := NewFileFeedStore("")
+	if !errors.Is(err, ErrMissingRootDir) {
+		t.Errorf("expected ErrMissingRootDir, got %v", err)
+	}
+	for _, plain := range []bool{false, true} {
+		dir := t.TempDir()
+		store, err := NewFileFeedStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plain {
+			testFeedStore(t, plainFeedStore{store})
+		} else {
+			testFeedStore(t, store)
+		}
+		b, err := os.ReadFile(filepath.Join(dir, "v3", "gbfs.json"))
+		if err != nil || string(b) != "new" {
+			t.Errorf("expected swapped file content, got %q, %v", b, err)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 {
+			t.Errorf("expected temporary directories to be removed, got %d entries", len(entries))
+		}
+	}
+	dir := t.TempDir()
+	if err := (&FileFeedStore{RootDir: filepath.Join(dir, "root")}).Put("../escape.json", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "root", "escape.json")); err != nil {
+		t.Errorf("expected file to stay in root directory, got %v", err)
+	}
+}
+
+func TestMemoryFeedStore(t *testing.T) {
+	testFeedStore(t, plainFeedStore{NewMemoryFeedStore()})
+	store := NewMemoryFeedStore()
+	testFeedStore(t, store)
+	b, err := store.Get("/v3/gbfs.json")
+	if err != nil || string(b) != "new" {
+		t.Errorf("expected swapped file content, got %q, %v", b, err)
+	}
+	_, err = store.Get("v3/en/system_information.json")
+	if !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("expected ErrFileNotFound for stale file, got %v", err)
+	}
+
+	srv := httptest.NewServer(store)
+	defer srv.Close()
+	res, err := http.Get(srv.URL + "/v3/gbfs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", res.StatusCode)
+	}
+	res, err = http.Get(srv.URL + "/v3/en/system_information.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,168 @@
+package validate
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	gbfs "github.com/petoc/gbfs/v3"
+)
+
+// rule checks scalar value and returns message of finding, or empty string
+// when value is valid.
+type rule struct {
+	id    string
+	check func(v any) string
+}
+
+func enum(values []string) *rule {
+	return &rule{
+		id: RuleEnum,
+		check: func(v any) string {
+			s, ok := v.(string)
+			if ok && gbfs.InSlice(s, values) {
+				return ""
+			}
+			return "invalid value " + quote(v) + ", expected one of " + strings.Join(values, ", ")
+		},
+	}
+}
+
+func inRange(min, max float64) *rule {
+	return &rule{
+		id: RuleRange,
+		check: func(v any) string {
+			f, ok := number(v)
+			if ok && f >= min && f <= max {
+				return ""
+			}
+			return "value " + quote(v) + " out of range [" + formatFloat(min) + ", " + formatFloat(max) + "]"
+		},
+	}
+}
+
+func format(layout, name string) *rule {
+	return &rule{
+		id: RuleFormat,
+		check: func(v any) string {
+			s, ok := v.(string)
+			if ok {
+				_, err := time.Parse(layout, s)
+				if err == nil {
+					return ""
+				}
+			}
+			return "invalid value " + quote(v) + ", expected " + name
+		},
+	}
+}
+
+var (
+	nonNegative = &rule{
+		id: RuleNonNegative,
+		check: func(v any) string {
+			f, ok := number(v)
+			if ok && f >= 0 {
+				return ""
+			}
+			return "value " + quote(v) + " must not be negative"
+		},
+	}
+	languagePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{1,8})*$`)
+	language        = &rule{
+		id: RuleLanguage,
+		check: func(v any) string {
+			s, ok := v.(string)
+			if ok && languagePattern.MatchString(s) {
+				return ""
+			}
+			return "invalid language code " + quote(v)
+		},
+	}
+	timestamp = format(time.RFC3339, "RFC3339 timestamp")
+	date      = format(gbfs.DateFormat, "date YYYY-MM-DD")
+)
+
+// skipKeys are not walked, coordinates of geometries are checked by
+// geometry validation.
+var skipKeys = map[string]bool{
+	"coordinates": true,
+}
+
+// signedKeys are numeric fields, which may be negative.
+var signedKeys = map[string]bool{
+	"rate": true,
+}
+
+// keyRules apply to fields with given name in any feed.
+var keyRules = map[string]*rule{
+	"lat":                  inRange(-90, 90),
+	"lon":                  inRange(-180, 180),
+	"language":             language,
+	"last_updated":         timestamp,
+	"last_reported":        timestamp,
+	"available_until":      timestamp,
+	"current_fuel_percent": inRange(0, 1),
+}
+
+// fieldRules apply to fields of feeds by path without indexes.
+var fieldRules = map[string]map[string]*rule{
+	gbfs.FeedNameGeofencingZones: {
+		"data.geofencing_zones.features[].properties.start": timestamp,
+		"data.geofencing_zones.features[].properties.end":   timestamp,
+	},
+	gbfs.FeedNameStationInformation: {
+		"data.stations[].rental_methods[]": enum(gbfs.RentalMethodAll()),
+		"data.stations[].parking_type":     enum(gbfs.ParkingTypeAll()),
+	},
+	gbfs.FeedNameSystemAlerts: {
+		"data.alerts[].type":          enum(gbfs.AlertTypeAll()),
+		"data.alerts[].times[].start": timestamp,
+		"data.alerts[].times[].end":   timestamp,
+	},
+	gbfs.FeedNameSystemInformation: {
+		"data.languages[]":                      language,
+		"data.start_date":                       date,
+		"data.termination_date":                 date,
+		"data.terms_last_updated":               date,
+		"data.privacy_last_updated":             date,
+		"data.brand_assets.brand_last_modified": date,
+	},
+	gbfs.FeedNameVehicleStatus: {
+		"data.vehicles[].vehicle_equipment[]": enum(gbfs.VehicleEquipmentAll()),
+	},
+	gbfs.FeedNameVehicleTypes: {
+		"data.vehicle_types[].form_factor":                         enum(gbfs.FormFactorAll()),
+		"data.vehicle_types[].propulsion_type":                     enum(gbfs.PropulsionTypeAll()),
+		"data.vehicle_types[].vehicle_accessories[]":               enum(gbfs.VehicleAccessoryAll()),
+		"data.vehicle_types[].return_constraint":                   enum(gbfs.ReturnConstraintAll()),
+		"data.vehicle_types[].vehicle_assets[].icon_last_modified": date,
+	},
+}
+
+func number(v any) (float64, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil && !math.IsNaN(f)
+}
+
+func quote(v any) string {
+	switch t := v.(type) {
+	case string:
+		return strconv.Quote(t)
+	case json.Number:
+		return t.String()
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,210 @@
+package validate
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+
+	gbfs "github.com/petoc/gbfs/v3"
+)
+
+const (
+	RuleVehicleCount = "vehicle_count"
+	RuleCapacity     = "capacity"
+	RuleRequiredFeed = "required_feed"
+)
+
+// System checks consistency of snapshot of system feeds keyed by feed name:
+// references between feeds, stations missing in station_status, counts of
+// vehicles per type, capacity of stations and feeds missing in gbfs.json.
+func System(feeds map[string]gbfs.Feed) Findings {
+	findings := Findings{}
+	names := []string{}
+	for name, feed := range feeds {
+		if present(feed) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		findings = append(findings, gbfs.ValidateReferences(feeds[name], feeds)...)
+	}
+	findings = append(findings, validateStations(feeds)...)
+	findings = append(findings, validateDiscovery(feeds, names)...)
+	return findings
+}
+
+func present(feed gbfs.Feed) bool {
+	return feed != nil && !reflect.ValueOf(feed).IsNil()
+}
+
+func validateStations(feeds map[string]gbfs.Feed) Findings {
+	findings := Findings{}
+	info, _ := feeds[gbfs.FeedNameStationInformation].(*gbfs.FeedStationInformation)
+	status, _ := feeds[gbfs.FeedNameStationStatus].(*gbfs.FeedStationStatus)
+	if info == nil || info.Data == nil || status == nil || status.Data == nil {
+		return findings
+	}
+	stations := map[gbfs.ID]*gbfs.FeedStationInformationStation{}
+	for _, v := range info.Data.Stations {
+		if v != nil && v.StationID != nil {
+			stations[*v.StationID] = v
+		}
+	}
+	reported := map[gbfs.ID]bool{}
+	for i, v := range status.Data.Stations {
+		if v == nil || v.StationID == nil {
+			continue
+		}
+		reported[*v.StationID] = true
+		path := "data.stations[" + strconv.Itoa(i) + "]"
+		if len(v.VehicleTypesAvailable) > 0 && v.NumVehiclesAvailable != nil {
+			var sum int64
+			for _, t := range v.VehicleTypesAvailable {
+				if t != nil && t.Count != nil {
+					sum += *t.Count
+				}
+			}
+			if sum != *v.NumVehiclesAvailable {
+				findings = append(findings, &Finding{
+					Severity: SeverityError,
+					Feed:     gbfs.FeedNameStationStatus,
+					Path:     path + ".vehicle_types_available",
+					Rule:     RuleVehicleCount,
+					Message:  "sum of counts " + strconv.FormatInt(sum, 10) + " does not match num_vehicles_available " + strconv.FormatInt(*v.NumVehiclesAvailable, 10),
+				})
+			}
+		}
+		station, ok := stations[*v.StationID]
+		if !ok || station.Capacity == nil {
+			continue
+		}
+		var used int64
+		if v.NumDocksAvailable != nil {
+			used += *v.NumDocksAvailable
+		}
+		if v.NumVehiclesAvailable != nil {
+			used += *v.NumVehiclesAvailable
+		}
+		if used > *station.Capacity {
+			findings = append(findings, &Finding{
+				Severity: SeverityError,
+				Feed:     gbfs.FeedNameStationStatus,
+				Path:     path,
+				Rule:     RuleCapacity,
+				Message:  "num_docks_available and num_vehicles_available " + strconv.FormatInt(used, 10) + " exceed capacity " + strconv.FormatInt(*station.Capacity, 10),
+			})
+		}
+	}
+	for i, v := range info.Data.Stations {
+		if v == nil || v.StationID == nil || reported[*v.StationID] {
+			continue
+		}
+		findings = append(findings, &Finding{
+			Severity: SeverityError,
+			Feed:     gbfs.FeedNameStationInformation,
+			Path:     "data.stations[" + strconv.Itoa(i) + "].station_id",
+			Rule:     RuleReference,
+			Message:  strconv.Quote(string(*v.StationID)) + " not found in " + gbfs.FeedNameStationStatus,
+		})
+	}
+	return findings
+}
+
+// validateDiscovery checks that gbfs.json lists system_information, feeds
+// present in snapshot and feeds referenced by them.
+func validateDiscovery(feeds map[string]gbfs.Feed, names []string) Findings {
+	findings := Findings{}
+	g, _ := feeds[gbfs.FeedNameGbfs].(*gbfs.FeedGbfs)
+	if g == nil || g.Data == nil {
+		return append(findings, &Finding{
+			Severity: SeverityError,
+			Feed:     gbfs.FeedNameGbfs,
+			Path:     "data.feeds",
+			Rule:     RuleRequiredFeed,
+			Message:  "missing gbfs feed",
+		})
+	}
+	listed := map[string]bool{}
+	for _, f := range g.Data.Feeds {
+		if f != nil && f.Name != nil {
+			listed[*f.Name] = true
+		}
+	}
+	required := map[string]string{
+		gbfs.FeedNameSystemInformation: "required feed",
+	}
+	for _, name := range names {
+		if name != gbfs.FeedNameGbfs && name != gbfs.FeedNameManifest {
+			required[name] = "present in snapshot"
+		}
+	}
+	if listed[gbfs.FeedNameStationInformation] || listed[gbfs.FeedNameStationStatus] {
+		required[gbfs.FeedNameStationInformation] = "required for docked system"
+		required[gbfs.FeedNameStationStatus] = "required for docked system"
+	}
+	for name, by := range referencedFeeds(feeds) {
+		required[name] = "referenced by " + by
+	}
+	missing := []string{}
+	for name := range required {
+		if !listed[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		findings = append(findings, &Finding{
+			Severity: SeverityError,
+			Feed:     gbfs.FeedNameGbfs,
+			Path:     "data.feeds",
+			Rule:     RuleRequiredFeed,
+			Message:  name + " not listed, " + required[name],
+		})
+	}
+	return findings
+}
+
+// referencedFeeds returns feeds referenced by identifiers in snapshot,
+// mapped to name of referencing feed.
+func referencedFeeds(feeds map[string]gbfs.Feed) map[string]string {
+	refs := map[string]string{}
+	if f, ok := feeds[gbfs.FeedNameVehicleStatus].(*gbfs.FeedVehicleStatus); ok && f != nil && f.Data != nil {
+		for _, v := range f.Data.Vehicles {
+			if v == nil {
+				continue
+			}
+			if v.VehicleTypeID != nil {
+				refs[gbfs.FeedNameVehicleTypes] = f.Name()
+			}
+			if v.PricingPlanID != nil {
+				refs[gbfs.FeedNameSystemPricingPlans] = f.Name()
+			}
+			if v.StationID != nil || v.HomeStationID != nil {
+				refs[gbfs.FeedNameStationInformation] = f.Name()
+			}
+		}
+	}
+	if f, ok := feeds[gbfs.FeedNameStationStatus].(*gbfs.FeedStationStatus); ok && f != nil && f.Data != nil {
+		for _, v := range f.Data.Stations {
+			if v != nil && len(v.VehicleTypesAvailable) > 0 {
+				refs[gbfs.FeedNameVehicleTypes] = f.Name()
+			}
+		}
+	}
+	if f, ok := feeds[gbfs.FeedNameStationInformation].(*gbfs.FeedStationInformation); ok && f != nil && f.Data != nil {
+		for _, v := range f.Data.Stations {
+			if v != nil && v.RegionID != nil {
+				refs[gbfs.FeedNameSystemRegions] = f.Name()
+			}
+		}
+	}
+	if f, ok := feeds[gbfs.FeedNameVehicleTypes].(*gbfs.FeedVehicleTypes); ok && f != nil && f.Data != nil {
+		for _, v := range f.Data.VehicleTypes {
+			if v != nil && (v.DefaultPricingPlanID != nil || len(v.PricingPlanIDs) > 0) {
+				refs[gbfs.FeedNameSystemPricingPlans] = f.Name()
+			}
+		}
+	}
+	return refs
+}
@@ -0,0 +1,41 @@
+package validate
+
+import (
+	"encoding/json"
+	"testing"
+
+	gbfs "github.com/petoc/gbfs/v3"
+)
+
+func TestSystemNullElements(t *testing.T) {
+	docs := map[string]string{
+		gbfs.FeedNameGbfs:               `{"data":{"feeds":[null,{"name":"station_information"}]}}`,
+		gbfs.FeedNameStationInformation: `{"data":{"stations":[null,{"station_id":"s1","vehicle_types_capacity":[null,{"vehicle_type_ids":[null]}],"vehicle_docks_capacity":[null]}]}}`,
+		gbfs.FeedNameStationStatus:      `{"data":{"stations":[null,{"station_id":"s1","vehicle_types_available":[null],"vehicle_docks_available":[null,{"vehicle_type_ids":[null]}]}]}}`,
+		gbfs.FeedNameVehicleStatus:      `{"data":{"vehicles":[null]}}`,
+		gbfs.FeedNameVehicleTypes:       `{"data":{"vehicle_types":[null]}}`,
+		gbfs.FeedNameGeofencingZones:    `{"data":{"global_rules":[null],"geofencing_zones":{"type":"FeatureCollection","features":[{"properties":{"rules":[null]}}]}}}`,
+		gbfs.FeedNameSystemAlerts:       `{"data":{"alerts":[null,{"station_ids":[null]}]}}`,
+		gbfs.FeedNameSystemRegions:      `{"data":{"regions":[null]}}`,
+		gbfs.FeedNameSystemPricingPlans: `{"data":{"plans":[null]}}`,
+	}
+	feeds := map[string]gbfs.Feed{}
+	for name, doc := range docs {
+		f := gbfs.FeedStruct(name)
+		if err := json.Unmarshal([]byte(doc), f); err != nil {
+			t.Fatal(name, err)
+		}
+		feeds[name] = f
+	}
+	System(feeds)
+	findings := gbfs.ValidateFeed(feeds[gbfs.FeedNameStationInformation], gbfs.V30)
+	found := false
+	for _, f := range findings {
+		if f.Path == "data.stations[0]" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected null station to be reported")
+	}
+}
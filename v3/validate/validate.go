@@ -0,0 +1,114 @@
+// Package validate checks decoded feeds against rules of GBFS specification
+// and reports findings with severity, JSON path and rule ID.
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	gbfs "github.com/petoc/gbfs/v3"
+)
+
+type (
+	Finding  = gbfs.ValidationError
+	Findings = gbfs.ValidationErrors
+)
+
+const (
+	SeverityError   = gbfs.SeverityError
+	SeverityWarning = gbfs.SeverityWarning
+
+	RuleRequired              = gbfs.RuleRequired
+	RuleConditionallyRequired = gbfs.RuleConditionallyRequired
+	RuleReference             = gbfs.RuleReference
+	RuleVersion               = gbfs.RuleVersion
+	RuleEnum                  = "enum"
+	RuleRange                 = "range"
+	RuleNonNegative           = "non_negative"
+	RuleFormat                = "format"
+	RuleLanguage              = "language"
+)
+
+// Feed checks feed against rules of given version: required fields, enum
+// membership, coordinate ranges, non-negative numbers, formats of
+// timestamps, dates and times, and language codes.
+func Feed(feed gbfs.Feed, version string) Findings {
+	findings := gbfs.ValidateFeed(feed, version)
+	if version != gbfs.V30 {
+		return findings
+	}
+	b, err := json.Marshal(feed)
+	if err != nil {
+		return findings
+	}
+	var v any
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	err = d.Decode(&v)
+	if err != nil {
+		return findings
+	}
+	w := &walker{
+		feed:     feed.Name(),
+		rules:    fieldRules[feed.Name()],
+		findings: findings,
+	}
+	w.walk("", "", "", v)
+	return w.findings
+}
+
+type walker struct {
+	feed     string
+	rules    map[string]*rule
+	findings Findings
+}
+
+// walk applies rules to scalar values of decoded feed. Path is JSON path
+// of value, generic path is path without indexes used to look up rules.
+func (w *walker) walk(path, generic, key string, v any) {
+	switch t := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if skipKeys[k] {
+				continue
+			}
+			if path == "" {
+				w.walk(k, k, k, t[k])
+				continue
+			}
+			w.walk(path+"."+k, generic+"."+k, k, t[k])
+		}
+	case []any:
+		for i, item := range t {
+			w.walk(path+"["+strconv.Itoa(i)+"]", generic+"[]", key, item)
+		}
+	case nil:
+	default:
+		r, ok := w.rules[generic]
+		if !ok {
+			r, ok = keyRules[key]
+		}
+		if !ok {
+			if _, isNumber := v.(json.Number); !isNumber || signedKeys[key] {
+				return
+			}
+			r = nonNegative
+		}
+		if msg := r.check(v); msg != "" {
+			w.findings = append(w.findings, &Finding{
+				Severity: SeverityError,
+				Feed:     w.feed,
+				Path:     path,
+				Rule:     r.id,
+				Message:  msg,
+			})
+		}
+	}
+}
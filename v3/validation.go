@@ -0,0 +1,470 @@
+package gbfs
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type ValidationMode int
+
+const (
+	ValidationModeOff ValidationMode = iota
+	ValidationModeWarn
+	ValidationModeStrict
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+
+	RuleRequired              = "required"
+	RuleConditionallyRequired = "conditionally_required"
+	RuleReference             = "reference"
+	RuleVersion               = "version"
+)
+
+type (
+	ValidationError struct {
+		Severity string `json:"severity"`
+		Feed     string `json:"feed"`
+		Path     string `json:"path"`
+		Rule     string `json:"rule"`
+		Message  string `json:"message"`
+	}
+	ValidationErrors []*ValidationError
+)
+
+func (e *ValidationError) Error() string {
+	return e.Severity + ": " + e.Feed + ": " + e.Path + ": " + e.Message + " (" + e.Rule + ")"
+}
+
+func (e ValidationErrors) Error() string {
+	msgs := []string{}
+	for _, v := range e {
+		msgs = append(msgs, v.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// HasErrors reports whether there is at least one finding with error
+// severity.
+func (e ValidationErrors) HasErrors() bool {
+	for _, v := range e {
+		if v.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFeed checks feed for missing required fields of given version.
+// Fields without omitempty in json tag are considered required, except
+// conditionally required fields, which are checked separately.
+func ValidateFeed(feed Feed, version string) ValidationErrors {
+	errs := ValidationErrors{}
+	if version != V30 {
+		return append(errs, &ValidationError{
+			Severity: SeverityWarning,
+			Feed:     feed.Name(),
+			Path:     "version",
+			Rule:     RuleVersion,
+			Message:  "unsupported version " + strconv.Quote(version),
+		})
+	}
+	if feed.GetVersion() == "" {
+		errs = append(errs, requiredError(feed.Name(), "version"))
+	}
+	validateRequired(feed.Name(), "", reflect.ValueOf(feed), &errs)
+	validateConditional(feed, &errs)
+	return errs
+}
+
+func requiredError(feed, path string) *ValidationError {
+	return &ValidationError{
+		Severity: SeverityError,
+		Feed:     feed,
+		Path:     path,
+		Rule:     RuleRequired,
+		Message:  "missing required field",
+	}
+}
+
+// conditionalFields are fields without omitempty, which are required only
+// in some cases.
+var conditionalFields = map[string]bool{
+	FeedNameSystemInformation + ".data.terms_last_updated":   true,
+	FeedNameSystemInformation + ".data.privacy_last_updated": true,
+	FeedNameVehicleStatus + ".data.vehicles[].lat":           true,
+	FeedNameVehicleStatus + ".data.vehicles[].lon":           true,
+}
+
+func validateRequired(feed, path string, v reflect.Value, errs *ValidationErrors) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			elemPath := path + "[" + strconv.Itoa(i) + "]"
+			if isNil(v.Index(i)) {
+				// null element of list
+				*errs = append(*errs, requiredError(feed, elemPath))
+				continue
+			}
+			validateRequired(feed, elemPath, v.Index(i), errs)
+		}
+	case reflect.Struct:
+		for _, field := range jsonFields(v) {
+			fieldPath := field.name
+			if path != "" {
+				fieldPath = path + "." + field.name
+			}
+			if !field.omitempty && isNil(field.value) && !conditionalFields[feed+"."+genericPath(fieldPath)] {
+				*errs = append(*errs, requiredError(feed, fieldPath))
+				continue
+			}
+			validateRequired(feed, fieldPath, field.value, errs)
+		}
+	}
+}
+
+type jsonField struct {
+	name      string
+	omitempty bool
+	value     reflect.Value
+}
+
+// jsonFields returns fields of struct as seen by encoding/json, fields of
+// embedded structs are shadowed by fields of outer struct.
+func jsonFields(v reflect.Value) []*jsonField {
+	fields := []*jsonField{}
+	embedded := []reflect.Value{}
+	seen := map[string]bool{}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			if f.Type.Kind() == reflect.Struct {
+				embedded = append(embedded, v.Field(i))
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name := strings.TrimSpace(parts[0])
+		if name == "" {
+			name = f.Name
+		}
+		seen[name] = true
+		fields = append(fields, &jsonField{
+			name:      name,
+			omitempty: InSlice("omitempty", parts[1:]),
+			value:     v.Field(i),
+		})
+	}
+	for _, e := range embedded {
+		for _, field := range jsonFields(e) {
+			if !seen[field.name] {
+				seen[field.name] = true
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	}
+	return false
+}
+
+// genericPath removes indexes from path.
+func genericPath(path string) string {
+	b := strings.Builder{}
+	skip := false
+	for _, r := range path {
+		switch {
+		case r == '[':
+			skip = true
+			b.WriteRune(r)
+		case r == ']':
+			skip = false
+			b.WriteRune(r)
+		case !skip:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func validateConditional(feed Feed, errs *ValidationErrors) {
+	conditional := func(path, message string) {
+		*errs = append(*errs, &ValidationError{
+			Severity: SeverityError,
+			Feed:     feed.Name(),
+			Path:     path,
+			Rule:     RuleConditionallyRequired,
+			Message:  message,
+		})
+	}
+	switch f := feed.(type) {
+	case *FeedSystemInformation:
+		if f.Data == nil {
+			return
+		}
+		if f.Data.TermsURL != nil && f.Data.TermsLastUpdated == nil {
+			conditional("data.terms_last_updated", "required when terms_url is set")
+		}
+		if f.Data.PrivacyURL != nil && f.Data.PrivacyLastUpdated == nil {
+			conditional("data.privacy_last_updated", "required when privacy_url is set")
+		}
+	case *FeedVehicleStatus:
+		if f.Data == nil {
+			return
+		}
+		for i, v := range f.Data.Vehicles {
+			if v == nil || v.StationID != nil {
+				continue
+			}
+			path := "data.vehicles[" + strconv.Itoa(i) + "]"
+			if v.Lat == nil {
+				conditional(path+".lat", "required when station_id is not set")
+			}
+			if v.Lon == nil {
+				conditional(path+".lon", "required when station_id is not set")
+			}
+		}
+	case *FeedVehicleTypes:
+		if f.Data == nil {
+			return
+		}
+		for i, v := range f.Data.VehicleTypes {
+			if v == nil || v.PropulsionType == nil || *v.PropulsionType == PropulsionTypeHuman {
+				continue
+			}
+			if v.MaxRangeMeters == nil {
+				conditional("data.vehicle_types["+strconv.Itoa(i)+"].max_range_meters", "required for motorized vehicles")
+			}
+		}
+	}
+}
+
+// ValidateReferences checks that identifiers referenced by feed exist in
+// other feeds. Feeds are keyed by feed name, references to feeds which are
+// not present are not checked. Null elements of lists are skipped, they
+// are reported by ValidateFeed.
+func ValidateReferences(feed Feed, feeds map[string]Feed) ValidationErrors {
+	errs := ValidationErrors{}
+	if feed == nil || reflect.ValueOf(feed).IsNil() {
+		return errs
+	}
+	stationIDs := feedIDs(feeds[FeedNameStationInformation])
+	vehicleTypeIDs := feedIDs(feeds[FeedNameVehicleTypes])
+	regionIDs := feedIDs(feeds[FeedNameSystemRegions])
+	planIDs := feedIDs(feeds[FeedNameSystemPricingPlans])
+	check := func(ids map[ID]bool, id *ID, path, target string) {
+		if ids == nil || id == nil || ids[*id] {
+			return
+		}
+		errs = append(errs, &ValidationError{
+			Severity: SeverityError,
+			Feed:     feed.Name(),
+			Path:     path,
+			Rule:     RuleReference,
+			Message:  strconv.Quote(string(*id)) + " not found in " + target,
+		})
+	}
+	switch f := feed.(type) {
+	case *FeedStationStatus:
+		if f.Data == nil {
+			break
+		}
+		for i, v := range f.Data.Stations {
+			if v == nil {
+				continue
+			}
+			path := "data.stations[" + strconv.Itoa(i) + "]"
+			check(stationIDs, v.StationID, path+".station_id", FeedNameStationInformation)
+			for j, t := range v.VehicleTypesAvailable {
+				if t == nil {
+					continue
+				}
+				check(vehicleTypeIDs, t.VehicleTypeID, path+".vehicle_types_available["+strconv.Itoa(j)+"].vehicle_type_id", FeedNameVehicleTypes)
+			}
+			for j, d := range v.VehicleDocksAvailable {
+				if d == nil {
+					continue
+				}
+				for k, id := range d.VehicleTypeIDs {
+					check(vehicleTypeIDs, id, path+".vehicle_docks_available["+strconv.Itoa(j)+"].vehicle_type_ids["+strconv.Itoa(k)+"]", FeedNameVehicleTypes)
+				}
+			}
+		}
+	case *FeedVehicleStatus:
+		if f.Data == nil {
+			break
+		}
+		for i, v := range f.Data.Vehicles {
+			if v == nil {
+				continue
+			}
+			path := "data.vehicles[" + strconv.Itoa(i) + "]"
+			check(vehicleTypeIDs, v.VehicleTypeID, path+".vehicle_type_id", FeedNameVehicleTypes)
+			check(stationIDs, v.StationID, path+".station_id", FeedNameStationInformation)
+			check(stationIDs, v.HomeStationID, path+".home_station_id", FeedNameStationInformation)
+			check(planIDs, v.PricingPlanID, path+".pricing_plan_id", FeedNameSystemPricingPlans)
+		}
+	case *FeedStationInformation:
+		if f.Data == nil {
+			break
+		}
+		for i, v := range f.Data.Stations {
+			if v == nil {
+				continue
+			}
+			path := "data.stations[" + strconv.Itoa(i) + "]"
+			check(regionIDs, v.RegionID, path+".region_id", FeedNameSystemRegions)
+			for j, c := range v.VehicleTypesCapacity {
+				if c == nil {
+					continue
+				}
+				for k, id := range c.VehicleTypeIDs {
+					check(vehicleTypeIDs, id, path+".vehicle_types_capacity["+strconv.Itoa(j)+"].vehicle_type_ids["+strconv.Itoa(k)+"]", FeedNameVehicleTypes)
+				}
+			}
+			for j, c := range v.VehicleDocksCapacity {
+				if c == nil {
+					continue
+				}
+				for k, id := range c.VehicleTypeIDs {
+					check(vehicleTypeIDs, id, path+".vehicle_docks_capacity["+strconv.Itoa(j)+"].vehicle_type_ids["+strconv.Itoa(k)+"]", FeedNameVehicleTypes)
+				}
+			}
+		}
+	case *FeedGeofencingZones:
+		if f.Data == nil {
+			break
+		}
+		checkRules := func(path string, rules []*FeedGeofencingZonesRule) {
+			for i, r := range rules {
+				if r == nil {
+					continue
+				}
+				for j, id := range r.VehicleTypeIDs {
+					check(vehicleTypeIDs, id, path+"["+strconv.Itoa(i)+"].vehicle_type_ids["+strconv.Itoa(j)+"]", FeedNameVehicleTypes)
+				}
+			}
+		}
+		checkRules("data.global_rules", f.Data.GlobalRules)
+		if f.Data.GeofencingZones != nil {
+			for i, z := range f.Data.GeofencingZones.Features {
+				if z != nil && z.Properties != nil {
+					checkRules("data.geofencing_zones.features["+strconv.Itoa(i)+"].properties.rules", z.Properties.Rules)
+				}
+			}
+		}
+	case *FeedVehicleTypes:
+		if f.Data == nil {
+			break
+		}
+		for i, v := range f.Data.VehicleTypes {
+			if v == nil {
+				continue
+			}
+			path := "data.vehicle_types[" + strconv.Itoa(i) + "]"
+			check(planIDs, v.DefaultPricingPlanID, path+".default_pricing_plan_id", FeedNameSystemPricingPlans)
+			for j, id := range v.PricingPlanIDs {
+				check(planIDs, id, path+".pricing_plan_ids["+strconv.Itoa(j)+"]", FeedNameSystemPricingPlans)
+			}
+		}
+	case *FeedSystemAlerts:
+		if f.Data == nil {
+			break
+		}
+		for i, v := range f.Data.Alerts {
+			if v == nil {
+				continue
+			}
+			path := "data.alerts[" + strconv.Itoa(i) + "]"
+			for j, id := range v.StationIDs {
+				check(stationIDs, id, path+".station_ids["+strconv.Itoa(j)+"]", FeedNameStationInformation)
+			}
+			for j, id := range v.RegionIDs {
+				check(regionIDs, id, path+".region_ids["+strconv.Itoa(j)+"]", FeedNameSystemRegions)
+			}
+		}
+	}
+	return errs
+}
+
+// feedIDs returns set of primary identifiers of feed, or nil when feed is
+// not available.
+func feedIDs(feed Feed) map[ID]bool {
+	if feed == nil || reflect.ValueOf(feed).IsNil() {
+		return nil
+	}
+	ids := map[ID]bool{}
+	add := func(id *ID) {
+		if id != nil {
+			ids[*id] = true
+		}
+	}
+	switch f := feed.(type) {
+	case *FeedStationInformation:
+		if f.Data == nil {
+			return nil
+		}
+		for _, v := range f.Data.Stations {
+			if v == nil {
+				continue
+			}
+			add(v.StationID)
+		}
+	case *FeedVehicleTypes:
+		if f.Data == nil {
+			return nil
+		}
+		for _, v := range f.Data.VehicleTypes {
+			if v == nil {
+				continue
+			}
+			add(v.VehicleTypeID)
+		}
+	case *FeedSystemRegions:
+		if f.Data == nil {
+			return nil
+		}
+		for _, v := range f.Data.Regions {
+			if v == nil {
+				continue
+			}
+			add(v.RegionID)
+		}
+	case *FeedSystemPricingPlans:
+		if f.Data == nil {
+			return nil
+		}
+		for _, v := range f.Data.Plans {
+			if v == nil {
+				continue
+			}
+			add(v.PlanID)
+		}
+	default:
+		return nil
+	}
+	return ids
+}
@@ -0,0 +1,109 @@
+package gbfs
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateFeedRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		feed     string
+		version  string
+		doc      string
+		expected []string
+		absent   []string
+	}{
+		{
+			name:     "required fields",
+			feed:     FeedNameSystemInformation,
+			version:  V30,
+			doc:      `{"version":"3.0","data":{"system_id":"s","languages":["en"],"timezone":"Europe/Bratislava"}}`,
+			expected: []string{"data.name required", "last_updated required"},
+			absent:   []string{"data.system_id required", "version required"},
+		},
+		{
+			name:     "missing version",
+			feed:     FeedNameSystemInformation,
+			version:  V30,
+			doc:      `{"data":{}}`,
+			expected: []string{"version required"},
+		},
+		{
+			name:     "unsupported version",
+			feed:     FeedNameSystemInformation,
+			version:  "2.3",
+			doc:      `{"data":{}}`,
+			expected: []string{"version version"},
+			absent:   []string{"data.name required"},
+		},
+		{
+			name:     "terms and privacy",
+			feed:     FeedNameSystemInformation,
+			version:  V30,
+			doc:      `{"data":{"terms_url":[{"text":"https://t","language":"en"}],"privacy_url":[{"text":"https://p","language":"en"}],"privacy_last_updated":"2024-01-01"}}`,
+			expected: []string{"data.terms_last_updated conditionally_required"},
+			absent:   []string{"data.terms_last_updated required", "data.privacy_last_updated conditionally_required"},
+		},
+		{
+			name:     "without terms",
+			feed:     FeedNameSystemInformation,
+			version:  V30,
+			doc:      `{"data":{}}`,
+			expected: []string{"data.system_id required"},
+			absent:   []string{"data.terms_last_updated conditionally_required", "data.terms_last_updated required"},
+		},
+		{
+			name:     "vehicle position",
+			feed:     FeedNameVehicleStatus,
+			version:  V30,
+			doc:      `{"data":{"vehicles":[{"vehicle_id":"v1","is_reserved":false,"is_disabled":false},{"vehicle_id":"v2","station_id":"s1","is_reserved":false,"is_disabled":false}]}}`,
+			expected: []string{"data.vehicles[0].lat conditionally_required", "data.vehicles[0].lon conditionally_required"},
+			absent:   []string{"data.vehicles[0].lat required", "data.vehicles[1].lat conditionally_required"},
+		},
+		{
+			name:     "range of motorized vehicles",
+			feed:     FeedNameVehicleTypes,
+			version:  V30,
+			doc:      `{"data":{"vehicle_types":[{"vehicle_type_id":"e","form_factor":"scooter","propulsion_type":"electric"},{"vehicle_type_id":"h","form_factor":"bicycle","propulsion_type":"human"}]}}`,
+			expected: []string{"data.vehicle_types[0].max_range_meters conditionally_required"},
+			absent:   []string{"data.vehicle_types[1].max_range_meters conditionally_required"},
+		},
+		{
+			name:     "null elements",
+			feed:     FeedNameStationStatus,
+			version:  V30,
+			doc:      `{"data":{"stations":[null,{"station_id":"s1","vehicle_types_available":[null]}]}}`,
+			expected: []string{"data.stations[0] required", "data.stations[1].vehicle_types_available[0] required", "data.stations[1].num_vehicles_available required"},
+			absent:   []string{"data.stations[1].station_id required"},
+		},
+		{
+			name:     "null vehicles",
+			feed:     FeedNameVehicleStatus,
+			version:  V30,
+			doc:      `{"data":{"vehicles":[null]}}`,
+			expected: []string{"data.vehicles[0] required"},
+			absent:   []string{"data.vehicles[0].lat conditionally_required"},
+		},
+	}
+	for _, test := range tests {
+		f := FeedStruct(test.feed)
+		if err := json.Unmarshal([]byte(test.doc), f); err != nil {
+			t.Fatal(test.name, err)
+		}
+		found := map[string]bool{}
+		for _, e := range ValidateFeed(f, test.version) {
+			found[e.Path+" "+e.Rule] = true
+		}
+		for _, v := range test.expected {
+			if !found[v] {
+				t.Errorf("%s: expected finding %s, got %v", test.name, v, found)
+			}
+		}
+		for _, v := range test.absent {
+			if found[v] {
+				t.Errorf("%s: unexpected finding %s", test.name, v)
+			}
+		}
+	}
+}
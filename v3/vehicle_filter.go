@@ -0,0 +1,197 @@
+package gbfs
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultPrivacyZonePrecision is number of decimal places coordinates are
+// rounded to in privacy zone without Precision, about 110 meters.
+const DefaultPrivacyZonePrecision = 3
+
+type (
+	// VehicleFilter removes vehicles from vehicle_status and coarsens their
+	// positions before feed is published.
+	VehicleFilter struct {
+		ExcludeReserved bool
+		ExcludeDisabled bool
+		// Exclude removes vehicles for which it returns true.
+		Exclude func(vehicle *FeedVehicleStatusVehicle) bool
+		// Zones coarsen coordinates of vehicles inside them, first matching
+		// zone is used.
+		Zones []*PrivacyZone
+		// ParkedDelay hides vehicles until they stay parked at the same
+		// station or position (within ParkedDistance meters, default 50)
+		// for given duration. Vehicles of first filtered feed are considered
+		// parked already, unless DelayInitialVehicles is set, so that fleet
+		// is not hidden after restart.
+		ParkedDelay          time.Duration
+		ParkedDistance       float64
+		DelayInitialVehicles bool
+		mu                   sync.Mutex
+		parked               map[ID]*parkedVehicle
+		started              bool
+	}
+	// PrivacyZone snaps coordinates of vehicles inside Polygon or
+	// MultiPolygon geometry to SnapLat and SnapLon, or rounds them to
+	// Precision decimal places, when snap point is not set. Zero Precision
+	// means DefaultPrivacyZonePrecision.
+	PrivacyZone struct {
+		Geometry  *GeoJSONGeometry
+		SnapLat   *float64
+		SnapLon   *float64
+		Precision int
+		once      sync.Once
+		polygons  GeoJSONMultiPolygon
+		err       error
+	}
+	parkedVehicle struct {
+		stationID ID
+		lat       *float64
+		lon       *float64
+		since     time.Time
+	}
+)
+
+// FilterFeed returns copy of vehicle_status feed with vehicles filtered,
+// other feeds are returned unchanged.
+func (f *VehicleFilter) FilterFeed(feed Feed) Feed {
+	v, ok := feed.(*FeedVehicleStatus)
+	if !ok || v == nil || v.Data == nil {
+		return feed
+	}
+	now := time.Now()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	seen := map[ID]bool{}
+	vehicles := []*FeedVehicleStatusVehicle{}
+	for _, vehicle := range v.Data.Vehicles {
+		if vehicle == nil {
+			continue
+		}
+		if vehicle.VehicleID != nil {
+			seen[*vehicle.VehicleID] = true
+		}
+		if !f.parkedLongEnough(vehicle, now) || f.excluded(vehicle) {
+			continue
+		}
+		vehicles = append(vehicles, f.coarsen(vehicle))
+	}
+	for id := range f.parked {
+		if !seen[id] {
+			delete(f.parked, id)
+		}
+	}
+	f.started = true
+	filtered := &FeedVehicleStatus{
+		Data: &FeedVehicleStatusData{
+			Vehicles: vehicles,
+		},
+	}
+	filtered.LastUpdated = v.LastUpdated
+	filtered.TTL = v.TTL
+	filtered.Version = v.Version
+	return filtered
+}
+
+func (f *VehicleFilter) excluded(vehicle *FeedVehicleStatusVehicle) bool {
+	if f.ExcludeReserved && vehicle.IsReserved != nil && bool(*vehicle.IsReserved) {
+		return true
+	}
+	if f.ExcludeDisabled && vehicle.IsDisabled != nil && bool(*vehicle.IsDisabled) {
+		return true
+	}
+	return f.Exclude != nil && f.Exclude(vehicle)
+}
+
+func (f *VehicleFilter) parkedLongEnough(vehicle *FeedVehicleStatusVehicle, now time.Time) bool {
+	if f.ParkedDelay <= 0 || vehicle.VehicleID == nil {
+		return true
+	}
+	if f.parked == nil {
+		f.parked = make(map[ID]*parkedVehicle)
+	}
+	current := &parkedVehicle{
+		since: now,
+	}
+	if vehicle.StationID != nil {
+		current.stationID = *vehicle.StationID
+	}
+	if vehicle.Lat != nil && vehicle.Lon != nil {
+		current.lat = NewFloat64(vehicle.Lat.Float64)
+		current.lon = NewFloat64(vehicle.Lon.Float64)
+	}
+	prev, ok := f.parked[*vehicle.VehicleID]
+	if !ok && !f.started && !f.DelayInitialVehicles {
+		current.since = now.Add(-f.ParkedDelay)
+		f.parked[*vehicle.VehicleID] = current
+		return true
+	}
+	if !ok || f.moved(prev, current) {
+		f.parked[*vehicle.VehicleID] = current
+		return false
+	}
+	return now.Sub(prev.since) >= f.ParkedDelay
+}
+
+func (f *VehicleFilter) moved(prev, current *parkedVehicle) bool {
+	if prev.stationID != "" || current.stationID != "" {
+		return prev.stationID != current.stationID
+	}
+	if prev.lat == nil || current.lat == nil {
+		return (prev.lat == nil) != (current.lat == nil)
+	}
+	maxDistance := f.ParkedDistance
+	if maxDistance <= 0 {
+		maxDistance = 50
+	}
+	return distance(*prev.lat, *prev.lon, *current.lat, *current.lon) > maxDistance
+}
+
+func (f *VehicleFilter) coarsen(vehicle *FeedVehicleStatusVehicle) *FeedVehicleStatusVehicle {
+	if vehicle.Lat == nil || vehicle.Lon == nil {
+		return vehicle
+	}
+	for _, zone := range f.Zones {
+		if !zone.Contains(vehicle.Lat.Float64, vehicle.Lon.Float64) {
+			continue
+		}
+		lat, lon := zone.coarsen(vehicle.Lat.Float64, vehicle.Lon.Float64)
+		coarsened := *vehicle
+		coarsened.Lat = NewCoordinate(lat)
+		coarsened.Lon = NewCoordinate(lon)
+		return &coarsened
+	}
+	return vehicle
+}
+
+func (z *PrivacyZone) coarsen(lat, lon float64) (float64, float64) {
+	if z.SnapLat != nil && z.SnapLon != nil {
+		return *z.SnapLat, *z.SnapLon
+	}
+	precision := z.Precision
+	if precision == 0 {
+		precision = DefaultPrivacyZonePrecision
+	}
+	p := math.Pow(10, float64(precision))
+	return math.Round(lat*p) / p, math.Round(lon*p) / p
+}
+
+// Validate returns error, when geometry of zone is not valid Polygon or
+// MultiPolygon.
+func (z *PrivacyZone) Validate() error {
+	z.once.Do(func() {
+		z.polygons, z.err = z.Geometry.MultiPolygon()
+	})
+	return z.err
+}
+
+// Contains reports whether point is inside zone. Zone with invalid geometry
+// contains no point.
+func (z *PrivacyZone) Contains(lat, lon float64) bool {
+	if z.Validate() != nil {
+		return false
+	}
+	return z.polygons.Contains(lon, lat)
+}
@@ -0,0 +1,81 @@
+package gbfs
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func filterVehicles(f *VehicleFilter, vehicles ...*FeedVehicleStatusVehicle) []*FeedVehicleStatusVehicle {
+	feed := f.FilterFeed(&FeedVehicleStatus{
+		Data: &FeedVehicleStatusData{
+			Vehicles: vehicles,
+		},
+	})
+	return feed.(*FeedVehicleStatus).Data.Vehicles
+}
+
+func TestVehicleFilterParkedDelay(t *testing.T) {
+	v1 := &FeedVehicleStatusVehicle{VehicleID: NewID("v1"), StationID: NewID("s1")}
+	v2 := &FeedVehicleStatusVehicle{VehicleID: NewID("v2"), StationID: NewID("s1")}
+	f := &VehicleFilter{ParkedDelay: time.Hour}
+	if vehicles := filterVehicles(f, v1); len(vehicles) != 1 {
+		t.Fatal("vehicle of first feed was hidden")
+	}
+	if vehicles := filterVehicles(f, v1, v2); len(vehicles) != 1 || *vehicles[0].VehicleID != "v1" {
+		t.Fatal("expected new vehicle to be hidden")
+	}
+	moved := &FeedVehicleStatusVehicle{VehicleID: NewID("v1"), StationID: NewID("s2")}
+	if vehicles := filterVehicles(f, moved, v2); len(vehicles) != 0 {
+		t.Fatal("expected moved vehicle to be hidden")
+	}
+	f = &VehicleFilter{ParkedDelay: time.Hour, DelayInitialVehicles: true}
+	if vehicles := filterVehicles(f, v1); len(vehicles) != 0 {
+		t.Fatal("expected vehicle of first feed to be hidden")
+	}
+}
+
+func TestPrivacyZoneDefaultPrecision(t *testing.T) {
+	geometry := &GeoJSONGeometry{}
+	err := json.Unmarshal([]byte(`{"type":"Polygon","coordinates":[[[17,48],[18,48],[18,49],[17,49],[17,48]]]}`), geometry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &VehicleFilter{
+		Zones: []*PrivacyZone{{Geometry: geometry}},
+	}
+	vehicles := filterVehicles(f, &FeedVehicleStatusVehicle{
+		VehicleID: NewID("v1"),
+		Lat:       NewCoordinate(48.123456),
+		Lon:       NewCoordinate(17.654321),
+	})
+	if vehicles[0].Lat.Float64 != 48.123 || vehicles[0].Lon.Float64 != 17.654 {
+		t.Fatalf("expected coordinates rounded to 3 decimal places, got %v %v", vehicles[0].Lat.Float64, vehicles[0].Lon.Float64)
+	}
+}
+
+func TestNewServerInvalidPrivacyZone(t *testing.T) {
+	for _, geometry := range []*GeoJSONGeometry{
+		nil,
+		{Type: "Point", Coordinates: GeoJSONPoint{17, 48}},
+		{Type: "Polygon", Coordinates: "invalid"},
+	} {
+		_, err := NewServer(ServerOptions{
+			SystemID:   "system_id",
+			Store:      NewMemoryFeedStore(),
+			BaseURL:    "http://127.0.0.1:8080",
+			DefaultTTL: 60,
+			VehicleFilters: []*VehicleFilter{
+				{Zones: []*PrivacyZone{{Geometry: geometry}}},
+			},
+		})
+		if !errors.Is(err, ErrInvalidGeometry) {
+			t.Errorf("expected ErrInvalidGeometry, got %v", err)
+		}
+	}
+	zone := &PrivacyZone{Geometry: &GeoJSONGeometry{Type: "Polygon", Coordinates: "invalid"}}
+	if zone.Contains(48, 17) {
+		t.Error("expected zone with invalid geometry to contain no point")
+	}
+}
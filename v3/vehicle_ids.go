@@ -0,0 +1,248 @@
+package gbfs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+type (
+	// VehicleIDRotator maps stable internal vehicle ids to public ids, which
+	// are rotated after each trip. Trip end is reported with TripEnded or
+	// Observe, or detected when vehicle disappears from vehicle_status, is
+	// moved to other station or moves more than MinDistance meters.
+	VehicleIDRotator struct {
+		Options  VehicleIDRotatorOptions
+		mu       sync.Mutex
+		vehicles map[ID]*rotatedVehicle
+		public   map[ID]ID
+	}
+	VehicleIDRotatorOptions struct {
+		// MinDistance in meters, default 100. Negative value disables
+		// detection of trips from position changes.
+		MinDistance float64
+		// NewID generates public id, default is random hex string.
+		NewID func() ID
+		// RentalURIs returns rental uris of vehicle with public id. Default
+		// replaces path segments and query values equal to internal id.
+		// URIs which cannot be parsed are dropped.
+		RentalURIs func(internalID, publicID ID, uris *RentalURIs) *RentalURIs
+	}
+	rotatedVehicle struct {
+		publicID  ID
+		stationID ID
+		lat       *float64
+		lon       *float64
+		tripEnded bool
+	}
+)
+
+func NewVehicleIDRotator(options VehicleIDRotatorOptions) *VehicleIDRotator {
+	if options.MinDistance == 0 {
+		options.MinDistance = 100
+	}
+	if options.NewID == nil {
+		options.NewID = randomID
+	}
+	if options.RentalURIs == nil {
+		options.RentalURIs = replaceRentalURIsID
+	}
+	return &VehicleIDRotator{
+		Options:  options,
+		vehicles: make(map[ID]*rotatedVehicle),
+		public:   make(map[ID]ID),
+	}
+}
+
+func randomID() ID {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return ID(hex.EncodeToString(b))
+}
+
+// TripEnded rotates public id of vehicle on its next publication.
+func (r *VehicleIDRotator) TripEnded(internalID ID) {
+	r.mu.Lock()
+	if v, ok := r.vehicles[internalID]; ok {
+		v.tripEnded = true
+	}
+	r.mu.Unlock()
+}
+
+// Observe reports trip ends of fleet events.
+func (r *VehicleIDRotator) Observe(events ...FleetEvent) {
+	for _, e := range events {
+		if v, ok := e.(*EventRentalEnded); ok {
+			r.TripEnded(v.VehicleID)
+		}
+	}
+}
+
+// PublicID returns current public id of vehicle.
+func (r *VehicleIDRotator) PublicID(internalID ID) (ID, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.vehicles[internalID]
+	if !ok {
+		return "", false
+	}
+	return v.publicID, true
+}
+
+// InternalID returns internal id of vehicle with current public id, e.g. to
+// resolve rental uris.
+func (r *VehicleIDRotator) InternalID(publicID ID) (ID, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id, ok := r.public[publicID]
+	return id, ok
+}
+
+// RotateFeed returns copy of vehicle_status feed with public vehicle ids,
+// other feeds are returned unchanged. Vehicles not present in feed are
+// forgotten and get new public id when they appear again.
+func (r *VehicleIDRotator) RotateFeed(feed Feed) Feed {
+	f, ok := feed.(*FeedVehicleStatus)
+	if !ok || f == nil || f.Data == nil {
+		return feed
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := map[ID]bool{}
+	vehicles := []*FeedVehicleStatusVehicle{}
+	for _, v := range f.Data.Vehicles {
+		if v == nil || v.VehicleID == nil {
+			continue
+		}
+		internalID := *v.VehicleID
+		seen[internalID] = true
+		publicID := r.rotate(internalID, v)
+		vehicle := *v
+		vehicle.VehicleID = NewID(string(publicID))
+		if v.RentalURIs != nil {
+			vehicle.RentalURIs = r.Options.RentalURIs(internalID, publicID, v.RentalURIs)
+		}
+		vehicles = append(vehicles, &vehicle)
+	}
+	for id, v := range r.vehicles {
+		if !seen[id] {
+			delete(r.public, v.publicID)
+			delete(r.vehicles, id)
+		}
+	}
+	rotated := &FeedVehicleStatus{
+		Data: &FeedVehicleStatusData{
+			Vehicles: vehicles,
+		},
+	}
+	rotated.LastUpdated = f.LastUpdated
+	rotated.TTL = f.TTL
+	rotated.Version = f.Version
+	return rotated
+}
+
+func (r *VehicleIDRotator) rotate(internalID ID, v *FeedVehicleStatusVehicle) ID {
+	var stationID ID
+	if v.StationID != nil {
+		stationID = *v.StationID
+	}
+	var lat, lon *float64
+	if v.Lat != nil && v.Lon != nil {
+		lat = NewFloat64(v.Lat.Float64)
+		lon = NewFloat64(v.Lon.Float64)
+	}
+	prev, ok := r.vehicles[internalID]
+	if ok && !prev.tripEnded && !r.moved(prev, stationID, lat, lon) {
+		return prev.publicID
+	}
+	if ok {
+		delete(r.public, prev.publicID)
+	}
+	publicID := r.Options.NewID()
+	for _, exists := r.public[publicID]; exists || publicID == internalID; _, exists = r.public[publicID] {
+		publicID = r.Options.NewID()
+	}
+	r.vehicles[internalID] = &rotatedVehicle{
+		publicID:  publicID,
+		stationID: stationID,
+		lat:       lat,
+		lon:       lon,
+	}
+	r.public[publicID] = internalID
+	return publicID
+}
+
+func (r *VehicleIDRotator) moved(prev *rotatedVehicle, stationID ID, lat, lon *float64) bool {
+	if stationID != "" || prev.stationID != "" {
+		return stationID != prev.stationID
+	}
+	if r.Options.MinDistance < 0 || lat == nil || prev.lat == nil {
+		return false
+	}
+	return distance(*prev.lat, *prev.lon, *lat, *lon) > r.Options.MinDistance
+}
+
+// distance returns great-circle distance in meters.
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371000
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func replaceRentalURIsID(internalID, publicID ID, uris *RentalURIs) *RentalURIs {
+	return &RentalURIs{
+		Android: replaceURIID(uris.Android, internalID, publicID),
+		IOS:     replaceURIID(uris.IOS, internalID, publicID),
+		Web:     replaceURIID(uris.Web, internalID, publicID),
+	}
+}
+
+// replaceURIID replaces host, path segments, query values and fragment of
+// uri equal to internal id with public id. Other parts of uri are kept.
+func replaceURIID(uri *string, internalID, publicID ID) *string {
+	if uri == nil {
+		return nil
+	}
+	u, err := url.Parse(*uri)
+	if err != nil {
+		return nil
+	}
+	id, public := string(internalID), string(publicID)
+	if u.Host == id {
+		u.Host = public
+	}
+	if u.Path != "" {
+		segments := strings.Split(u.Path, "/")
+		for i, segment := range segments {
+			if segment == id {
+				segments[i] = public
+			}
+		}
+		u.Path = strings.Join(segments, "/")
+		u.RawPath = ""
+	}
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		for i, param := range params {
+			k, v, ok := strings.Cut(param, "=")
+			if !ok {
+				continue
+			}
+			if v, err := url.QueryUnescape(v); err == nil && v == id {
+				params[i] = k + "=" + url.QueryEscape(public)
+			}
+		}
+		u.RawQuery = strings.Join(params, "&")
+	}
+	if u.Fragment == id {
+		u.Fragment = public
+		u.RawFragment = ""
+	}
+	return NewString(u.String())
+}
@@ -0,0 +1,39 @@
+package gbfs
+
+import "testing"
+
+func TestReplaceURIID(t *testing.T) {
+	tests := []struct {
+		uri      string
+		expected string
+	}{
+		{"https://x/rent/bike-42", "https://x/rent/pub-1"},
+		{"https://x/rent/bike-42/", "https://x/rent/pub-1/"},
+		{"https://x/rent/bike-42.html", "https://x/rent/bike-42.html"},
+		{"https://x/rent?lang=en&id=bike-42", "https://x/rent?lang=en&id=pub-1"},
+		{"https://x/rent?id=BIKE-bike-42&lang=en", "https://x/rent?id=BIKE-bike-42&lang=en"},
+		{"app://rent#bike-42", "app://rent#pub-1"},
+		{"app://rent#bike-42-info", "app://rent#bike-42-info"},
+		{"app://bike-42/rent", "app://pub-1/rent"},
+		{"https://x/rent?bike-42=1", "https://x/rent?bike-42=1"},
+		{"https://bike-42.example.com/rent", "https://bike-42.example.com/rent"},
+		{"https://x/rent/bike%2D42", "https://x/rent/pub-1"},
+		{"mailto:bike-42@example.com", "mailto:bike-42@example.com"},
+		{"://", ""},
+	}
+	for _, test := range tests {
+		uri := replaceURIID(NewString(test.uri), "bike-42", "pub-1")
+		switch {
+		case test.expected == "" && uri != nil:
+			t.Errorf("%s: expected uri to be dropped, got %s", test.uri, *uri)
+		case test.expected != "" && uri == nil:
+			t.Errorf("%s: expected %s, got nil", test.uri, test.expected)
+		case test.expected != "" && *uri != test.expected:
+			t.Errorf("%s: expected %s, got %s", test.uri, test.expected, *uri)
+		}
+	}
+	uri := replaceURIID(NewString("https://x/v1/rent/1?id=1&q=1x"), "1", "abcd")
+	if expected := "https://x/v1/rent/abcd?id=abcd&q=1x"; uri == nil || *uri != expected {
+		t.Errorf("expected %s, got %v", expected, uri)
+	}
+}